@@ -14,7 +14,14 @@ func main() {
 		Timeout: 3 * time.Second,
 	}
 
-	resp, err := client.Get(url)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error building request: %v\n", err)
+		os.Exit(1)
+	}
+	req.Header.Set("User-Agent", "mpb-healthcheck")
+
+	resp, err := client.Do(req)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error making request: %v\n", err)
 		os.Exit(1)
@@ -1,13 +1,22 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"mpb/configs"
 	_ "mpb/docs"
+	"mpb/internal/app"
 	"mpb/internal/auth"
 	"mpb/internal/posts"
 	"mpb/pkg/db"
+	"mpb/pkg/jwtkeys"
 	"mpb/pkg/redis"
+	"os"
 	"runtime"
+	"strconv"
+	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/ThreeDotsLabs/watermill"
@@ -33,9 +42,21 @@ func main() {
 	}
 	defer redisClient.Close()
 
-	app := fiber.New()
+	if len(os.Args) > 1 {
+		if err := runCommand(conf, database, redisClient, os.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	jwtKeys, err := jwtkeys.NewKeySet(conf)
+	if err != nil {
+		log.Fatalf("Failed to load JWT keys: %v", err)
+	}
+
+	server := fiber.New()
 
-	api := app.Group("/api")
+	api := server.Group("/api")
 
 	logger := watermill.NewStdLogger(false, false)
 	pubsub := gochannel.NewGoChannel(gochannel.Config{}, logger)
@@ -46,24 +67,8 @@ func main() {
 	log.Infof("Watermill pubsub initialized, publisher=%p, subscriber=%p",
 		message.Publisher(publisher), message.Subscriber(subscriber))
 
-	// auth блок
-	authRepo := auth.NewAuthRepository(conf, database, redisClient.Client)
-	authService := auth.NewAuthService(authRepo, []byte(conf.JWT.SecretKey), conf.JWT.AccessTokenTTL)
-	authHandler := auth.NewAuthHandlers(authService)
-	authRoutes := auth.NewAuthRoutes(api, authHandler)
-	authRoutes.Register()
-
-	// posts блок
-	postRepo := posts.NewPostsRepository(database)
-	metricsService := posts.NewMetricsService(redisClient.Client, publisher, logger)
-	postService := posts.NewPostsService(postRepo, metricsService, publisher, logger)
-	postsHandler := posts.NewPostsHandlers(postService, metricsService)
-	postsRoutes := posts.NewPostsRoutes(api, postsHandler, []byte(conf.JWT.SecretKey))
-	postsRoutes.Register()
-
-	metricsConsumer := posts.NewMetricsSyncConsumer(postRepo, logger)
-	if err := metricsConsumer.StartConsumers(subscriber); err != nil {
-		log.Fatalf("Failed to start metrics consumers: %v", err)
+	if err := app.RegisterModules(api, database, redisClient, jwtKeys, publisher, subscriber, logger, conf); err != nil {
+		log.Fatalf("Failed to register modules: %v", err)
 	}
 
 	for i := 0; i < 20; i++ {
@@ -71,10 +76,57 @@ func main() {
 	}
 	time.Sleep(500 * time.Millisecond)
 
-	app.Get("/swagger/*", fiberSwagger.WrapHandler)
+	server.Get("/swagger/*", fiberSwagger.WrapHandler)
+	server.Get("/.well-known/jwks.json", auth.JWKSHandler(jwtKeys))
 
 	log.Info("Starting server on :8000")
-	if err := app.Listen(":8000"); err != nil {
+	if err := server.Listen(":8000"); err != nil {
 		log.Fatal(err)
 	}
 }
+
+// runCommand выполняет служебные команды вместо запуска сервера: mpb metrics reconcile [--dry-run]
+func runCommand(conf *configs.Config, database *db.Db, redisClient *redis.Redis, args []string) error {
+	if len(args) < 2 || args[0] != "metrics" || args[1] != "reconcile" {
+		return fmt.Errorf("unknown command %q, usage: mpb metrics reconcile [--dry-run]", strings.Join(args, " "))
+	}
+
+	flags := flag.NewFlagSet("metrics reconcile", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "report drift without repairing it")
+	if err := flags.Parse(args[2:]); err != nil {
+		return err
+	}
+
+	logger := watermill.NewStdLogger(false, false)
+	metricsService := posts.NewMetricsService(redisClient.Client, nil, logger, conf.Posts)
+	postService := posts.NewPostsService(posts.NewPostsRepository(database), metricsService, nil, logger)
+
+	out := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(out, "POST\tLIKES rows/db/redis\tVIEWS db/redis\tLIKES →\tVIEWS →")
+	report, err := postService.ReconcileMetrics(context.Background(), *dryRun, func(d posts.MetricsDrift) {
+		fmt.Fprintf(out, "%d\t%d/%d/%s\t%d/%s\t%d\t%d\n",
+			d.PostID,
+			d.LikeRows, d.DBLikes, redisCounter(d.RedisLikes, d.HasRedisLikes),
+			d.DBViews, redisCounter(d.RedisViews, d.HasRedisViews),
+			d.Likes, d.Views)
+	})
+	out.Flush()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("scanned %d posts, %d drifted", report.Scanned, report.Drifted)
+	if *dryRun {
+		fmt.Println(" (dry run, nothing repaired)")
+	} else {
+		fmt.Printf(", repaired %d Redis keys and %d posts rows\n", report.RedisRepaired, report.DBRepaired)
+	}
+	return nil
+}
+
+func redisCounter(n int, ok bool) string {
+	if !ok {
+		return "-"
+	}
+	return strconv.Itoa(n)
+}
@@ -2,6 +2,8 @@ package configs
 
 import (
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -13,8 +15,19 @@ type AWSConfig struct {
 }
 
 type JWTConfig struct {
-	SecretKey      string
-	AccessTokenTTL time.Duration
+	// SecretKey больше не подписывает токены: пока он задан, принимаются HS256-токены без kid, выданные до перехода
+	SecretKey string
+	// LegacyUntil — момент перехода плюс AccessTokenTTL; позже легаси-токены не принимаются, а SecretKey нужно убрать
+	LegacyUntil time.Time
+	// KeysDir — каталог с *.pem ключами, имя файла без расширения служит kid
+	KeysDir string
+	// EphemeralKey разрешает работать без KeysDir с временным ключом — только для локальной разработки
+	EphemeralKey    bool
+	SigningKeyID    string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	// RevocationCacheTTL — сколько инстанс помнит результат проверки отзыва токена
+	RevocationCacheTTL time.Duration
 }
 
 type DbConfig struct {
@@ -25,11 +38,73 @@ type RedisConfig struct {
 	Addr string
 }
 
+type PostsConfig struct {
+	PublishInterval time.Duration
+	Reactions       []string
+	// ViewDedupeWindow — повторные просмотры одного зрителя в этом окне не засчитываются
+	ViewDedupeWindow time.Duration
+	// ReconcileInterval — период сверки счётчиков Redis и PostgreSQL, 0 отключает сверку
+	ReconcileInterval time.Duration
+	// ViewsFlushInterval и ViewsFlushBatch — как часто и после скольких событий просмотры сбрасываются в PostgreSQL
+	ViewsFlushInterval time.Duration
+	ViewsFlushBatch    int
+	// TrendingFlushInterval — как часто накопленные очки тренда записываются в Redis
+	TrendingFlushInterval time.Duration
+}
+
+type FeedConfig struct {
+	// Size — сколько записей хранит лента пользователя в Redis
+	Size int
+	// FanoutMaxFollowers — авторы с большим числом подписчиков не рассылаются по лентам, а подмешиваются при чтении
+	FanoutMaxFollowers int
+	// TTL — лента неактивного пользователя удаляется и затем собирается заново при обращении
+	TTL time.Duration
+}
+
+type CommentsConfig struct {
+	// FlagWords — комментарии с этими словами (без учёта регистра) автоматически попадают в очередь модерации
+	FlagWords []string
+	// FlagMaxLinks — комментарии, где ссылок больше, тоже попадают в очередь; 0 отключает проверку
+	FlagMaxLinks int
+}
+
+type AuthConfig struct {
+	// PublicURL — адрес фронтенда, от которого строятся ссылки в письмах
+	PublicURL string
+	// EmailTokenTTL — срок действия ссылок подтверждения адреса
+	EmailTokenTTL time.Duration
+	// EmailResendInterval — не чаще этого письмо подтверждения отправляется повторно
+	EmailResendInterval time.Duration
+	// PasswordResetTTL — срок действия ссылки сброса пароля
+	PasswordResetTTL time.Duration
+	// TOTPIssuer — имя сервиса в приложении-аутентификаторе
+	TOTPIssuer string
+	// MFATokenTTL — сколько действует токен mfa_pending между паролем и кодом второго фактора
+	MFATokenTTL time.Duration
+	// RequireVerifiedEmail запрещает публиковать посты и комментарии до подтверждения адреса
+	RequireVerifiedEmail bool
+}
+
+type MailConfig struct {
+	// Backend — smtp, file (письма сохраняются в Dir) или log
+	Backend      string
+	From         string
+	SMTPAddr     string
+	SMTPUsername string
+	SMTPPassword string
+	Dir          string
+}
+
 type Config struct {
-	Db    DbConfig
-	Redis RedisConfig
-	JWT   JWTConfig
-	AWS   AWSConfig
+	Db       DbConfig
+	Redis    RedisConfig
+	JWT      JWTConfig
+	Auth     AuthConfig
+	Mail     MailConfig
+	AWS      AWSConfig
+	Posts    PostsConfig
+	Feed     FeedConfig
+	Comments CommentsConfig
 }
 
 func LoadConfig() *Config {
@@ -42,6 +117,172 @@ func LoadConfig() *Config {
 		}
 	}
 
+	refreshTTL := 7 * 24 * time.Hour
+	if v := os.Getenv("JWT_REFRESH_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			refreshTTL = d
+		}
+	}
+
+	revocationCacheTTL := 5 * time.Second
+	if v := os.Getenv("JWT_REVOCATION_CACHE_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			revocationCacheTTL = d
+		}
+	}
+
+	var legacyUntil time.Time
+	if v := os.Getenv("JWT_LEGACY_UNTIL"); v != "" {
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			legacyUntil = t
+		}
+	}
+
+	publishInterval := 30 * time.Second
+	if v := os.Getenv("PUBLISH_SCHEDULER_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			publishInterval = d
+		}
+	}
+
+	reactions := []string{"👍", "❤️", "😂", "😮", "😢", "🔥"}
+	if v := os.Getenv("POST_REACTIONS"); v != "" {
+		reactions = nil
+		for _, r := range strings.Split(v, ",") {
+			if r = strings.TrimSpace(r); r != "" {
+				reactions = append(reactions, r)
+			}
+		}
+	}
+
+	viewDedupeWindow := 30 * time.Minute
+	if v := os.Getenv("VIEW_DEDUPE_WINDOW"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			viewDedupeWindow = d
+		}
+	}
+
+	reconcileInterval := 15 * time.Minute
+	if v := os.Getenv("METRICS_RECONCILE_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			reconcileInterval = d
+		}
+	}
+
+	viewsFlushInterval := 5 * time.Second
+	if v := os.Getenv("VIEWS_FLUSH_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			viewsFlushInterval = d
+		}
+	}
+
+	viewsFlushBatch := 500
+	if v := os.Getenv("VIEWS_FLUSH_BATCH"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			viewsFlushBatch = n
+		}
+	}
+
+	trendingFlushInterval := 10 * time.Second
+	if v := os.Getenv("TRENDING_FLUSH_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			trendingFlushInterval = d
+		}
+	}
+
+	feedSize := 500
+	if v := os.Getenv("FEED_SIZE"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			feedSize = n
+		}
+	}
+
+	feedFanoutMaxFollowers := 5000
+	if v := os.Getenv("FEED_FANOUT_MAX_FOLLOWERS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			feedFanoutMaxFollowers = n
+		}
+	}
+
+	feedTTL := 7 * 24 * time.Hour
+	if v := os.Getenv("FEED_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			feedTTL = d
+		}
+	}
+
+	var commentFlagWords []string
+	for _, w := range strings.Split(os.Getenv("COMMENTS_FLAG_WORDS"), ",") {
+		if w = strings.TrimSpace(strings.ToLower(w)); w != "" {
+			commentFlagWords = append(commentFlagWords, w)
+		}
+	}
+
+	commentFlagMaxLinks := 3
+	if v := os.Getenv("COMMENTS_FLAG_MAX_LINKS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			commentFlagMaxLinks = n
+		}
+	}
+
+	publicURL := strings.TrimRight(os.Getenv("APP_PUBLIC_URL"), "/")
+	if publicURL == "" {
+		publicURL = "http://localhost:8000"
+	}
+
+	emailTokenTTL := 24 * time.Hour
+	if v := os.Getenv("EMAIL_TOKEN_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			emailTokenTTL = d
+		}
+	}
+
+	emailResendInterval := time.Minute
+	if v := os.Getenv("EMAIL_RESEND_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			emailResendInterval = d
+		}
+	}
+
+	passwordResetTTL := 30 * time.Minute
+	if v := os.Getenv("PASSWORD_RESET_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			passwordResetTTL = d
+		}
+	}
+
+	totpIssuer := os.Getenv("TOTP_ISSUER")
+	if totpIssuer == "" {
+		totpIssuer = "mpb"
+	}
+
+	mfaTokenTTL := 5 * time.Minute
+	if v := os.Getenv("MFA_TOKEN_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			mfaTokenTTL = d
+		}
+	}
+
+	requireVerifiedEmail, _ := strconv.ParseBool(os.Getenv("REQUIRE_VERIFIED_EMAIL"))
+
+	ephemeralKey, _ := strconv.ParseBool(os.Getenv("JWT_EPHEMERAL_KEY"))
+
+	// по умолчанию письма пишутся в файлы: log-бэкенд включается только явно
+	mailBackend := os.Getenv("MAIL_BACKEND")
+	if mailBackend == "" {
+		mailBackend = "file"
+	}
+
+	mailFrom := os.Getenv("MAIL_FROM")
+	if mailFrom == "" {
+		mailFrom = "no-reply@mpb.local"
+	}
+
+	mailDir := os.Getenv("MAIL_DIR")
+	if mailDir == "" {
+		mailDir = "./mail"
+	}
+
 	redisAddr := os.Getenv("REDIS_ADDR")
 	if redisAddr == "" {
 		redisAddr = "localhost:6379"
@@ -55,12 +296,53 @@ func LoadConfig() *Config {
 			Addr: redisAddr,
 		},
 		JWT: JWTConfig{
-			SecretKey:      os.Getenv("JWT_SECRET"),
-			AccessTokenTTL: ttl,
+			SecretKey:          os.Getenv("JWT_SECRET"),
+			LegacyUntil:        legacyUntil,
+			KeysDir:            os.Getenv("JWT_KEYS_DIR"),
+			EphemeralKey:       ephemeralKey,
+			SigningKeyID:       os.Getenv("JWT_SIGNING_KEY_ID"),
+			AccessTokenTTL:     ttl,
+			RefreshTokenTTL:    refreshTTL,
+			RevocationCacheTTL: revocationCacheTTL,
+		},
+		Auth: AuthConfig{
+			PublicURL:            publicURL,
+			EmailTokenTTL:        emailTokenTTL,
+			EmailResendInterval:  emailResendInterval,
+			PasswordResetTTL:     passwordResetTTL,
+			TOTPIssuer:           totpIssuer,
+			MFATokenTTL:          mfaTokenTTL,
+			RequireVerifiedEmail: requireVerifiedEmail,
+		},
+		Mail: MailConfig{
+			Backend:      mailBackend,
+			From:         mailFrom,
+			SMTPAddr:     os.Getenv("SMTP_ADDR"),
+			SMTPUsername: os.Getenv("SMTP_USERNAME"),
+			SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+			Dir:          mailDir,
 		},
 		AWS: AWSConfig{
 			Region: os.Getenv("AWS_REGION"),
 			Bucket: os.Getenv("AWS_BUCKET"),
 		},
+		Posts: PostsConfig{
+			PublishInterval:       publishInterval,
+			Reactions:             reactions,
+			ViewDedupeWindow:      viewDedupeWindow,
+			ReconcileInterval:     reconcileInterval,
+			ViewsFlushInterval:    viewsFlushInterval,
+			ViewsFlushBatch:       viewsFlushBatch,
+			TrendingFlushInterval: trendingFlushInterval,
+		},
+		Feed: FeedConfig{
+			Size:               feedSize,
+			FanoutMaxFollowers: feedFanoutMaxFollowers,
+			TTL:                feedTTL,
+		},
+		Comments: CommentsConfig{
+			FlagWords:    commentFlagWords,
+			FlagMaxLinks: commentFlagMaxLinks,
+		},
 	}
 }
@@ -1222,6 +1222,7 @@ const docTemplate = `{
             "properties": {
                 "description": {
                     "type": "string",
+                    "maxLength": 50000,
                     "minLength": 10
                 },
                 "tag": {
@@ -1403,6 +1404,7 @@ const docTemplate = `{
             "properties": {
                 "description": {
                     "type": "string",
+                    "maxLength": 50000,
                     "minLength": 10
                 },
                 "tag": {
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"log"
 	"mpb/configs"
+	"mpb/internal/auth"
 	"mpb/pkg/db"
+	"mpb/pkg/jwtkeys"
 	"mpb/pkg/redis"
 	"os"
 	"os/signal"
@@ -24,6 +26,7 @@ type App struct {
 	conf       *configs.Config
 	db         *db.Db
 	redis      *redis.Redis
+	jwtKeys    *jwtkeys.KeySet
 	fiberApp   *fiber.App
 	publisher  message.Publisher
 	subscriber message.Subscriber
@@ -38,6 +41,11 @@ func New(conf *configs.Config) (*App, error) {
 		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
+	jwtKeys, err := jwtkeys.NewKeySet(conf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load JWT keys: %w", err)
+	}
+
 	app := fiber.New()
 
 	logger := watermill.NewStdLogger(false, false)
@@ -47,6 +55,7 @@ func New(conf *configs.Config) (*App, error) {
 		conf:       conf,
 		db:         database,
 		redis:      redisClient,
+		jwtKeys:    jwtKeys,
 		fiberApp:   app,
 		publisher:  pubsub,
 		subscriber: pubsub,
@@ -56,9 +65,12 @@ func New(conf *configs.Config) (*App, error) {
 
 func (a *App) Run() error {
 	api := a.fiberApp.Group("/api")
-	RegisterModules(api, a.db, a.redis, a.publisher, a.subscriber, a.logger, a.conf)
+	if err := RegisterModules(api, a.db, a.redis, a.jwtKeys, a.publisher, a.subscriber, a.logger, a.conf); err != nil {
+		return err
+	}
 
 	a.fiberApp.Get("/swagger/*", fiberSwagger.WrapHandler)
+	a.fiberApp.Get("/.well-known/jwks.json", auth.JWKSHandler(a.jwtKeys))
 
 	for i := 0; i < 20; i++ {
 		runtime.Gosched()
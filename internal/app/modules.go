@@ -1,15 +1,22 @@
 package app
 
 import (
+	"context"
+	"fmt"
 	"mpb/configs"
 	"mpb/internal/auth"
 	"mpb/internal/comments"
+	"mpb/internal/feed"
 	"mpb/internal/post_attachments"
 	"mpb/internal/posts"
 	"mpb/internal/stories"
+	"mpb/internal/tags"
 	"mpb/internal/user_attachments"
 	"mpb/internal/users"
 	"mpb/pkg/db"
+	"mpb/pkg/jwtkeys"
+	"mpb/pkg/mailer"
+	"mpb/pkg/middleware"
 	"mpb/pkg/redis"
 	"mpb/pkg/s3"
 
@@ -18,74 +25,123 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
+// RegisterModules — единственная точка сборки модулей: регистрирует маршруты и запускает фоновые задачи и консьюмеры
 func RegisterModules(
 	api fiber.Router,
 	database *db.Db,
 	redisClient *redis.Redis,
+	jwtKeys *jwtkeys.KeySet,
 	publisher message.Publisher,
 	subscriber message.Subscriber,
 	logger watermill.LoggerAdapter,
 	conf *configs.Config,
-) {
+) error {
 	// s3 подключить
 	s3Client, err := s3.NewS3Client(conf)
 	if err != nil {
-		logger.Error("failed to init S3 client", err, nil)
-		return
+		return fmt.Errorf("failed to init S3 client: %w", err)
+	}
+
+	mail, err := mailer.New(conf)
+	if err != nil {
+		return fmt.Errorf("failed to init mailer: %w", err)
 	}
 
 	// auth блок
 	authRepo := auth.NewAuthRepository(conf, database, redisClient.Client)
-	authService := auth.NewAuthService(authRepo, []byte(conf.JWT.SecretKey), conf.JWT.AccessTokenTTL)
+	revocations := auth.NewRevocationStore(redisClient.Client, conf.JWT.AccessTokenTTL, conf.JWT.RevocationCacheTTL)
+	middleware.SetRevocationChecker(revocations)
+	middleware.SetVerifiedEmailRequired(conf.Auth.RequireVerifiedEmail)
+	authService := auth.NewAuthService(authRepo, revocations, mail, jwtKeys, logger, conf.JWT, conf.Auth)
 	authHandler := auth.NewAuthHandlers(authService)
-	authRoutes := auth.NewAuthRoutes(api, authHandler)
+	authRoutes := auth.NewAuthRoutes(api, authHandler, jwtKeys)
 	authRoutes.Register()
 
 	// posts блок
 	postRepo := posts.NewPostsRepository(database)
-	metricsService := posts.NewMetricsService(redisClient.Client, publisher, logger)
+	metricsService := posts.NewMetricsService(redisClient.Client, publisher, logger, conf.Posts)
 	postService := posts.NewPostsService(postRepo, metricsService, publisher, logger)
 	postHandler := posts.NewPostsHandlers(postService, metricsService)
-	postRoutes := posts.NewPostsRoutes(api, postHandler, []byte(conf.JWT.SecretKey))
+	postRoutes := posts.NewPostsRoutes(api, postHandler, jwtKeys)
 	postRoutes.Register()
 
+	go func() {
+		if err := postService.WarmLikeCache(context.Background()); err != nil {
+			logger.Error("failed to restore likes cache", err, nil)
+		}
+	}()
+
+	publishScheduler := posts.NewPublishScheduler(postService, conf.Posts.PublishInterval, logger)
+	publishScheduler.Start(context.Background())
+
+	if conf.Posts.ReconcileInterval > 0 {
+		metricsReconciler := posts.NewMetricsReconciler(postService, conf.Posts.ReconcileInterval, logger)
+		metricsReconciler.Start(context.Background())
+	}
+
+	// tags блок
+	tagsRepo := tags.NewTagsRepository(database)
+	tagsService := tags.NewTagsService(tagsRepo)
+	tagsHandler := tags.NewTagsHandlers(tagsService, postService)
+	tagsRoutes := tags.NewTagsRoutes(api, tagsHandler, jwtKeys)
+	tagsRoutes.Register()
+
 	// post attachments блоки
 	postAttachmentRepo := post_attachments.NewPostAttacmentsRepository(database)
 	postAttachmentService := post_attachments.NewPostAttachmentsService(postAttachmentRepo)
 	postAttachmentHandler := post_attachments.NewPostAttachmentsHandlers(postAttachmentService, s3Client)
-	postAttachmentRoutes := post_attachments.NewPostAttachmentsRoutes(api, postAttachmentHandler, []byte(conf.JWT.SecretKey))
+	postAttachmentRoutes := post_attachments.NewPostAttachmentsRoutes(api, postAttachmentHandler, jwtKeys)
 	postAttachmentRoutes.Register()
 
 	// comments блок
 	commentRepo := comments.NewCommentsRepository(database)
-	commentService := comments.NewCommentsService(commentRepo)
+	commentService := comments.NewCommentsService(commentRepo, publisher, logger, conf.Comments)
 	commentHandler := comments.NewCommentsHandlers(commentService)
-	commentRoutes := comments.NewCommentsRoutes(api, commentHandler, []byte(conf.JWT.SecretKey))
+	commentRoutes := comments.NewCommentsRoutes(api, commentHandler, jwtKeys)
 	commentRoutes.Register()
 
 	// users блок
 	usersRepo := users.NewUsersRepository(database)
-	usersService := users.NewUsersService(usersRepo, postRepo)
-	usersHandler := users.NewUsersHandlers(usersService)
-	usersRoutes := users.NewUsersRoutes(api, usersHandler)
+	usersService := users.NewUsersService(usersRepo, postRepo, publisher, logger)
+	usersHandler := users.NewUsersHandlers(usersService, postService)
+	usersRoutes := users.NewUsersRoutes(api, usersHandler, jwtKeys)
 	usersRoutes.Register()
 
+	// feed блок
+	feedRepo := feed.NewFeedRepository(database)
+	feedTimeline := feed.NewTimelineService(redisClient.Client, conf.Feed.Size, conf.Feed.TTL)
+	feedService := feed.NewFeedService(feedRepo, feedTimeline, postService, conf.Feed, logger)
+	feedHandler := feed.NewFeedHandlers(feedService)
+	feedRoutes := feed.NewFeedRoutes(api, feedHandler, jwtKeys)
+	feedRoutes.Register()
+
 	// user attachments блок
 	userAttachmentRepo := user_attachments.NewUserAttachmentsRepository(database)
 	userAttachmentService := user_attachments.NewUserAttachmentsService(userAttachmentRepo)
 	userAttachmentHandler := user_attachments.NewUserAttachmentsHandlers(userAttachmentService, s3Client)
-	userAttachmentRoutes := user_attachments.NewUserAttachmentsRoutes(api, userAttachmentHandler, []byte(conf.JWT.SecretKey))
+	userAttachmentRoutes := user_attachments.NewUserAttachmentsRoutes(api, userAttachmentHandler, jwtKeys)
 	userAttachmentRoutes.Register()
 
 	// stories блок
 	storiesRepo := stories.NewStoriesRepository(database)
 	storiesService := stories.NewStoriesService(storiesRepo)
 	storiesHandler := stories.NewStoriesHandlers(storiesService, s3Client)
-	storiesRoutes := stories.NewStoriesRoutes(api, storiesHandler, []byte(conf.JWT.SecretKey))
+	storiesRoutes := stories.NewStoriesRoutes(api, storiesHandler, jwtKeys)
 	storiesRoutes.Register()
 
-	metricsConsumer := posts.NewMetricsSyncConsumer(postRepo, logger)
+	metricsConsumer := posts.NewMetricsSyncConsumer(postRepo, logger, conf.Posts)
 	if err := metricsConsumer.StartConsumers(subscriber); err != nil {
-		logger.Error("failed to start metrics consumers", err, nil)
+		return fmt.Errorf("failed to start metrics consumers: %w", err)
+	}
+
+	trendingConsumer := posts.NewTrendingConsumer(postRepo, metricsService, logger, conf.Posts)
+	if err := trendingConsumer.Start(subscriber); err != nil {
+		return fmt.Errorf("failed to start trending consumer: %w", err)
+	}
+
+	fanoutConsumer := feed.NewFanoutConsumer(feedRepo, feedTimeline, logger, conf.Feed)
+	if err := fanoutConsumer.Start(subscriber); err != nil {
+		return fmt.Errorf("failed to start feed consumer: %w", err)
 	}
+	return nil
 }
@@ -0,0 +1,10 @@
+package dto
+
+type EmailTokenRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type ChangeEmailRequest struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
@@ -3,6 +3,7 @@ package dto
 type LoginRequest struct {
 	Username     string `json:"username" validate:"required,min=3"`
 	Password     string `json:"password" validate:"required,min=6"`
+	Device       string `json:"device" validate:"omitempty,max=100"`
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
 }
@@ -2,10 +2,25 @@ package dto
 
 import (
 	"mpb/internal/user"
+	"time"
 )
 
+// LoginResponse — пара токенов либо, если включён второй фактор, только mfa_token для /auth/login/mfa
 type LoginResponse struct {
-	Token        string     `json:"token"`
-	User         *user.User `json:"user"`
-	RefreshToken string     `json:"refresh_token"`
+	Token        string     `json:"token,omitempty"`
+	User         *user.User `json:"user,omitempty"`
+	RefreshToken string     `json:"refresh_token,omitempty"`
+	MFARequired  bool       `json:"mfa_required,omitempty"`
+	MFAToken     string     `json:"mfa_token,omitempty"`
+}
+
+type SessionResponse struct {
+	ID         int       `json:"id"`
+	Device     string    `json:"device"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	Current    bool      `json:"current"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
 }
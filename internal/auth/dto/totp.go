@@ -0,0 +1,31 @@
+package dto
+
+type LoginMFARequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	// Code — шесть цифр из приложения или код восстановления
+	Code string `json:"code" validate:"required,max=32"`
+}
+
+type EnrollTOTPRequest struct {
+	Password string `json:"password" validate:"required"`
+}
+
+type TOTPEnrollResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauth_url"`
+	// QRCode — PNG с otpauth_url, в JSON кодируется base64
+	QRCode []byte `json:"qr_png" swaggertype:"string" format:"base64"`
+}
+
+type TOTPCodeRequest struct {
+	Code string `json:"code" validate:"required,max=32"`
+}
+
+type DisableTOTPRequest struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required,max=32"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
@@ -0,0 +1,199 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mpb/pkg/errors_constant"
+	"mpb/pkg/mailer"
+	"mpb/pkg/security"
+	"net/url"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Типы токенов из писем; middleware не принимает их как access-токены
+const (
+	emailVerifyTokenType = "email_verify"
+	emailChangeTokenType = "email_change"
+)
+
+// VerifyEmail подтверждает адрес по ссылке из письма. Ссылка действует, пока адрес пользователя не сменился.
+func (s *AuthService) VerifyEmail(ctx context.Context, token string) error {
+	claims, err := s.parseEmailToken(token, emailVerifyTokenType)
+	if err != nil {
+		return err
+	}
+
+	verified, err := s.repo.MarkEmailVerified(ctx, claims.userID, claims.email)
+	if err != nil {
+		return fmt.Errorf("failed to verify email: %w", err)
+	}
+	if !verified {
+		return errors_constant.InvalidEmailToken
+	}
+	return nil
+}
+
+// ResendVerification повторно отправляет ссылку подтверждения, не чаще раза в EmailResendInterval
+func (s *AuthService) ResendVerification(ctx context.Context, userID int) error {
+	user, err := s.repo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt != nil || user.Email == nil {
+		return errors_constant.EmailAlreadyVerified
+	}
+
+	if err := s.cooldown(ctx, fmt.Sprintf("email_resend:%d", userID)); err != nil {
+		return err
+	}
+	return s.sendVerification(ctx, userID, *user.Email)
+}
+
+// RequestEmailChange отправляет ссылку подтверждения на новый адрес; до перехода по ней действует старый адрес.
+// Старый адрес получает уведомление, чтобы владелец заметил смену, которую не запрашивал.
+func (s *AuthService) RequestEmailChange(ctx context.Context, userID int, password, newEmail string) error {
+	user, err := s.repo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if !security.CheckPasswordHash(password, user.PasswordHash) {
+		return errors_constant.InvalidPassword
+	}
+	if user.Email != nil && *user.Email == newEmail {
+		return errors_constant.EmailUnchanged
+	}
+
+	taken, err := s.repo.EmailTaken(ctx, newEmail)
+	if err != nil {
+		return fmt.Errorf("failed to check email: %w", err)
+	}
+	if taken {
+		return errors_constant.EmailTaken
+	}
+
+	if err := s.cooldown(ctx, fmt.Sprintf("email_change:%d", userID)); err != nil {
+		return err
+	}
+
+	oldEmail := ""
+	if user.Email != nil {
+		oldEmail = *user.Email
+	}
+	token, err := s.keys.Sign(jwt.MapClaims{
+		"typ":     emailChangeTokenType,
+		"user_id": userID,
+		"email":   newEmail,
+		"from":    oldEmail,
+		"exp":     time.Now().Add(s.conf.EmailTokenTTL).Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      newEmail,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf("Follow the link to use this address for your account:\n\n%s\n\nThe link expires in %s.",
+			s.link("/confirm-email", token), s.conf.EmailTokenTTL),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send confirmation email: %w", err)
+	}
+
+	if oldEmail != "" {
+		err = s.mailer.Send(ctx, mailer.Message{
+			To:      oldEmail,
+			Subject: "Email change requested",
+			Body: fmt.Sprintf("A change of your account email to %s was requested. "+
+				"It takes effect only after the new address is confirmed. If this wasn't you, change your password.", newEmail),
+		})
+		if err != nil {
+			s.logger.Error("failed to send email change notice", err, watermill.LogFields{"user_id": userID})
+		}
+	}
+	return nil
+}
+
+// ConfirmEmailChange переключает адрес по ссылке из письма и возвращает новый адрес.
+// Ссылка срабатывает один раз: после смены старый адрес в токене больше не совпадает.
+func (s *AuthService) ConfirmEmailChange(ctx context.Context, token string) (string, error) {
+	claims, err := s.parseEmailToken(token, emailChangeTokenType)
+	if err != nil {
+		return "", err
+	}
+
+	changed, err := s.repo.ChangeEmail(ctx, claims.userID, claims.from, claims.email)
+	if err != nil {
+		if errors.Is(err, errors_constant.EmailTaken) {
+			return "", err
+		}
+		return "", fmt.Errorf("failed to change email: %w", err)
+	}
+	if !changed {
+		return "", errors_constant.InvalidEmailToken
+	}
+	return claims.email, nil
+}
+
+func (s *AuthService) sendVerification(ctx context.Context, userID int, email string) error {
+	token, err := s.keys.Sign(jwt.MapClaims{
+		"typ":     emailVerifyTokenType,
+		"user_id": userID,
+		"email":   email,
+		"exp":     time.Now().Add(s.conf.EmailTokenTTL).Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf("Follow the link to confirm your email address:\n\n%s\n\nThe link expires in %s.",
+			s.link("/verify-email", token), s.conf.EmailTokenTTL),
+	})
+}
+
+// cooldown ограничивает частоту писем по ключу
+func (s *AuthService) cooldown(ctx context.Context, key string) error {
+	if s.conf.EmailResendInterval <= 0 {
+		return nil
+	}
+	acquired, err := s.repo.AcquireCooldown(ctx, key, s.conf.EmailResendInterval)
+	if err != nil {
+		return fmt.Errorf("failed to check email rate limit: %w", err)
+	}
+	if !acquired {
+		return errors_constant.EmailResendTooSoon
+	}
+	return nil
+}
+
+func (s *AuthService) link(path, token string) string {
+	return s.conf.PublicURL + path + "?token=" + url.QueryEscape(token)
+}
+
+type emailClaims struct {
+	userID int
+	email  string
+	from   string
+}
+
+func (s *AuthService) parseEmailToken(token, typ string) (*emailClaims, error) {
+	claims, err := s.keys.Parse(token)
+	if err != nil || claims["typ"] != typ {
+		return nil, errors_constant.InvalidEmailToken
+	}
+
+	userID, okUser := claims["user_id"].(float64)
+	email, okEmail := claims["email"].(string)
+	if !okUser || !okEmail {
+		return nil, errors_constant.InvalidEmailToken
+	}
+	from, _ := claims["from"].(string)
+	return &emailClaims{userID: int(userID), email: email, from: from}, nil
+}
@@ -4,7 +4,10 @@ import (
 	"errors"
 	"mpb/internal/auth/dto"
 	"mpb/pkg/errors_constant"
+	"mpb/pkg/jwtkeys"
 	"mpb/pkg/middleware"
+	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -19,13 +22,14 @@ func NewAuthHandlers(authService *AuthService) *AuthHandlers {
 
 // Login godoc
 // @Summary Login user
-// @Description Authenticate user and get JWT
+// @Description Authenticate user and get JWT. With two-factor authentication enabled, returns only mfa_required and mfa_token to exchange at /api/auth/login/mfa.
 // @Tags Auth
 // @Accept json
 // @Produce json
 // @Param request body dto.LoginRequest true "Login credentials"
 // @Success 200 {object} dto.LoginResponse
 // @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Router /api/auth/login [post]
 func (handler *AuthHandlers) Login(c *fiber.Ctx) error {
 	req := middleware.Body[dto.LoginRequest](c)
@@ -33,8 +37,14 @@ func (handler *AuthHandlers) Login(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
 	}
 
-	resp, err := handler.AuthService.Login(req.Username, req.Password)
+	client := clientInfo(c)
+	client.Device = req.Device
+
+	resp, err := handler.AuthService.Login(c.Context(), req.Username, req.Password, client)
 	if err != nil {
+		if errors.Is(err, errors_constant.UserBanned) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+		}
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
 	}
 
@@ -43,7 +53,7 @@ func (handler *AuthHandlers) Login(c *fiber.Ctx) error {
 
 // Register AuthHandlers godoc
 // @Summary Register user
-// @Description Create a new user account
+// @Description Create a new user account and send an email verification link
 // @Tags Auth
 // @Accept json
 // @Produce json
@@ -57,8 +67,8 @@ func (handler *AuthHandlers) Register(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
 	}
 
-	if err := handler.AuthService.Register(*req); err != nil {
-		if errors.Is(err, errors_constant.UserAlreadyExists) {
+	if err := handler.AuthService.Register(c.Context(), *req); err != nil {
+		if errors.Is(err, errors_constant.UserAlreadyExists) || errors.Is(err, errors_constant.EmailTaken) {
 			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
@@ -69,7 +79,7 @@ func (handler *AuthHandlers) Register(c *fiber.Ctx) error {
 
 // Refresh godoc
 // @Summary Refresh tokens
-// @Description Get new access and refresh token
+// @Description Get new access and refresh token. The refresh token is single-use: presenting an already rotated token revokes its session.
 // @Tags Auth
 // @Accept json
 // @Produce json
@@ -83,10 +93,585 @@ func (h *AuthHandlers) Refresh(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
 	}
 
-	resp, err := h.AuthService.Refresh(req.RefreshToken)
+	resp, err := h.AuthService.Refresh(c.Context(), req.RefreshToken, clientInfo(c))
 	if err != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
 	}
 
 	return c.JSON(resp)
 }
+
+// ListSessions godoc
+// @Summary Active sessions
+// @Description Devices the user is logged in on, most recently active first
+// @Tags Auth
+// @Produce json
+// @Success 200 {array} dto.SessionResponse
+// @Failure 401 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/auth/sessions [get]
+func (h *AuthHandlers) ListSessions(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(int)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "user not authenticated"})
+	}
+
+	sessions, err := h.AuthService.Sessions(c.Context(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	currentID, _ := c.Locals("session_id").(int)
+	resp := make([]dto.SessionResponse, len(sessions))
+	for i, s := range sessions {
+		resp[i] = dto.SessionResponse{
+			ID:         s.ID,
+			Device:     s.Device,
+			IP:         s.IP,
+			UserAgent:  s.UserAgent,
+			Current:    s.ID == currentID,
+			CreatedAt:  s.CreatedAt,
+			LastSeenAt: s.LastSeenAt,
+			ExpiresAt:  s.ExpiresAt,
+		}
+	}
+
+	return c.JSON(resp)
+}
+
+// RevokeSession godoc
+// @Summary Log out a session
+// @Description Revokes the session's refresh and access tokens
+// @Tags Auth
+// @Param id path int true "Session ID"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/auth/sessions/{id} [delete]
+func (h *AuthHandlers) RevokeSession(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid session id"})
+	}
+
+	userID, ok := c.Locals("user_id").(int)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "user not authenticated"})
+	}
+
+	if err := h.AuthService.RevokeSession(c.Context(), userID, id); err != nil {
+		if errors.Is(err, errors_constant.SessionNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// RevokeAllSessions godoc
+// @Summary Log out everywhere
+// @Description Revokes every session of the user, including the current one
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} map[string]int64
+// @Failure 401 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/auth/sessions [delete]
+func (h *AuthHandlers) RevokeAllSessions(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(int)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "user not authenticated"})
+	}
+
+	revoked, err := h.AuthService.RevokeAllSessions(c.Context(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"revoked": revoked})
+}
+
+// Logout godoc
+// @Summary Logout
+// @Description Revokes the presented access token and the current session
+// @Tags Auth
+// @Success 204
+// @Failure 401 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/auth/logout [post]
+func (h *AuthHandlers) Logout(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(int)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "user not authenticated"})
+	}
+	sessionID, _ := c.Locals("session_id").(int)
+	jti, _ := c.Locals("token_id").(string)
+	exp, _ := c.Locals("token_exp").(time.Time)
+
+	if err := h.AuthService.Logout(c.Context(), userID, sessionID, jti, exp); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// BanUser godoc
+// @Summary Ban user
+// @Description Disables the account and immediately revokes all its sessions and tokens (admin only)
+// @Tags Admin
+// @Param id path int true "User ID"
+// @Success 204
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/admin/users/{id}/ban [post]
+func (h *AuthHandlers) BanUser(c *fiber.Ctx) error {
+	return h.setBanned(c, true)
+}
+
+// UnbanUser godoc
+// @Summary Unban user
+// @Description Allows the account to log in again (admin only)
+// @Tags Admin
+// @Param id path int true "User ID"
+// @Success 204
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/admin/users/{id}/ban [delete]
+func (h *AuthHandlers) UnbanUser(c *fiber.Ctx) error {
+	return h.setBanned(c, false)
+}
+
+func (h *AuthHandlers) setBanned(c *fiber.Ctx, banned bool) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid user id"})
+	}
+
+	if banned {
+		err = h.AuthService.BanUser(c.Context(), id)
+	} else {
+		err = h.AuthService.UnbanUser(c.Context(), id)
+	}
+	if err != nil {
+		if errors.Is(err, errors_constant.UserNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// VerifyEmail godoc
+// @Summary Verify email
+// @Description Confirms the address with the token from the verification link. Refresh tokens afterwards to get an access token with email_verified set.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dto.EmailTokenRequest true "Token from the link"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Router /api/auth/verify-email [post]
+func (h *AuthHandlers) VerifyEmail(c *fiber.Ctx) error {
+	req := middleware.Body[dto.EmailTokenRequest](c)
+	if req == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+	}
+
+	if err := h.AuthService.VerifyEmail(c.Context(), req.Token); err != nil {
+		return emailError(c, err)
+	}
+
+	return c.JSON(fiber.Map{"message": "email verified"})
+}
+
+// ResendVerification godoc
+// @Summary Resend verification email
+// @Description Sends a new verification link, at most once per EMAIL_RESEND_INTERVAL
+// @Tags Auth
+// @Produce json
+// @Success 202 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/auth/verify-email/resend [post]
+func (h *AuthHandlers) ResendVerification(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(int)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "user not authenticated"})
+	}
+
+	if err := h.AuthService.ResendVerification(c.Context(), userID); err != nil {
+		return emailError(c, err)
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"message": "verification email sent"})
+}
+
+// ChangeEmail godoc
+// @Summary Change email
+// @Description Sends a confirmation link to the new address; the current address stays in use until the link is followed
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dto.ChangeEmailRequest true "New email and current password"
+// @Success 202 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/auth/email [post]
+func (h *AuthHandlers) ChangeEmail(c *fiber.Ctx) error {
+	req := middleware.Body[dto.ChangeEmailRequest](c)
+	if req == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+	}
+
+	userID, ok := c.Locals("user_id").(int)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "user not authenticated"})
+	}
+
+	if err := h.AuthService.RequestEmailChange(c.Context(), userID, req.Password, req.Email); err != nil {
+		return emailError(c, err)
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"message": "confirmation email sent"})
+}
+
+// ConfirmEmailChange godoc
+// @Summary Confirm email change
+// @Description Switches the account to the new address with the token from the confirmation link
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dto.EmailTokenRequest true "Token from the link"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/auth/email/confirm [post]
+func (h *AuthHandlers) ConfirmEmailChange(c *fiber.Ctx) error {
+	req := middleware.Body[dto.EmailTokenRequest](c)
+	if req == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+	}
+
+	email, err := h.AuthService.ConfirmEmailChange(c.Context(), req.Token)
+	if err != nil {
+		return emailError(c, err)
+	}
+
+	return c.JSON(fiber.Map{"email": email})
+}
+
+func emailError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, errors_constant.InvalidEmailToken), errors.Is(err, errors_constant.EmailUnchanged):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, errors_constant.InvalidPassword):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, errors_constant.UserNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, errors_constant.EmailTaken), errors.Is(err, errors_constant.EmailAlreadyVerified):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, errors_constant.EmailResendTooSoon):
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+}
+
+// ForgotPassword godoc
+// @Summary Forgot password
+// @Description Emails a single-use password reset link. The response is the same whether or not the account exists.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dto.ForgotPasswordRequest true "Account email"
+// @Success 202 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Router /api/auth/password/forgot [post]
+func (h *AuthHandlers) ForgotPassword(c *fiber.Ctx) error {
+	req := middleware.Body[dto.ForgotPasswordRequest](c)
+	if req == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+	}
+
+	h.AuthService.ForgotPassword(req.Email)
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"message": "if the account exists, a reset link has been sent"})
+}
+
+// ResetPassword godoc
+// @Summary Reset password
+// @Description Sets a new password with the token from the reset link and logs out every session
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dto.ResetPasswordRequest true "Token and new password"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Router /api/auth/password/reset [post]
+func (h *AuthHandlers) ResetPassword(c *fiber.Ctx) error {
+	req := middleware.Body[dto.ResetPasswordRequest](c)
+	if req == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+	}
+
+	if err := h.AuthService.ResetPassword(c.Context(), req.Token, req.Password); err != nil {
+		return passwordError(c, err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ChangePassword godoc
+// @Summary Change password
+// @Description Changes the password of the logged-in user and logs out every session, including the current one
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dto.ChangePasswordRequest true "Current and new password"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/auth/password [put]
+func (h *AuthHandlers) ChangePassword(c *fiber.Ctx) error {
+	req := middleware.Body[dto.ChangePasswordRequest](c)
+	if req == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+	}
+
+	userID, ok := c.Locals("user_id").(int)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "user not authenticated"})
+	}
+
+	if err := h.AuthService.ChangePassword(c.Context(), userID, req.CurrentPassword, req.NewPassword); err != nil {
+		return passwordError(c, err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func passwordError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, errors_constant.InvalidResetToken), errors.Is(err, errors_constant.PasswordUnchanged):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, errors_constant.InvalidPassword):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, errors_constant.UserNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+}
+
+// LoginMFA godoc
+// @Summary Complete login with a second factor
+// @Description Exchanges the mfa_token from /api/auth/login and an authenticator code or a recovery code for JWT and refresh tokens. The mfa_token is single-use.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dto.LoginMFARequest true "MFA token and code"
+// @Success 200 {object} dto.LoginResponse
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Router /api/auth/login/mfa [post]
+func (h *AuthHandlers) LoginMFA(c *fiber.Ctx) error {
+	req := middleware.Body[dto.LoginMFARequest](c)
+	if req == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+	}
+
+	resp, err := h.AuthService.LoginMFA(c.Context(), req.MFAToken, req.Code, clientInfo(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, errors_constant.InvalidMFAToken), errors.Is(err, errors_constant.InvalidMFACode):
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, errors_constant.UserBanned):
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, errors_constant.TooManyMFAAttempts):
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": err.Error()})
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+
+	return c.JSON(resp)
+}
+
+// EnrollTOTP godoc
+// @Summary Start two-factor enrollment
+// @Description Creates a TOTP secret and returns it as an otpauth URI and a base64 QR PNG. Nothing changes at login until the secret is confirmed at /api/auth/2fa/verify.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dto.EnrollTOTPRequest true "Current password"
+// @Success 200 {object} dto.TOTPEnrollResponse
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/auth/2fa/enroll [post]
+func (h *AuthHandlers) EnrollTOTP(c *fiber.Ctx) error {
+	req := middleware.Body[dto.EnrollTOTPRequest](c)
+	if req == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+	}
+
+	userID, ok := c.Locals("user_id").(int)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "user not authenticated"})
+	}
+
+	enrollment, err := h.AuthService.EnrollTOTP(c.Context(), userID, req.Password)
+	if err != nil {
+		return mfaError(c, err)
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.JSON(dto.TOTPEnrollResponse{
+		Secret:     enrollment.Secret,
+		OTPAuthURL: enrollment.URL,
+		QRCode:     enrollment.QRCode,
+	})
+}
+
+// ConfirmTOTP godoc
+// @Summary Confirm two-factor enrollment
+// @Description Enables two-factor authentication with a code from the authenticator app and returns recovery codes. They are shown only once.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dto.TOTPCodeRequest true "Authenticator code"
+// @Success 200 {object} dto.RecoveryCodesResponse
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/auth/2fa/verify [post]
+func (h *AuthHandlers) ConfirmTOTP(c *fiber.Ctx) error {
+	req := middleware.Body[dto.TOTPCodeRequest](c)
+	if req == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+	}
+
+	userID, ok := c.Locals("user_id").(int)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "user not authenticated"})
+	}
+
+	codes, err := h.AuthService.ConfirmTOTP(c.Context(), userID, req.Code)
+	if err != nil {
+		return mfaError(c, err)
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.JSON(dto.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Regenerate recovery codes
+// @Description Replaces all recovery codes; requires a code from the authenticator app
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body dto.TOTPCodeRequest true "Authenticator code"
+// @Success 200 {object} dto.RecoveryCodesResponse
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/auth/2fa/recovery-codes [post]
+func (h *AuthHandlers) RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	req := middleware.Body[dto.TOTPCodeRequest](c)
+	if req == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+	}
+
+	userID, ok := c.Locals("user_id").(int)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "user not authenticated"})
+	}
+
+	codes, err := h.AuthService.RegenerateRecoveryCodes(c.Context(), userID, req.Code)
+	if err != nil {
+		return mfaError(c, err)
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.JSON(dto.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableTOTP godoc
+// @Summary Disable two-factor authentication
+// @Description Requires the current password and an authenticator code or a recovery code
+// @Tags Auth
+// @Accept json
+// @Param request body dto.DisableTOTPRequest true "Password and code"
+// @Success 204
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/auth/2fa [delete]
+func (h *AuthHandlers) DisableTOTP(c *fiber.Ctx) error {
+	req := middleware.Body[dto.DisableTOTPRequest](c)
+	if req == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+	}
+
+	userID, ok := c.Locals("user_id").(int)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "user not authenticated"})
+	}
+
+	if err := h.AuthService.DisableTOTP(c.Context(), userID, req.Password, req.Code); err != nil {
+		return mfaError(c, err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func mfaError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, errors_constant.InvalidPassword), errors.Is(err, errors_constant.InvalidMFACode):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, errors_constant.UserNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, errors_constant.TOTPAlreadyEnabled), errors.Is(err, errors_constant.TOTPNotEnabled):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, errors_constant.TooManyMFAAttempts):
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+}
+
+// JWKSHandler godoc
+// @Summary JSON Web Key Set
+// @Description Public keys for verifying access tokens, selected by the kid header
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} jwtkeys.JWKS
+// @Router /.well-known/jwks.json [get]
+func JWKSHandler(keys *jwtkeys.KeySet) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+		return c.JSON(keys.JWKS())
+	}
+}
+
+// clientInfo собирает адрес и user agent клиента; слишком длинный user agent обрезается
+func clientInfo(c *fiber.Ctx) ClientInfo {
+	userAgent := c.Get(fiber.HeaderUserAgent)
+	if len(userAgent) > 512 {
+		userAgent = userAgent[:512]
+	}
+	return ClientInfo{IP: c.IP(), UserAgent: userAgent}
+}
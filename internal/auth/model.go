@@ -0,0 +1,37 @@
+package auth
+
+import "time"
+
+// Session — вход пользователя с одного устройства; refresh-токены сессии образуют одно семейство
+type Session struct {
+	ID         int        `db:"id"`
+	UserID     int        `db:"user_id"`
+	Device     string     `db:"device"`
+	IP         string     `db:"ip"`
+	UserAgent  string     `db:"user_agent"`
+	RefreshJTI string     `db:"refresh_jti"`
+	CreatedAt  time.Time  `db:"created_at"`
+	LastSeenAt time.Time  `db:"last_seen_at"`
+	ExpiresAt  time.Time  `db:"expires_at"`
+	RevokedAt  *time.Time `db:"revoked_at"`
+}
+
+// Active сообщает, что сессия не отозвана и не истекла
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// ClientInfo — сведения об устройстве из запроса на вход или обновление токенов
+type ClientInfo struct {
+	Device    string
+	IP        string
+	UserAgent string
+}
+
+// TOTP — второй фактор пользователя; до подтверждения кодом EnabledAt пуст и вход не меняется
+type TOTP struct {
+	UserID    int        `db:"user_id"`
+	Secret    string     `db:"secret"`
+	CreatedAt time.Time  `db:"created_at"`
+	EnabledAt *time.Time `db:"enabled_at"`
+}
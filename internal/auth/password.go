@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mpb/pkg/errors_constant"
+	"mpb/pkg/mailer"
+	"mpb/pkg/security"
+	"net/url"
+	"time"
+)
+
+// passwordResetTimeout ограничивает фоновую отправку письма сброса
+const passwordResetTimeout = 30 * time.Second
+
+// ForgotPassword запускает отправку ссылки сброса и сразу возвращает управление:
+// ответ не зависит от того, есть ли учётная запись, ни содержимым, ни временем.
+func (s *AuthService) ForgotPassword(email string) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), passwordResetTimeout)
+		defer cancel()
+
+		if err := s.sendPasswordReset(ctx, email); err != nil {
+			s.logger.Error("failed to send password reset email", err, nil)
+		}
+	}()
+}
+
+// sendPasswordReset отправляет одноразовую ссылку сброса; в базе хранится только хеш токена.
+// Неизвестный адрес, заблокированная учётная запись и слишком частые запросы молча пропускаются.
+func (s *AuthService) sendPasswordReset(ctx context.Context, email string) error {
+	user, err := s.repo.FindByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, errors_constant.UserNotFound) {
+			return nil
+		}
+		return err
+	}
+	if !user.IsActive {
+		return nil
+	}
+
+	if err := s.cooldown(ctx, fmt.Sprintf("password_reset:%d", user.ID)); err != nil {
+		if errors.Is(err, errors_constant.EmailResendTooSoon) {
+			return nil
+		}
+		return err
+	}
+
+	token, err := security.RandomToken(32)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.CreatePasswordReset(ctx, user.ID, security.HashToken(token), time.Now().Add(s.conf.PasswordResetTTL)); err != nil {
+		return fmt.Errorf("failed to save password reset: %w", err)
+	}
+
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Follow the link to choose a new password:\n\n%s\n\nThe link expires in %s and works once. "+
+			"If you didn't request a reset, ignore this email.",
+			s.conf.PublicURL+"/reset-password?token="+url.QueryEscape(token), s.conf.PasswordResetTTL),
+	})
+}
+
+// ResetPassword ставит новый пароль по токену из письма и завершает все сессии пользователя
+func (s *AuthService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	hashed, err := security.HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	userID, err := s.repo.ResetPassword(ctx, security.HashToken(token), hashed)
+	if err != nil {
+		if errors.Is(err, errors_constant.InvalidResetToken) {
+			return err
+		}
+		return fmt.Errorf("failed to reset password: %w", err)
+	}
+
+	return s.passwordChanged(ctx, userID)
+}
+
+// ChangePassword меняет пароль по текущему паролю и завершает все сессии пользователя, включая текущую
+func (s *AuthService) ChangePassword(ctx context.Context, userID int, currentPassword, newPassword string) error {
+	user, err := s.repo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if !security.CheckPasswordHash(currentPassword, user.PasswordHash) {
+		return errors_constant.InvalidPassword
+	}
+	if currentPassword == newPassword {
+		return errors_constant.PasswordUnchanged
+	}
+
+	hashed, err := security.HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	updated, err := s.repo.UpdatePassword(ctx, userID, hashed)
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+	if !updated {
+		return errors_constant.UserNotFound
+	}
+
+	return s.passwordChanged(ctx, userID)
+}
+
+// passwordChanged отзывает все сессии и токены и уведомляет владельца; сбой уведомления только логируется
+func (s *AuthService) passwordChanged(ctx context.Context, userID int) error {
+	if _, err := s.revokeAll(ctx, userID); err != nil {
+		return err
+	}
+
+	s.notify(ctx, userID, "Your password was changed",
+		"The password of your account was changed and all sessions were logged out. If this wasn't you, reset your password.")
+	return nil
+}
@@ -11,6 +11,8 @@ import (
 	"mpb/pkg/errors_constant"
 	"time"
 
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -28,36 +30,26 @@ func NewAuthRepository(config *configs.Config, database *db.Db, red *redis.Clien
 	}
 }
 
-func (repo *AuthRepository) SetRefreshToken(userId int, token string, ttl time.Duration) error {
-	key := fmt.Sprintf("refresh_token:%d", userId)
-	return repo.redis.Set(context.Background(), key, token, ttl).Err()
-}
-
-func (repo *AuthRepository) GetRefreshToken(userId int) (string, error) {
-	key := fmt.Sprintf("refresh_token:%d", userId)
-	return repo.redis.Get(context.Background(), key).Result()
-}
-
-func (repo *AuthRepository) DeleteRefreshToken(userId int) error {
-	key := fmt.Sprintf("refresh_token:%d", userId)
-	return repo.redis.Del(context.Background(), key).Err()
-}
-
-func (repo *AuthRepository) Register(username, passwordHash, email, name string, age int) error {
+// Register создаёт пользователя и возвращает его id; занятый адрес — EmailTaken
+func (repo *AuthRepository) Register(username, passwordHash, email, name string, age int) (int, error) {
 	var exists bool
 	err := repo.db.Conn.Get(&exists, `SELECT EXISTS (SELECT 1 FROM users WHERE username = $1)`, username)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	if exists {
-		return errors_constant.UserAlreadyExists
+		return 0, errors_constant.UserAlreadyExists
 	}
 
-	_, err = repo.db.Conn.Exec(
-		`INSERT INTO users (username, password_hash, email, name, age, is_active) VALUES ($1,$2,$3,$4,$5, TRUE)`,
+	var id int
+	err = repo.db.Conn.Get(&id,
+		`INSERT INTO users (username, password_hash, email, name, age, is_active) VALUES ($1,$2,$3,$4,$5, TRUE) RETURNING id`,
 		username, passwordHash, email, name, age,
 	)
-	return err
+	if isUniqueViolation(err) {
+		return 0, errors_constant.EmailTaken
+	}
+	return id, err
 }
 
 func (repo *AuthRepository) FindByUsername(username string) (*model.User, error) {
@@ -71,3 +63,354 @@ func (repo *AuthRepository) FindByUsername(username string) (*model.User, error)
 	}
 	return &user, nil
 }
+
+func (repo *AuthRepository) FindByID(userID int) (*model.User, error) {
+	var user model.User
+	err := repo.db.Conn.Get(&user, `SELECT * FROM users WHERE id = $1 AND deleted_at IS NULL`, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors_constant.UserNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// CreateSession сохраняет новую сессию и заодно удаляет истёкшие сессии пользователя
+func (repo *AuthRepository) CreateSession(ctx context.Context, s *Session) error {
+	if _, err := repo.db.Conn.ExecContext(ctx,
+		`DELETE FROM sessions WHERE user_id = $1 AND expires_at < NOW()`, s.UserID,
+	); err != nil {
+		return fmt.Errorf("failed to prune sessions: %w", err)
+	}
+
+	return repo.db.Conn.QueryRowxContext(ctx, `
+		INSERT INTO sessions (user_id, device, ip, user_agent, refresh_jti, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, last_seen_at`,
+		s.UserID, s.Device, s.IP, s.UserAgent, s.RefreshJTI, s.ExpiresAt,
+	).Scan(&s.ID, &s.CreatedAt, &s.LastSeenAt)
+}
+
+func (repo *AuthRepository) FindSession(ctx context.Context, sessionID int) (*Session, error) {
+	var session Session
+	err := repo.db.Conn.GetContext(ctx, &session, `SELECT * FROM sessions WHERE id = $1`, sessionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors_constant.SessionNotFound
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// RotateSession заменяет jti действующего refresh-токена, только если предъявлен именно он.
+// false означает, что сессия отозвана, истекла или oldJTI уже был заменён.
+func (repo *AuthRepository) RotateSession(ctx context.Context, sessionID int, oldJTI, newJTI string, client ClientInfo, expiresAt time.Time) (bool, error) {
+	res, err := repo.db.Conn.ExecContext(ctx, `
+		UPDATE sessions
+		SET refresh_jti = $3, ip = $4, user_agent = $5, last_seen_at = NOW(), expires_at = $6
+		WHERE id = $1 AND refresh_jti = $2 AND revoked_at IS NULL AND expires_at > NOW()`,
+		sessionID, oldJTI, newJTI, client.IP, client.UserAgent, expiresAt,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ListSessions возвращает действующие сессии пользователя, последние активные первыми
+func (repo *AuthRepository) ListSessions(ctx context.Context, userID int) ([]Session, error) {
+	sessions := []Session{}
+	err := repo.db.Conn.SelectContext(ctx, &sessions, `
+		SELECT * FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY last_seen_at DESC, id DESC`, userID)
+	return sessions, err
+}
+
+// RevokeSession отзывает действующую сессию пользователя; false — такой сессии нет
+func (repo *AuthRepository) RevokeSession(ctx context.Context, userID, sessionID int) (bool, error) {
+	res, err := repo.db.Conn.ExecContext(ctx, `
+		UPDATE sessions SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`, sessionID, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// RevokeUserSessions отзывает все сессии пользователя и возвращает их число
+func (repo *AuthRepository) RevokeUserSessions(ctx context.Context, userID int) (int64, error) {
+	res, err := repo.db.Conn.ExecContext(ctx,
+		`UPDATE sessions SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`, userID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// SetUserActive включает или выключает учётную запись; false — пользователя нет
+func (repo *AuthRepository) SetUserActive(ctx context.Context, userID int, active bool) (bool, error) {
+	res, err := repo.db.Conn.ExecContext(ctx,
+		`UPDATE users SET is_active = $2 WHERE id = $1 AND deleted_at IS NULL`, userID, active)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// EmailTaken сообщает, что адрес уже принадлежит какой-либо учётной записи
+func (repo *AuthRepository) EmailTaken(ctx context.Context, email string) (bool, error) {
+	var taken bool
+	err := repo.db.Conn.GetContext(ctx, &taken, `SELECT EXISTS (SELECT 1 FROM users WHERE email = $1)`, email)
+	return taken, err
+}
+
+// MarkEmailVerified подтверждает адрес, если он всё ещё принадлежит пользователю; false — адрес с тех пор сменился
+func (repo *AuthRepository) MarkEmailVerified(ctx context.Context, userID int, email string) (bool, error) {
+	res, err := repo.db.Conn.ExecContext(ctx, `
+		UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW())
+		WHERE id = $1 AND email = $2 AND deleted_at IS NULL`, userID, email)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ChangeEmail меняет адрес from на подтверждённый адрес to.
+// false — адрес уже сменился, поэтому каждая ссылка смены срабатывает один раз.
+func (repo *AuthRepository) ChangeEmail(ctx context.Context, userID int, from, to string) (bool, error) {
+	res, err := repo.db.Conn.ExecContext(ctx, `
+		UPDATE users SET email = $3, email_verified_at = NOW()
+		WHERE id = $1 AND COALESCE(email, '') = $2 AND deleted_at IS NULL`, userID, from, to)
+	if isUniqueViolation(err) {
+		return false, errors_constant.EmailTaken
+	}
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// AcquireCooldown занимает ключ на ttl; false — ключ ещё занят предыдущим вызовом
+func (repo *AuthRepository) AcquireCooldown(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	return repo.redis.SetNX(ctx, key, 1, ttl).Result()
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// FindByEmail ищет действующую учётную запись по адресу
+func (repo *AuthRepository) FindByEmail(ctx context.Context, email string) (*model.User, error) {
+	var user model.User
+	err := repo.db.Conn.GetContext(ctx, &user, `SELECT * FROM users WHERE email = $1 AND deleted_at IS NULL`, email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors_constant.UserNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// CreatePasswordReset сохраняет хеш токена сброса и удаляет отработавшие токены пользователя
+func (repo *AuthRepository) CreatePasswordReset(ctx context.Context, userID int, tokenHash string, expiresAt time.Time) error {
+	if _, err := repo.db.Conn.ExecContext(ctx,
+		`DELETE FROM password_resets WHERE user_id = $1 AND (used_at IS NOT NULL OR expires_at < NOW())`, userID,
+	); err != nil {
+		return fmt.Errorf("failed to prune password resets: %w", err)
+	}
+
+	_, err := repo.db.Conn.ExecContext(ctx,
+		`INSERT INTO password_resets (user_id, token_hash, expires_at) VALUES ($1, $2, $3)`,
+		userID, tokenHash, expiresAt,
+	)
+	return err
+}
+
+// ResetPassword погашает токен сброса и ставит новый пароль в одной транзакции; возвращает id пользователя.
+// Остальные выданные пользователю токены сброса гасятся вместе с ним.
+func (repo *AuthRepository) ResetPassword(ctx context.Context, tokenHash, passwordHash string) (int, error) {
+	tx, err := repo.db.Conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var userID int
+	err = tx.GetContext(ctx, &userID, `
+		UPDATE password_resets SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id`, tokenHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, errors_constant.InvalidResetToken
+		}
+		return 0, err
+	}
+
+	res, err := tx.ExecContext(ctx,
+		`UPDATE users SET password_hash = $2 WHERE id = $1 AND deleted_at IS NULL`, userID, passwordHash)
+	if err != nil {
+		return 0, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return 0, errors_constant.InvalidResetToken
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE password_resets SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL`, userID,
+	); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit: %w", err)
+	}
+	return userID, nil
+}
+
+// UpdatePassword меняет пароль и гасит невостребованные токены сброса; false — пользователя нет
+func (repo *AuthRepository) UpdatePassword(ctx context.Context, userID int, passwordHash string) (bool, error) {
+	res, err := repo.db.Conn.ExecContext(ctx,
+		`UPDATE users SET password_hash = $2 WHERE id = $1 AND deleted_at IS NULL`, userID, passwordHash)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil || n == 0 {
+		return false, err
+	}
+
+	_, err = repo.db.Conn.ExecContext(ctx,
+		`UPDATE password_resets SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL`, userID)
+	return true, err
+}
+
+// FindTOTP возвращает второй фактор пользователя; TOTPNotEnabled — регистрация не начиналась
+func (repo *AuthRepository) FindTOTP(ctx context.Context, userID int) (*TOTP, error) {
+	var t TOTP
+	err := repo.db.Conn.GetContext(ctx, &t, `SELECT * FROM user_totp WHERE user_id = $1`, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors_constant.TOTPNotEnabled
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+// SaveTOTPSecret начинает или перезапускает регистрацию; false — второй фактор уже включён
+func (repo *AuthRepository) SaveTOTPSecret(ctx context.Context, userID int, secret string) (bool, error) {
+	res, err := repo.db.Conn.ExecContext(ctx, `
+		INSERT INTO user_totp (user_id, secret) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, created_at = NOW()
+		WHERE user_totp.enabled_at IS NULL`, userID, secret)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// EnableTOTP включает второй фактор и сохраняет коды восстановления; false — регистрация не начата или уже завершена
+func (repo *AuthRepository) EnableTOTP(ctx context.Context, userID int, codeHashes []string) (bool, error) {
+	tx, err := repo.db.Conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		`UPDATE user_totp SET enabled_at = NOW() WHERE user_id = $1 AND enabled_at IS NULL`, userID)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit: %w", err)
+	}
+	return true, nil
+}
+
+// ReplaceRecoveryCodes заменяет все коды восстановления пользователя новыми
+func (repo *AuthRepository) ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error {
+	tx, err := repo.db.Conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+	return nil
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sqlx.Tx, userID int, codeHashes []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	const insert = `INSERT INTO recovery_codes (user_id, code_hash) SELECT $1, unnest($2::text[])`
+	if _, err := tx.ExecContext(ctx, insert, userID, pq.Array(codeHashes)); err != nil {
+		return fmt.Errorf("failed to save recovery codes: %w", err)
+	}
+	return nil
+}
+
+// UseRecoveryCode погашает код восстановления; false — кода нет или он уже использован
+func (repo *AuthRepository) UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error) {
+	res, err := repo.db.Conn.ExecContext(ctx, `
+		UPDATE recovery_codes SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// DisableTOTP удаляет второй фактор вместе с кодами восстановления
+func (repo *AuthRepository) DisableTOTP(ctx context.Context, userID int) error {
+	tx, err := repo.db.Conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_totp WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CountAttempt увеличивает счётчик попыток по ключу; окно ttl отсчитывается от первой попытки
+func (repo *AuthRepository) CountAttempt(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	pipe := repo.redis.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.ExpireNX(ctx, key, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
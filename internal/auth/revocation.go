@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"mpb/pkg/middleware"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// maxCachedTokens ограничивает локальный кэш: при переполнении он сбрасывается целиком
+const maxCachedTokens = 10000
+
+type cachedRevocation struct {
+	revoked bool
+	until   time.Time
+}
+
+// RevocationStore хранит в Redis отозванные access-токены (по jti), отозванные сессии и для каждого пользователя
+// отметку времени, раньше которой выданные токены недействительны. Ключи живут не дольше access-токена.
+// Ответы кэшируются локально на cacheTTL, поэтому другие инстансы видят отзыв с этой задержкой.
+type RevocationStore struct {
+	redis    *redis.Client
+	tokenTTL time.Duration
+	cacheTTL time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cachedRevocation
+}
+
+func NewRevocationStore(client *redis.Client, tokenTTL, cacheTTL time.Duration) *RevocationStore {
+	return &RevocationStore{
+		redis:    client,
+		tokenTTL: tokenTTL,
+		cacheTTL: cacheTTL,
+		cache:    make(map[string]cachedRevocation),
+	}
+}
+
+func revokedTokenKey(jti string) string {
+	return "revoked_token:" + jti
+}
+
+func revokedSessionKey(sessionID int) string {
+	return fmt.Sprintf("revoked_session:%d", sessionID)
+}
+
+func tokensValidAfterKey(userID int) string {
+	return fmt.Sprintf("tokens_valid_after:%d", userID)
+}
+
+// RevokeToken отзывает один access-токен до его истечения
+func (s *RevocationStore) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if jti == "" || ttl <= 0 {
+		return nil
+	}
+	if err := s.redis.Set(ctx, revokedTokenKey(jti), 1, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+
+	s.mu.Lock()
+	s.cache[jti] = cachedRevocation{revoked: true, until: expiresAt}
+	s.mu.Unlock()
+	return nil
+}
+
+// RevokeSession отзывает все access-токены сессии
+func (s *RevocationStore) RevokeSession(ctx context.Context, sessionID int) error {
+	if err := s.redis.Set(ctx, revokedSessionKey(sessionID), 1, s.tokenTTL).Err(); err != nil {
+		return fmt.Errorf("failed to revoke session tokens: %w", err)
+	}
+	s.resetCache()
+	return nil
+}
+
+// RevokeUserTokens делает недействительными все токены пользователя, выданные не позже at.
+// iat хранится с точностью до секунды, поэтому отзываются и токены, выданные в ту же секунду:
+// иначе токен, полученный за мгновение до отзыва, остался бы действительным.
+func (s *RevocationStore) RevokeUserTokens(ctx context.Context, userID int, at time.Time) error {
+	if err := s.redis.Set(ctx, tokensValidAfterKey(userID), at.Unix(), s.tokenTTL).Err(); err != nil {
+		return fmt.Errorf("failed to revoke user tokens: %w", err)
+	}
+	s.resetCache()
+	return nil
+}
+
+// IsRevoked проверяет jti, сессию и отметку пользователя одним запросом к Redis
+func (s *RevocationStore) IsRevoked(ctx context.Context, claims middleware.TokenClaims) (bool, error) {
+	now := time.Now()
+	if claims.ID != "" {
+		s.mu.Lock()
+		cached, ok := s.cache[claims.ID]
+		s.mu.Unlock()
+		if ok && now.Before(cached.until) {
+			return cached.revoked, nil
+		}
+	}
+
+	values, err := s.redis.MGet(ctx,
+		revokedTokenKey(claims.ID),
+		revokedSessionKey(claims.SessionID),
+		tokensValidAfterKey(claims.UserID),
+	).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+
+	revoked := values[0] != nil || values[1] != nil
+	if raw, ok := values[2].(string); ok {
+		if validAfter, err := strconv.ParseInt(raw, 10, 64); err == nil && claims.IssuedAt.Unix() <= validAfter {
+			revoked = true
+		}
+	}
+
+	if claims.ID != "" {
+		s.mu.Lock()
+		if len(s.cache) >= maxCachedTokens {
+			s.cache = make(map[string]cachedRevocation)
+		}
+		s.cache[claims.ID] = cachedRevocation{revoked: revoked, until: now.Add(s.cacheTTL)}
+		s.mu.Unlock()
+	}
+	return revoked, nil
+}
+
+// resetCache сбрасывает локальный кэш: отзыв сессии или пользователя затрагивает неизвестные заранее jti
+func (s *RevocationStore) resetCache() {
+	s.mu.Lock()
+	s.cache = make(map[string]cachedRevocation)
+	s.mu.Unlock()
+}
@@ -2,6 +2,8 @@ package auth
 
 import (
 	"mpb/internal/auth/dto"
+	"mpb/internal/user"
+	"mpb/pkg/jwtkeys"
 	"mpb/pkg/middleware"
 
 	"github.com/gofiber/fiber/v2"
@@ -10,10 +12,11 @@ import (
 type AuthRoutes struct {
 	router  fiber.Router
 	handler *AuthHandlers
+	jwtKeys *jwtkeys.KeySet
 }
 
-func NewAuthRoutes(router fiber.Router, handler *AuthHandlers) *AuthRoutes {
-	return &AuthRoutes{router: router, handler: handler}
+func NewAuthRoutes(router fiber.Router, handler *AuthHandlers, jwtKeys *jwtkeys.KeySet) *AuthRoutes {
+	return &AuthRoutes{router: router, handler: handler, jwtKeys: jwtKeys}
 }
 
 func (r *AuthRoutes) Register() {
@@ -29,8 +32,61 @@ func (r *AuthRoutes) Register() {
 		r.handler.Login,
 	)
 
+	auth.Post("/login/mfa",
+		middleware.ValidateBody[dto.LoginMFARequest](),
+		r.handler.LoginMFA,
+	)
+
 	auth.Post("/refresh",
 		middleware.ValidateBody[dto.RefreshRequest](),
 		r.handler.Refresh,
 	)
+
+	auth.Post("/verify-email",
+		middleware.ValidateBody[dto.EmailTokenRequest](),
+		r.handler.VerifyEmail,
+	)
+	auth.Post("/verify-email/resend", middleware.JWTAuth(r.jwtKeys), r.handler.ResendVerification)
+
+	auth.Post("/email",
+		middleware.JWTAuth(r.jwtKeys),
+		middleware.ValidateBody[dto.ChangeEmailRequest](),
+		r.handler.ChangeEmail,
+	)
+	auth.Post("/email/confirm",
+		middleware.ValidateBody[dto.EmailTokenRequest](),
+		r.handler.ConfirmEmailChange,
+	)
+
+	password := auth.Group("/password")
+	password.Post("/forgot",
+		middleware.ValidateBody[dto.ForgotPasswordRequest](),
+		r.handler.ForgotPassword,
+	)
+	password.Post("/reset",
+		middleware.ValidateBody[dto.ResetPasswordRequest](),
+		r.handler.ResetPassword,
+	)
+	password.Put("/",
+		middleware.JWTAuth(r.jwtKeys),
+		middleware.ValidateBody[dto.ChangePasswordRequest](),
+		r.handler.ChangePassword,
+	)
+
+	twoFactor := auth.Group("/2fa", middleware.JWTAuth(r.jwtKeys))
+	twoFactor.Post("/enroll", middleware.ValidateBody[dto.EnrollTOTPRequest](), r.handler.EnrollTOTP)
+	twoFactor.Post("/verify", middleware.ValidateBody[dto.TOTPCodeRequest](), r.handler.ConfirmTOTP)
+	twoFactor.Post("/recovery-codes", middleware.ValidateBody[dto.TOTPCodeRequest](), r.handler.RegenerateRecoveryCodes)
+	twoFactor.Delete("/", middleware.ValidateBody[dto.DisableTOTPRequest](), r.handler.DisableTOTP)
+
+	auth.Post("/logout", middleware.JWTAuth(r.jwtKeys), r.handler.Logout)
+
+	sessions := auth.Group("/sessions", middleware.JWTAuth(r.jwtKeys))
+	sessions.Get("/", r.handler.ListSessions)
+	sessions.Delete("/", r.handler.RevokeAllSessions)
+	sessions.Delete("/:id", r.handler.RevokeSession)
+
+	admin := r.router.Group("/admin/users", middleware.JWTAuth(r.jwtKeys), middleware.RequireRole(user.RoleAdmin))
+	admin.Post("/:id/ban", r.handler.BanUser)
+	admin.Delete("/:id/ban", r.handler.UnbanUser)
 }
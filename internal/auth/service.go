@@ -1,39 +1,105 @@
 package auth
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"mpb/configs"
 	"mpb/internal/auth/dto"
+	model "mpb/internal/user"
+	"mpb/pkg/errors_constant"
+	"mpb/pkg/jwtkeys"
+	"mpb/pkg/mailer"
 	"mpb/pkg/security"
 	"time"
 
+	"github.com/ThreeDotsLabs/watermill"
 	"github.com/golang-jwt/jwt/v5"
 )
 
+const refreshTokenType = "refresh"
+
+type AuthRepositoryInterface interface {
+	Register(username, passwordHash, email, name string, age int) (int, error)
+	FindByUsername(username string) (*model.User, error)
+	FindByID(userID int) (*model.User, error)
+	CreateSession(ctx context.Context, s *Session) error
+	FindSession(ctx context.Context, sessionID int) (*Session, error)
+	RotateSession(ctx context.Context, sessionID int, oldJTI, newJTI string, client ClientInfo, expiresAt time.Time) (bool, error)
+	ListSessions(ctx context.Context, userID int) ([]Session, error)
+	RevokeSession(ctx context.Context, userID, sessionID int) (bool, error)
+	RevokeUserSessions(ctx context.Context, userID int) (int64, error)
+	SetUserActive(ctx context.Context, userID int, active bool) (bool, error)
+	EmailTaken(ctx context.Context, email string) (bool, error)
+	MarkEmailVerified(ctx context.Context, userID int, email string) (bool, error)
+	ChangeEmail(ctx context.Context, userID int, from, to string) (bool, error)
+	AcquireCooldown(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	FindByEmail(ctx context.Context, email string) (*model.User, error)
+	CreatePasswordReset(ctx context.Context, userID int, tokenHash string, expiresAt time.Time) error
+	ResetPassword(ctx context.Context, tokenHash, passwordHash string) (int, error)
+	UpdatePassword(ctx context.Context, userID int, passwordHash string) (bool, error)
+	FindTOTP(ctx context.Context, userID int) (*TOTP, error)
+	SaveTOTPSecret(ctx context.Context, userID int, secret string) (bool, error)
+	EnableTOTP(ctx context.Context, userID int, codeHashes []string) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error
+	UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error)
+	DisableTOTP(ctx context.Context, userID int) error
+	CountAttempt(ctx context.Context, key string, ttl time.Duration) (int64, error)
+}
+
+// TokenRevoker отзывает уже выданные access-токены
+type TokenRevoker interface {
+	RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error
+	RevokeSession(ctx context.Context, sessionID int) error
+	RevokeUserTokens(ctx context.Context, userID int, at time.Time) error
+}
+
 type AuthService struct {
-	repo       *AuthRepository
-	jwtKey     []byte
+	repo       AuthRepositoryInterface
+	revoker    TokenRevoker
+	mailer     mailer.Mailer
+	keys       *jwtkeys.KeySet
+	logger     watermill.LoggerAdapter
 	tokenTTL   time.Duration
 	refreshTTL time.Duration
+	conf       configs.AuthConfig
 }
 
-func NewAuthService(repo *AuthRepository, jwtKey []byte, ttl time.Duration) *AuthService {
+func NewAuthService(repo AuthRepositoryInterface, revoker TokenRevoker, mail mailer.Mailer, keys *jwtkeys.KeySet, logger watermill.LoggerAdapter, jwtConf configs.JWTConfig, conf configs.AuthConfig) *AuthService {
 	return &AuthService{
 		repo:       repo,
-		jwtKey:     jwtKey,
-		tokenTTL:   ttl,
-		refreshTTL: 7 * 24 * time.Hour,
+		revoker:    revoker,
+		mailer:     mail,
+		keys:       keys,
+		logger:     logger,
+		tokenTTL:   jwtConf.AccessTokenTTL,
+		refreshTTL: jwtConf.RefreshTokenTTL,
+		conf:       conf,
 	}
 }
 
-func (s *AuthService) Register(req dto.RegisterRequest) error {
+// Register создаёт учётную запись и отправляет ссылку подтверждения адреса.
+// Сбой почты не отменяет регистрацию: письмо можно запросить повторно.
+func (s *AuthService) Register(ctx context.Context, req dto.RegisterRequest) error {
 	hashed, err := security.HashPassword(req.Password)
 	if err != nil {
 		return err
 	}
-	return s.repo.Register(req.Username, hashed, req.Email, req.Name, req.Age)
+
+	userID, err := s.repo.Register(req.Username, hashed, req.Email, req.Name, req.Age)
+	if err != nil {
+		return err
+	}
+
+	if err := s.sendVerification(ctx, userID, req.Email); err != nil {
+		s.logger.Error("failed to send verification email", err, watermill.LogFields{"user_id": userID})
+	}
+	return nil
 }
 
-func (s *AuthService) Login(username, password string) (*dto.LoginResponse, error) {
+// Login проверяет пароль и открывает новую сессию; сессии на других устройствах не затрагиваются.
+// С включённым вторым фактором вместо токенов возвращается mfa_token для LoginMFA.
+func (s *AuthService) Login(ctx context.Context, username, password string, client ClientInfo) (*dto.LoginResponse, error) {
 	user, err := s.repo.FindByUsername(username)
 	if err != nil {
 		return nil, errors.New("invalid username or password")
@@ -42,18 +108,46 @@ func (s *AuthService) Login(username, password string) (*dto.LoginResponse, erro
 	if !security.CheckPasswordHash(password, user.PasswordHash) {
 		return nil, errors.New("invalid username or password")
 	}
+	if !user.IsActive {
+		return nil, errors_constant.UserBanned
+	}
 
-	accessToken, err := s.generateAccessToken(user.ID, user.Username)
+	mfa, err := s.mfaEnabled(ctx, user.ID)
 	if err != nil {
 		return nil, err
 	}
+	if mfa {
+		return s.mfaChallenge(user, client.Device)
+	}
+	return s.startSession(ctx, user, client)
+}
 
-	refreshToken, err := s.generateRefreshToken(user.ID)
+// startSession открывает сессию и выдаёт первую пару токенов
+func (s *AuthService) startSession(ctx context.Context, user *model.User, client ClientInfo) (*dto.LoginResponse, error) {
+	jti, err := security.RandomToken(16)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := s.repo.SetRefreshToken(user.ID, refreshToken, s.refreshTTL); err != nil {
+	session := &Session{
+		UserID:     user.ID,
+		Device:     client.Device,
+		IP:         client.IP,
+		UserAgent:  client.UserAgent,
+		RefreshJTI: jti,
+		ExpiresAt:  time.Now().Add(s.refreshTTL),
+	}
+	if err := s.repo.CreateSession(ctx, session); err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	accessToken, err := s.generateAccessToken(user, session.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := s.generateRefreshToken(user.ID, session.ID, jti)
+	if err != nil {
 		return nil, err
 	}
 
@@ -64,41 +158,47 @@ func (s *AuthService) Login(username, password string) (*dto.LoginResponse, erro
 	}, nil
 }
 
-func (s *AuthService) Refresh(refreshToken string) (*dto.RefreshResponse, error) {
-	token, err := jwt.Parse(refreshToken, func(token *jwt.Token) (interface{}, error) {
-		return s.jwtKey, nil
-	})
-	if err != nil || !token.Valid {
+// Refresh выдаёт новую пару токенов и заменяет refresh-токен сессии.
+// Повторное предъявление уже заменённого токена считается кражей: сессия отзывается целиком.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string, client ClientInfo) (*dto.RefreshResponse, error) {
+	claims, err := s.keys.Parse(refreshToken)
+	if err != nil {
 		return nil, errors.New("invalid refresh token")
 	}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return nil, errors.New("invalid token claims")
-	}
-
-	userIDFloat, ok := claims["user_id"].(float64)
-	if !ok {
+	userIDFloat, okUser := claims["user_id"].(float64)
+	sessionIDFloat, okSession := claims["sid"].(float64)
+	jti, okJTI := claims["jti"].(string)
+	if !okUser || !okSession || !okJTI || claims["typ"] != refreshTokenType {
 		return nil, errors.New("invalid token payload")
 	}
-	userID := int(userIDFloat)
+	userID, sessionID := int(userIDFloat), int(sessionIDFloat)
 
-	storedToken, err := s.repo.GetRefreshToken(userID)
-	if err != nil || storedToken != refreshToken {
-		return nil, errors.New("refresh token not found or expired")
+	newJTI, err := security.RandomToken(16)
+	if err != nil {
+		return nil, err
 	}
 
-	newAccess, err := s.generateAccessToken(userID, claims["username"].(string))
+	rotated, err := s.repo.RotateSession(ctx, sessionID, jti, newJTI, client, time.Now().Add(s.refreshTTL))
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+	if !rotated {
+		return nil, s.rejectRefresh(ctx, userID, sessionID)
 	}
 
-	newRefresh, err := s.generateRefreshToken(userID)
+	user, err := s.repo.FindByID(userID)
+	if err != nil || !user.IsActive {
+		return nil, errors.New("refresh token not found or expired")
+	}
+
+	newAccess, err := s.generateAccessToken(user, sessionID)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := s.repo.SetRefreshToken(userID, newRefresh, s.refreshTTL); err != nil {
+	newRefresh, err := s.generateRefreshToken(userID, sessionID, newJTI)
+	if err != nil {
 		return nil, err
 	}
 
@@ -108,23 +208,127 @@ func (s *AuthService) Refresh(refreshToken string) (*dto.RefreshResponse, error)
 	}, nil
 }
 
-func (s *AuthService) generateAccessToken(userID int, username string) (string, error) {
+// rejectRefresh объясняет неудачную замену: если сессия ещё действует, значит предъявлен старый токен семейства
+func (s *AuthService) rejectRefresh(ctx context.Context, userID, sessionID int) error {
+	session, err := s.repo.FindSession(ctx, sessionID)
+	if err != nil || session.UserID != userID || !session.Active(time.Now()) {
+		return errors.New("refresh token not found or expired")
+	}
+
+	if err := s.RevokeSession(ctx, userID, sessionID); err != nil {
+		return err
+	}
+	return errors_constant.RefreshTokenReused
+}
+
+// Sessions возвращает действующие сессии пользователя
+func (s *AuthService) Sessions(ctx context.Context, userID int) ([]Session, error) {
+	sessions, err := s.repo.ListSessions(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// RevokeSession завершает одну сессию пользователя: перестают действовать и refresh-, и access-токены сессии
+func (s *AuthService) RevokeSession(ctx context.Context, userID, sessionID int) error {
+	revoked, err := s.repo.RevokeSession(ctx, userID, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	if !revoked {
+		return errors_constant.SessionNotFound
+	}
+	return s.revoker.RevokeSession(ctx, sessionID)
+}
+
+// RevokeAllSessions завершает все сессии пользователя, включая текущую
+func (s *AuthService) RevokeAllSessions(ctx context.Context, userID int) (int64, error) {
+	return s.revokeAll(ctx, userID)
+}
+
+// Logout завершает текущую сессию и сразу отзывает предъявленный access-токен
+func (s *AuthService) Logout(ctx context.Context, userID, sessionID int, jti string, expiresAt time.Time) error {
+	if err := s.revoker.RevokeToken(ctx, jti, expiresAt); err != nil {
+		return err
+	}
+	if sessionID == 0 {
+		return nil
+	}
+
+	err := s.RevokeSession(ctx, userID, sessionID)
+	if errors.Is(err, errors_constant.SessionNotFound) {
+		return nil
+	}
+	return err
+}
+
+// BanUser блокирует учётную запись: вход запрещён, все сессии и выданные токены отзываются
+func (s *AuthService) BanUser(ctx context.Context, userID int) error {
+	if err := s.setActive(ctx, userID, false); err != nil {
+		return err
+	}
+	_, err := s.revokeAll(ctx, userID)
+	return err
+}
+
+// UnbanUser снова разрешает вход; отозванные токены не восстанавливаются
+func (s *AuthService) UnbanUser(ctx context.Context, userID int) error {
+	return s.setActive(ctx, userID, true)
+}
+
+func (s *AuthService) setActive(ctx context.Context, userID int, active bool) error {
+	found, err := s.repo.SetUserActive(ctx, userID, active)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	if !found {
+		return errors_constant.UserNotFound
+	}
+	return nil
+}
+
+// revokeAll отзывает все сессии пользователя и все выданные ему до этого момента access-токены
+func (s *AuthService) revokeAll(ctx context.Context, userID int) (int64, error) {
+	n, err := s.repo.RevokeUserSessions(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	if err := s.revoker.RevokeUserTokens(ctx, userID, time.Now()); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+func (s *AuthService) generateAccessToken(user *model.User, sessionID int) (string, error) {
+	jti, err := security.RandomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
 	claims := jwt.MapClaims{
-		"user_id":  userID,
-		"username": username,
-		"exp":      time.Now().Add(s.tokenTTL).Unix(),
+		"user_id":        user.ID,
+		"username":       user.Username,
+		"role":           user.Role,
+		"email_verified": user.EmailVerifiedAt != nil,
+		"sid":            sessionID,
+		"jti":            jti,
+		"iat":            now.Unix(),
+		"exp":            now.Add(s.tokenTTL).Unix(),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(s.jwtKey)
+	return s.keys.Sign(claims)
 }
 
-func (s *AuthService) generateRefreshToken(userID int) (string, error) {
+func (s *AuthService) generateRefreshToken(userID, sessionID int, jti string) (string, error) {
 	claims := jwt.MapClaims{
 		"user_id": userID,
+		"sid":     sessionID,
+		"jti":     jti,
+		"typ":     refreshTokenType,
 		"exp":     time.Now().Add(s.refreshTTL).Unix(),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(s.jwtKey)
+	return s.keys.Sign(claims)
 }
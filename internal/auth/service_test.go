@@ -1,16 +1,25 @@
 package auth
 
 import (
+	"context"
 	"errors"
+	"mpb/configs"
 	"mpb/internal/auth/dto"
 	"mpb/internal/user"
 	"mpb/pkg/errors_constant"
+	"mpb/pkg/jwtkeys"
+	"mpb/pkg/mailer"
+	"mpb/pkg/security"
+	"net/url"
+	"strings"
 	"testing"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/pquerna/otp/totp"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // MockAuthRepository is a mock implementation of AuthRepository
@@ -18,9 +27,9 @@ type MockAuthRepository struct {
 	mock.Mock
 }
 
-func (m *MockAuthRepository) Register(username, passwordHash, email, name string, age int) error {
+func (m *MockAuthRepository) Register(username, passwordHash, email, name string, age int) (int, error) {
 	args := m.Called(username, passwordHash, email, name, age)
-	return args.Error(0)
+	return args.Int(0), args.Error(1)
 }
 
 func (m *MockAuthRepository) FindByUsername(username string) (*user.User, error) {
@@ -31,14 +40,171 @@ func (m *MockAuthRepository) FindByUsername(username string) (*user.User, error)
 	return args.Get(0).(*user.User), args.Error(1)
 }
 
-func (m *MockAuthRepository) SetRefreshToken(userID int, token string, ttl time.Duration) error {
-	args := m.Called(userID, token, ttl)
+func (m *MockAuthRepository) FindByID(userID int) (*user.User, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*user.User), args.Error(1)
+}
+
+func (m *MockAuthRepository) CreateSession(ctx context.Context, s *Session) error {
+	args := m.Called(ctx, s)
 	return args.Error(0)
 }
 
-func (m *MockAuthRepository) GetRefreshToken(userID int) (string, error) {
-	args := m.Called(userID)
-	return args.String(0), args.Error(1)
+func (m *MockAuthRepository) FindSession(ctx context.Context, sessionID int) (*Session, error) {
+	args := m.Called(ctx, sessionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Session), args.Error(1)
+}
+
+func (m *MockAuthRepository) RotateSession(ctx context.Context, sessionID int, oldJTI, newJTI string, client ClientInfo, expiresAt time.Time) (bool, error) {
+	args := m.Called(ctx, sessionID, oldJTI, newJTI, client, expiresAt)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAuthRepository) ListSessions(ctx context.Context, userID int) ([]Session, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]Session), args.Error(1)
+}
+
+func (m *MockAuthRepository) RevokeSession(ctx context.Context, userID, sessionID int) (bool, error) {
+	args := m.Called(ctx, userID, sessionID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAuthRepository) RevokeUserSessions(ctx context.Context, userID int) (int64, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockAuthRepository) SetUserActive(ctx context.Context, userID int, active bool) (bool, error) {
+	args := m.Called(ctx, userID, active)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAuthRepository) EmailTaken(ctx context.Context, email string) (bool, error) {
+	args := m.Called(ctx, email)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAuthRepository) MarkEmailVerified(ctx context.Context, userID int, email string) (bool, error) {
+	args := m.Called(ctx, userID, email)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAuthRepository) ChangeEmail(ctx context.Context, userID int, from, to string) (bool, error) {
+	args := m.Called(ctx, userID, from, to)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAuthRepository) AcquireCooldown(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	args := m.Called(ctx, key, ttl)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAuthRepository) FindByEmail(ctx context.Context, email string) (*user.User, error) {
+	args := m.Called(ctx, email)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*user.User), args.Error(1)
+}
+
+func (m *MockAuthRepository) CreatePasswordReset(ctx context.Context, userID int, tokenHash string, expiresAt time.Time) error {
+	args := m.Called(ctx, userID, tokenHash, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockAuthRepository) ResetPassword(ctx context.Context, tokenHash, passwordHash string) (int, error) {
+	args := m.Called(ctx, tokenHash, passwordHash)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockAuthRepository) UpdatePassword(ctx context.Context, userID int, passwordHash string) (bool, error) {
+	args := m.Called(ctx, userID, passwordHash)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAuthRepository) FindTOTP(ctx context.Context, userID int) (*TOTP, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*TOTP), args.Error(1)
+}
+
+func (m *MockAuthRepository) SaveTOTPSecret(ctx context.Context, userID int, secret string) (bool, error) {
+	args := m.Called(ctx, userID, secret)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAuthRepository) EnableTOTP(ctx context.Context, userID int, codeHashes []string) (bool, error) {
+	args := m.Called(ctx, userID, codeHashes)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAuthRepository) ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error {
+	args := m.Called(ctx, userID, codeHashes)
+	return args.Error(0)
+}
+
+func (m *MockAuthRepository) UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error) {
+	args := m.Called(ctx, userID, codeHash)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAuthRepository) DisableTOTP(ctx context.Context, userID int) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockAuthRepository) CountAttempt(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	args := m.Called(ctx, key, ttl)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+type MockTokenRevoker struct {
+	mock.Mock
+}
+
+func (m *MockTokenRevoker) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	return m.Called(ctx, jti, expiresAt).Error(0)
+}
+
+func (m *MockTokenRevoker) RevokeSession(ctx context.Context, sessionID int) error {
+	return m.Called(ctx, sessionID).Error(0)
+}
+
+func (m *MockTokenRevoker) RevokeUserTokens(ctx context.Context, userID int, at time.Time) error {
+	return m.Called(ctx, userID, at).Error(0)
+}
+
+func newTestKeys(t *testing.T) *jwtkeys.KeySet {
+	keys, err := jwtkeys.Generate()
+	require.NoError(t, err)
+	return keys
+}
+
+// fakeMailer запоминает отправленные письма
+type fakeMailer struct {
+	sent []mailer.Message
+}
+
+func (m *fakeMailer) Send(_ context.Context, msg mailer.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func newTestService(repo AuthRepositoryInterface, revoker TokenRevoker, keys *jwtkeys.KeySet, mail mailer.Mailer) *AuthService {
+	return NewAuthService(repo, revoker, mail, keys, watermill.NopLogger{},
+		configs.JWTConfig{AccessTokenTTL: 24 * time.Hour, RefreshTokenTTL: 7 * 24 * time.Hour},
+		configs.AuthConfig{PublicURL: "http://localhost:8000", EmailTokenTTL: time.Hour, EmailResendInterval: time.Minute,
+			PasswordResetTTL: 30 * time.Minute, TOTPIssuer: "mpb", MFATokenTTL: 5 * time.Minute},
+	)
 }
 
 func TestAuthService_Register(t *testing.T) {
@@ -58,7 +224,7 @@ func TestAuthService_Register(t *testing.T) {
 				Age:      25,
 			},
 			mockSetup: func(repo *MockAuthRepository) {
-				repo.On("Register", "testuser", mock.AnythingOfType("string"), "test@example.com", "Test User", 25).Return(nil)
+				repo.On("Register", "testuser", mock.AnythingOfType("string"), "test@example.com", "Test User", 25).Return(7, nil)
 			},
 			expectedError: nil,
 		},
@@ -72,7 +238,7 @@ func TestAuthService_Register(t *testing.T) {
 				Age:      30,
 			},
 			mockSetup: func(repo *MockAuthRepository) {
-				repo.On("Register", "existinguser", mock.AnythingOfType("string"), "existing@example.com", "Existing User", 30).Return(errors_constant.UserAlreadyExists)
+				repo.On("Register", "existinguser", mock.AnythingOfType("string"), "existing@example.com", "Existing User", 30).Return(0, errors_constant.UserAlreadyExists)
 			},
 			expectedError: errors_constant.UserAlreadyExists,
 		},
@@ -81,30 +247,25 @@ func TestAuthService_Register(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			repo := new(MockAuthRepository)
-			jwtKey := []byte("test-secret-key")
-			ttl := 24 * time.Hour
+			revoker := new(MockTokenRevoker)
+			jwtKey := newTestKeys(t)
 
 			tt.mockSetup(repo)
 
-			// Note: AuthService requires *AuthRepository, not an interface
-			// This makes unit testing difficult. For proper testing, AuthRepository should be an interface.
-			// For now, we'll skip these tests and recommend using integration tests instead.
-			t.Skip("Skipping - AuthRepository is not an interface, use integration tests instead")
-
-			service := &AuthService{
-				repo:       nil,
-				jwtKey:     jwtKey,
-				tokenTTL:   ttl,
-				refreshTTL: 7 * 24 * time.Hour,
-			}
+			mail := &fakeMailer{}
+			service := newTestService(repo, revoker, jwtKey, mail)
 
-			err := service.Register(tt.req)
+			err := service.Register(context.Background(), tt.req)
 
 			if tt.expectedError != nil {
 				assert.Error(t, err)
 				assert.True(t, errors.Is(err, tt.expectedError))
+				assert.Empty(t, mail.sent)
 			} else {
 				assert.NoError(t, err)
+				require.Len(t, mail.sent, 1)
+				assert.Equal(t, tt.req.Email, mail.sent[0].To)
+				assert.Contains(t, mail.sent[0].Body, "http://localhost:8000/verify-email?token=")
 			}
 
 			repo.AssertExpectations(t)
@@ -113,6 +274,9 @@ func TestAuthService_Register(t *testing.T) {
 }
 
 func TestAuthService_Login(t *testing.T) {
+	passwordHash, err := security.HashPassword("password123")
+	assert.NoError(t, err)
+
 	tests := []struct {
 		name          string
 		username      string
@@ -130,11 +294,17 @@ func TestAuthService_Login(t *testing.T) {
 				u := &user.User{
 					ID:           1,
 					Username:     "testuser",
-					PasswordHash: "$2a$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy", // hash of "password123"
+					PasswordHash: passwordHash,
 					Email:        &email,
+					IsActive:     true,
 				}
 				repo.On("FindByUsername", "testuser").Return(u, nil)
-				repo.On("SetRefreshToken", 1, mock.AnythingOfType("string"), mock.AnythingOfType("time.Duration")).Return(nil)
+				repo.On("FindTOTP", mock.Anything, 1).Return(nil, errors_constant.TOTPNotEnabled)
+				repo.On("CreateSession", mock.Anything, mock.MatchedBy(func(s *Session) bool {
+					return s.UserID == 1 && s.Device == "phone" && s.RefreshJTI != ""
+				})).Run(func(args mock.Arguments) {
+					args.Get(1).(*Session).ID = 5
+				}).Return(nil)
 			},
 			expectedError: false,
 		},
@@ -157,8 +327,9 @@ func TestAuthService_Login(t *testing.T) {
 				u := &user.User{
 					ID:           1,
 					Username:     "testuser",
-					PasswordHash: "$2a$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy",
+					PasswordHash: passwordHash,
 					Email:        &email,
+					IsActive:     true,
 				}
 				repo.On("FindByUsername", "testuser").Return(u, nil)
 			},
@@ -170,24 +341,14 @@ func TestAuthService_Login(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			repo := new(MockAuthRepository)
-			jwtKey := []byte("test-secret-key")
-			ttl := 24 * time.Hour
+			revoker := new(MockTokenRevoker)
+			jwtKey := newTestKeys(t)
 
 			tt.mockSetup(repo)
 
-			// Note: AuthService requires *AuthRepository, not an interface
-			// This makes unit testing difficult. For proper testing, AuthRepository should be an interface.
-			// For now, we'll skip these tests and recommend using integration tests instead.
-			t.Skip("Skipping - AuthRepository is not an interface, use integration tests instead")
-
-			service := &AuthService{
-				repo:       nil,
-				jwtKey:     jwtKey,
-				tokenTTL:   ttl,
-				refreshTTL: 7 * 24 * time.Hour,
-			}
+			service := newTestService(repo, revoker, jwtKey, &fakeMailer{})
 
-			response, err := service.Login(tt.username, tt.password)
+			response, err := service.Login(context.Background(), tt.username, tt.password, ClientInfo{Device: "phone"})
 
 			if tt.expectedError {
 				assert.Error(t, err)
@@ -201,11 +362,9 @@ func TestAuthService_Login(t *testing.T) {
 				assert.NotNil(t, response.User)
 
 				// Verify token is valid JWT
-				token, parseErr := jwt.Parse(response.Token, func(token *jwt.Token) (interface{}, error) {
-					return jwtKey, nil
-				})
+				claims, parseErr := jwtKey.Parse(response.Token)
 				assert.NoError(t, parseErr)
-				assert.True(t, token.Valid)
+				assert.Equal(t, float64(5), claims["sid"])
 			}
 
 			repo.AssertExpectations(t)
@@ -217,21 +376,21 @@ func TestAuthService_Refresh(t *testing.T) {
 	tests := []struct {
 		name          string
 		refreshToken  string
-		mockSetup     func(*MockAuthRepository, string)
+		mockSetup     func(*MockAuthRepository, *MockTokenRevoker, string)
 		expectedError bool
 		errorMsg      string
 	}{
 		{
 			name: "successful refresh",
-			mockSetup: func(repo *MockAuthRepository, token string) {
-				repo.On("GetRefreshToken", 1).Return(token, nil)
-				repo.On("SetRefreshToken", 1, mock.AnythingOfType("string"), mock.AnythingOfType("time.Duration")).Return(nil)
+			mockSetup: func(repo *MockAuthRepository, revoker *MockTokenRevoker, token string) {
+				repo.On("RotateSession", mock.Anything, 3, "old-jti", mock.AnythingOfType("string"), ClientInfo{}, mock.AnythingOfType("time.Time")).Return(true, nil)
+				repo.On("FindByID", 1).Return(&user.User{ID: 1, Username: "testuser", Role: user.RoleUser, IsActive: true}, nil)
 			},
 			expectedError: false,
 		},
 		{
 			name: "invalid token",
-			mockSetup: func(repo *MockAuthRepository, token string) {
+			mockSetup: func(repo *MockAuthRepository, revoker *MockTokenRevoker, token string) {
 				// No repository calls expected
 			},
 			refreshToken:  "invalid.token.here",
@@ -239,50 +398,48 @@ func TestAuthService_Refresh(t *testing.T) {
 			errorMsg:      "invalid refresh token",
 		},
 		{
-			name: "token not found in repository",
-			mockSetup: func(repo *MockAuthRepository, token string) {
-				repo.On("GetRefreshToken", 1).Return("", errors.New("not found"))
+			name: "session revoked",
+			mockSetup: func(repo *MockAuthRepository, revoker *MockTokenRevoker, token string) {
+				revokedAt := time.Now()
+				repo.On("RotateSession", mock.Anything, 3, "old-jti", mock.AnythingOfType("string"), ClientInfo{}, mock.AnythingOfType("time.Time")).Return(false, nil)
+				repo.On("FindSession", mock.Anything, 3).Return(&Session{ID: 3, UserID: 1, RevokedAt: &revokedAt, ExpiresAt: time.Now().Add(time.Hour)}, nil)
 			},
 			expectedError: true,
 			errorMsg:      "refresh token not found or expired",
 		},
+		{
+			name: "rotated token reused",
+			mockSetup: func(repo *MockAuthRepository, revoker *MockTokenRevoker, token string) {
+				repo.On("RotateSession", mock.Anything, 3, "old-jti", mock.AnythingOfType("string"), ClientInfo{}, mock.AnythingOfType("time.Time")).Return(false, nil)
+				repo.On("FindSession", mock.Anything, 3).Return(&Session{ID: 3, UserID: 1, RefreshJTI: "new-jti", ExpiresAt: time.Now().Add(time.Hour)}, nil)
+				repo.On("RevokeSession", mock.Anything, 1, 3).Return(true, nil)
+				revoker.On("RevokeSession", mock.Anything, 3).Return(nil)
+			},
+			expectedError: true,
+			errorMsg:      errors_constant.RefreshTokenReused.Error(),
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			repo := new(MockAuthRepository)
-			jwtKey := []byte("test-secret-key")
-			ttl := 24 * time.Hour
-
-			// Note: AuthService requires *AuthRepository, not an interface
-			// This makes unit testing difficult. For proper testing, AuthRepository should be an interface.
-			// For now, we'll skip these tests and recommend using integration tests instead.
-			t.Skip("Skipping - AuthRepository is not an interface, use integration tests instead")
-
-			service := &AuthService{
-				repo:       nil,
-				jwtKey:     jwtKey,
-				tokenTTL:   ttl,
-				refreshTTL: 7 * 24 * time.Hour,
-			}
+			revoker := new(MockTokenRevoker)
+			jwtKey := newTestKeys(t)
+
+			service := newTestService(repo, revoker, jwtKey, &fakeMailer{})
 
 			// Generate a valid refresh token for successful test
 			var refreshToken string
 			if tt.refreshToken == "" {
 				// Create a valid token
-				claims := jwt.MapClaims{
-					"user_id": 1,
-					"exp":     time.Now().Add(7 * 24 * time.Hour).Unix(),
-				}
-				token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-				refreshToken, _ = token.SignedString(jwtKey)
+				refreshToken, _ = service.generateRefreshToken(1, 3, "old-jti")
 			} else {
 				refreshToken = tt.refreshToken
 			}
 
-			tt.mockSetup(repo, refreshToken)
+			tt.mockSetup(repo, revoker, refreshToken)
 
-			response, err := service.Refresh(refreshToken)
+			response, err := service.Refresh(context.Background(), refreshToken, ClientInfo{})
 
 			if tt.expectedError {
 				assert.Error(t, err)
@@ -297,73 +454,425 @@ func TestAuthService_Refresh(t *testing.T) {
 				assert.NotEmpty(t, response.RefreshToken)
 
 				// Verify new access token is valid
-				token, parseErr := jwt.Parse(response.AccessToken, func(token *jwt.Token) (interface{}, error) {
-					return jwtKey, nil
-				})
+				_, parseErr := jwtKey.Parse(response.AccessToken)
 				assert.NoError(t, parseErr)
-				assert.True(t, token.Valid)
 			}
 
 			repo.AssertExpectations(t)
+			revoker.AssertExpectations(t)
 		})
 	}
 }
 
 func TestAuthService_generateAccessToken(t *testing.T) {
-	jwtKey := []byte("test-secret-key")
+	jwtKey := newTestKeys(t)
 	ttl := 24 * time.Hour
 
 	service := &AuthService{
 		repo:       nil,
-		jwtKey:     jwtKey,
+		keys:       jwtKey,
 		tokenTTL:   ttl,
 		refreshTTL: 7 * 24 * time.Hour,
 	}
 
-	token, err := service.generateAccessToken(1, "testuser")
+	token, err := service.generateAccessToken(&user.User{ID: 1, Username: "testuser", Role: user.RoleAdmin}, 5)
 	assert.NoError(t, err)
 	assert.NotEmpty(t, token)
 
 	// Verify token can be parsed
-	parsedToken, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
-		return jwtKey, nil
-	})
+	claims, err := jwtKey.Parse(token)
 	assert.NoError(t, err)
-	assert.True(t, parsedToken.Valid)
 
 	// Verify claims
-	claims, ok := parsedToken.Claims.(jwt.MapClaims)
-	assert.True(t, ok)
 	assert.Equal(t, float64(1), claims["user_id"])
 	assert.Equal(t, "testuser", claims["username"])
+	assert.Equal(t, user.RoleAdmin, claims["role"])
+	assert.Equal(t, false, claims["email_verified"])
+	assert.Equal(t, float64(5), claims["sid"])
+	assert.NotEmpty(t, claims["jti"])
+	assert.NotNil(t, claims["iat"])
 	assert.NotNil(t, claims["exp"])
 }
 
 func TestAuthService_generateRefreshToken(t *testing.T) {
-	jwtKey := []byte("test-secret-key")
+	jwtKey := newTestKeys(t)
 	ttl := 24 * time.Hour
 
 	service := &AuthService{
 		repo:       nil,
-		jwtKey:     jwtKey,
+		keys:       jwtKey,
 		tokenTTL:   ttl,
 		refreshTTL: 7 * 24 * time.Hour,
 	}
 
-	token, err := service.generateRefreshToken(1)
+	token, err := service.generateRefreshToken(1, 5, "jti")
 	assert.NoError(t, err)
 	assert.NotEmpty(t, token)
 
 	// Verify token can be parsed
-	parsedToken, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
-		return jwtKey, nil
-	})
+	claims, err := jwtKey.Parse(token)
 	assert.NoError(t, err)
-	assert.True(t, parsedToken.Valid)
 
 	// Verify claims
-	claims, ok := parsedToken.Claims.(jwt.MapClaims)
-	assert.True(t, ok)
 	assert.Equal(t, float64(1), claims["user_id"])
+	assert.Equal(t, float64(5), claims["sid"])
+	assert.Equal(t, "jti", claims["jti"])
+	assert.Equal(t, refreshTokenType, claims["typ"])
 	assert.NotNil(t, claims["exp"])
 }
+
+func TestAuthService_Logout(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockAuthRepository)
+	revoker := new(MockTokenRevoker)
+	service := newTestService(repo, revoker, newTestKeys(t), &fakeMailer{})
+	exp := time.Now().Add(time.Hour)
+
+	revoker.On("RevokeToken", ctx, "jti", exp).Return(nil)
+	repo.On("RevokeSession", ctx, 1, 3).Return(false, nil)
+
+	// сессия уже отозвана с другого устройства — выход всё равно успешен
+	assert.NoError(t, service.Logout(ctx, 1, 3, "jti", exp))
+	repo.AssertExpectations(t)
+	revoker.AssertNotCalled(t, "RevokeSession", mock.Anything, mock.Anything)
+}
+
+func TestAuthService_BanUser(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockAuthRepository)
+	revoker := new(MockTokenRevoker)
+	service := newTestService(repo, revoker, newTestKeys(t), &fakeMailer{})
+
+	repo.On("SetUserActive", ctx, 1, false).Return(true, nil)
+	repo.On("RevokeUserSessions", ctx, 1).Return(int64(2), nil)
+	revoker.On("RevokeUserTokens", ctx, 1, mock.AnythingOfType("time.Time")).Return(nil)
+
+	assert.NoError(t, service.BanUser(ctx, 1))
+	repo.AssertExpectations(t)
+	revoker.AssertExpectations(t)
+
+	repo.On("SetUserActive", ctx, 2, false).Return(false, nil)
+	assert.ErrorIs(t, service.BanUser(ctx, 2), errors_constant.UserNotFound)
+}
+
+// linkToken достаёт токен из ссылки в письме
+func linkToken(t *testing.T, msg mailer.Message) string {
+	t.Helper()
+	_, rest, found := strings.Cut(msg.Body, "?token=")
+	require.True(t, found, "message has no link")
+	token, _, _ := strings.Cut(rest, "\n")
+	token, err := url.QueryUnescape(token)
+	require.NoError(t, err)
+	return token
+}
+
+func TestAuthService_VerifyEmail(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockAuthRepository)
+	keys := newTestKeys(t)
+	mail := &fakeMailer{}
+	service := newTestService(repo, new(MockTokenRevoker), keys, mail)
+
+	repo.On("Register", "testuser", mock.AnythingOfType("string"), "test@example.com", "Test User", 25).Return(7, nil)
+	require.NoError(t, service.Register(ctx, dto.RegisterRequest{
+		Username: "testuser", Password: "password123", Email: "test@example.com", Name: "Test User", Age: 25,
+	}))
+	require.Len(t, mail.sent, 1)
+	token := linkToken(t, mail.sent[0])
+
+	repo.On("MarkEmailVerified", ctx, 7, "test@example.com").Return(true, nil).Once()
+	assert.NoError(t, service.VerifyEmail(ctx, token))
+
+	// адрес успел смениться
+	repo.On("MarkEmailVerified", ctx, 7, "test@example.com").Return(false, nil).Once()
+	assert.ErrorIs(t, service.VerifyEmail(ctx, token), errors_constant.InvalidEmailToken)
+
+	// токен другого назначения не подходит
+	refresh, err := service.generateRefreshToken(7, 1, "jti")
+	require.NoError(t, err)
+	assert.ErrorIs(t, service.VerifyEmail(ctx, refresh), errors_constant.InvalidEmailToken)
+
+	repo.AssertExpectations(t)
+}
+
+func TestAuthService_ResendVerification(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockAuthRepository)
+	mail := &fakeMailer{}
+	service := newTestService(repo, new(MockTokenRevoker), newTestKeys(t), mail)
+
+	email := "test@example.com"
+	verifiedAt := time.Now()
+	repo.On("FindByID", 1).Return(&user.User{ID: 1, Email: &email}, nil)
+	repo.On("FindByID", 2).Return(&user.User{ID: 2, Email: &email, EmailVerifiedAt: &verifiedAt}, nil)
+	repo.On("AcquireCooldown", ctx, "email_resend:1", time.Minute).Return(true, nil).Once()
+	repo.On("AcquireCooldown", ctx, "email_resend:1", time.Minute).Return(false, nil).Once()
+
+	assert.NoError(t, service.ResendVerification(ctx, 1))
+	assert.ErrorIs(t, service.ResendVerification(ctx, 1), errors_constant.EmailResendTooSoon)
+	assert.ErrorIs(t, service.ResendVerification(ctx, 2), errors_constant.EmailAlreadyVerified)
+	assert.Len(t, mail.sent, 1)
+
+	repo.AssertExpectations(t)
+}
+
+func TestAuthService_ChangeEmail(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockAuthRepository)
+	mail := &fakeMailer{}
+	service := newTestService(repo, new(MockTokenRevoker), newTestKeys(t), mail)
+
+	hash, err := security.HashPassword("password123")
+	require.NoError(t, err)
+	oldEmail := "old@example.com"
+	repo.On("FindByID", 1).Return(&user.User{ID: 1, Email: &oldEmail, PasswordHash: hash}, nil)
+
+	assert.ErrorIs(t, service.RequestEmailChange(ctx, 1, "wrong", "new@example.com"), errors_constant.InvalidPassword)
+	assert.ErrorIs(t, service.RequestEmailChange(ctx, 1, "password123", oldEmail), errors_constant.EmailUnchanged)
+
+	repo.On("EmailTaken", ctx, "taken@example.com").Return(true, nil)
+	assert.ErrorIs(t, service.RequestEmailChange(ctx, 1, "password123", "taken@example.com"), errors_constant.EmailTaken)
+	assert.Empty(t, mail.sent)
+
+	repo.On("EmailTaken", ctx, "new@example.com").Return(false, nil)
+	repo.On("AcquireCooldown", ctx, "email_change:1", time.Minute).Return(true, nil)
+	require.NoError(t, service.RequestEmailChange(ctx, 1, "password123", "new@example.com"))
+
+	// ссылка уходит на новый адрес, уведомление — на старый
+	require.Len(t, mail.sent, 2)
+	assert.Equal(t, "new@example.com", mail.sent[0].To)
+	assert.Equal(t, oldEmail, mail.sent[1].To)
+	assert.NotContains(t, mail.sent[1].Body, "token=")
+
+	token := linkToken(t, mail.sent[0])
+	repo.On("ChangeEmail", ctx, 1, oldEmail, "new@example.com").Return(true, nil).Once()
+	email, err := service.ConfirmEmailChange(ctx, token)
+	require.NoError(t, err)
+	assert.Equal(t, "new@example.com", email)
+
+	// повторный переход по ссылке
+	repo.On("ChangeEmail", ctx, 1, oldEmail, "new@example.com").Return(false, nil).Once()
+	_, err = service.ConfirmEmailChange(ctx, token)
+	assert.ErrorIs(t, err, errors_constant.InvalidEmailToken)
+
+	// ссылка подтверждения регистрации не меняет адрес
+	require.NoError(t, service.sendVerification(ctx, 1, "new@example.com"))
+	_, err = service.ConfirmEmailChange(ctx, linkToken(t, mail.sent[2]))
+	assert.ErrorIs(t, err, errors_constant.InvalidEmailToken)
+
+	repo.AssertExpectations(t)
+}
+
+func TestAuthService_sendPasswordReset(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockAuthRepository)
+	mail := &fakeMailer{}
+	service := newTestService(repo, new(MockTokenRevoker), newTestKeys(t), mail)
+
+	// неизвестный и заблокированный адреса неотличимы от успеха
+	repo.On("FindByEmail", ctx, "nobody@example.com").Return(nil, errors_constant.UserNotFound)
+	repo.On("FindByEmail", ctx, "banned@example.com").Return(&user.User{ID: 2}, nil)
+	assert.NoError(t, service.sendPasswordReset(ctx, "nobody@example.com"))
+	assert.NoError(t, service.sendPasswordReset(ctx, "banned@example.com"))
+
+	var savedHash string
+	repo.On("FindByEmail", ctx, "test@example.com").Return(&user.User{ID: 1, IsActive: true}, nil)
+	repo.On("AcquireCooldown", ctx, "password_reset:1", time.Minute).Return(true, nil).Once()
+	repo.On("CreatePasswordReset", ctx, 1, mock.AnythingOfType("string"), mock.AnythingOfType("time.Time")).
+		Run(func(args mock.Arguments) { savedHash = args.String(2) }).Return(nil)
+	require.NoError(t, service.sendPasswordReset(ctx, "test@example.com"))
+
+	require.Len(t, mail.sent, 1)
+	assert.Equal(t, "test@example.com", mail.sent[0].To)
+	token := linkToken(t, mail.sent[0])
+	assert.Equal(t, security.HashToken(token), savedHash)
+	assert.NotEqual(t, token, savedHash)
+
+	repo.On("AcquireCooldown", ctx, "password_reset:1", time.Minute).Return(false, nil).Once()
+	assert.NoError(t, service.sendPasswordReset(ctx, "test@example.com"))
+	assert.Len(t, mail.sent, 1)
+
+	repo.AssertExpectations(t)
+}
+
+func TestAuthService_ResetPassword(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockAuthRepository)
+	revoker := new(MockTokenRevoker)
+	mail := &fakeMailer{}
+	service := newTestService(repo, revoker, newTestKeys(t), mail)
+
+	repo.On("ResetPassword", ctx, security.HashToken("used"), mock.AnythingOfType("string")).Return(0, errors_constant.InvalidResetToken)
+	assert.ErrorIs(t, service.ResetPassword(ctx, "used", "newpassword"), errors_constant.InvalidResetToken)
+
+	email := "test@example.com"
+	repo.On("ResetPassword", ctx, security.HashToken("token"), mock.MatchedBy(func(hash string) bool {
+		return security.CheckPasswordHash("newpassword", hash)
+	})).Return(1, nil)
+	repo.On("RevokeUserSessions", ctx, 1).Return(int64(2), nil)
+	revoker.On("RevokeUserTokens", ctx, 1, mock.AnythingOfType("time.Time")).Return(nil)
+	repo.On("FindByID", 1).Return(&user.User{ID: 1, Email: &email}, nil)
+
+	require.NoError(t, service.ResetPassword(ctx, "token", "newpassword"))
+	require.Len(t, mail.sent, 1)
+	assert.Equal(t, email, mail.sent[0].To)
+
+	repo.AssertExpectations(t)
+	revoker.AssertExpectations(t)
+}
+
+func TestAuthService_ChangePassword(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockAuthRepository)
+	revoker := new(MockTokenRevoker)
+	service := newTestService(repo, revoker, newTestKeys(t), &fakeMailer{})
+
+	hash, err := security.HashPassword("password123")
+	require.NoError(t, err)
+	repo.On("FindByID", 1).Return(&user.User{ID: 1, PasswordHash: hash}, nil)
+
+	assert.ErrorIs(t, service.ChangePassword(ctx, 1, "wrong", "newpassword"), errors_constant.InvalidPassword)
+	assert.ErrorIs(t, service.ChangePassword(ctx, 1, "password123", "password123"), errors_constant.PasswordUnchanged)
+	repo.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+
+	repo.On("UpdatePassword", ctx, 1, mock.AnythingOfType("string")).Return(true, nil)
+	repo.On("RevokeUserSessions", ctx, 1).Return(int64(3), nil)
+	revoker.On("RevokeUserTokens", ctx, 1, mock.AnythingOfType("time.Time")).Return(nil)
+	require.NoError(t, service.ChangePassword(ctx, 1, "password123", "newpassword"))
+
+	repo.AssertExpectations(t)
+	revoker.AssertExpectations(t)
+}
+
+func TestAuthService_EnrollTOTP(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockAuthRepository)
+	mail := &fakeMailer{}
+	service := newTestService(repo, new(MockTokenRevoker), newTestKeys(t), mail)
+
+	hash, err := security.HashPassword("password123")
+	require.NoError(t, err)
+	email := "test@example.com"
+	repo.On("FindByID", 1).Return(&user.User{ID: 1, Username: "testuser", Email: &email, PasswordHash: hash}, nil)
+
+	_, err = service.EnrollTOTP(ctx, 1, "wrong")
+	assert.ErrorIs(t, err, errors_constant.InvalidPassword)
+
+	var secret string
+	repo.On("SaveTOTPSecret", ctx, 1, mock.AnythingOfType("string")).
+		Run(func(args mock.Arguments) { secret = args.String(2) }).Return(true, nil).Once()
+	enrollment, err := service.EnrollTOTP(ctx, 1, "password123")
+	require.NoError(t, err)
+	assert.Equal(t, secret, enrollment.Secret)
+	assert.True(t, strings.HasPrefix(enrollment.URL, "otpauth://totp/mpb:testuser?"))
+	assert.Equal(t, []byte("\x89PNG"), enrollment.QRCode[:4])
+
+	repo.On("SaveTOTPSecret", ctx, 1, mock.AnythingOfType("string")).Return(false, nil).Once()
+	_, err = service.EnrollTOTP(ctx, 1, "password123")
+	assert.ErrorIs(t, err, errors_constant.TOTPAlreadyEnabled)
+
+	// подтверждение кодом из приложения выдаёт коды восстановления, в базу уходят только хеши
+	pending := &TOTP{UserID: 1, Secret: secret}
+	repo.On("FindTOTP", ctx, 1).Return(pending, nil)
+	repo.On("CountAttempt", ctx, "mfa_attempts:1", mfaAttemptWindow).Return(int64(1), nil)
+
+	_, err = service.ConfirmTOTP(ctx, 1, "000000")
+	if err == nil {
+		t.Skip("000000 happened to be the current code")
+	}
+	assert.ErrorIs(t, err, errors_constant.InvalidMFACode)
+
+	code, err := totp.GenerateCode(secret, time.Now())
+	require.NoError(t, err)
+	repo.On("AcquireCooldown", ctx, "totp_used:1:"+code, totpReuseWindow).Return(true, nil)
+	var hashes []string
+	repo.On("EnableTOTP", ctx, 1, mock.Anything).
+		Run(func(args mock.Arguments) { hashes = args.Get(2).([]string) }).Return(true, nil)
+
+	codes, err := service.ConfirmTOTP(ctx, 1, code)
+	require.NoError(t, err)
+	require.Len(t, codes, recoveryCodeCount)
+	require.Len(t, hashes, recoveryCodeCount)
+	assert.Equal(t, security.HashToken(normalizeRecoveryCode(codes[0])), hashes[0])
+	assert.NotContains(t, hashes, codes[0])
+	require.Len(t, mail.sent, 1)
+
+	repo.AssertExpectations(t)
+}
+
+func TestAuthService_LoginMFA(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockAuthRepository)
+	keys := newTestKeys(t)
+	service := newTestService(repo, new(MockTokenRevoker), keys, &fakeMailer{})
+
+	key, err := totp.Generate(totp.GenerateOpts{Issuer: "mpb", AccountName: "testuser"})
+	require.NoError(t, err)
+	enabledAt := time.Now()
+	hash, err := security.HashPassword("password123")
+	require.NoError(t, err)
+	u := &user.User{ID: 1, Username: "testuser", PasswordHash: hash, IsActive: true}
+
+	repo.On("FindByUsername", "testuser").Return(u, nil)
+	repo.On("FindByID", 1).Return(u, nil)
+	repo.On("FindTOTP", ctx, 1).Return(&TOTP{UserID: 1, Secret: key.Secret(), EnabledAt: &enabledAt}, nil)
+	repo.On("CountAttempt", ctx, "mfa_attempts:1", mfaAttemptWindow).Return(int64(1), nil)
+
+	// пароль верный, но токенов ещё нет
+	challenge, err := service.Login(ctx, "testuser", "password123", ClientInfo{Device: "phone"})
+	require.NoError(t, err)
+	assert.True(t, challenge.MFARequired)
+	assert.Empty(t, challenge.Token)
+	assert.Empty(t, challenge.RefreshToken)
+	assert.Nil(t, challenge.User)
+
+	// mfa_pending не годится как access-токен, а refresh-токен не годится как mfa_pending
+	claims, err := keys.Parse(challenge.MFAToken)
+	require.NoError(t, err)
+	assert.Equal(t, mfaPendingTokenType, claims["typ"])
+	refresh, err := service.generateRefreshToken(1, 1, "jti")
+	require.NoError(t, err)
+	_, err = service.LoginMFA(ctx, refresh, "123456", ClientInfo{})
+	assert.ErrorIs(t, err, errors_constant.InvalidMFAToken)
+
+	code, err := totp.GenerateCode(key.Secret(), time.Now())
+	require.NoError(t, err)
+	repo.On("AcquireCooldown", ctx, "totp_used:1:"+code, totpReuseWindow).Return(true, nil).Once()
+	repo.On("AcquireCooldown", ctx, "mfa_token:"+claims["jti"].(string), mock.AnythingOfType("time.Duration")).Return(true, nil).Once()
+	repo.On("CreateSession", ctx, mock.MatchedBy(func(s *Session) bool {
+		return s.UserID == 1 && s.Device == "phone"
+	})).Return(nil)
+
+	resp, err := service.LoginMFA(ctx, challenge.MFAToken, code, ClientInfo{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, resp.Token)
+	assert.NotEmpty(t, resp.RefreshToken)
+
+	// повтор того же кода не проходит
+	repo.On("AcquireCooldown", ctx, "totp_used:1:"+code, totpReuseWindow).Return(false, nil).Once()
+	_, err = service.LoginMFA(ctx, challenge.MFAToken, code, ClientInfo{})
+	assert.ErrorIs(t, err, errors_constant.InvalidMFACode)
+
+	// код восстановления принимается в любом написании, но только один раз
+	repo.On("UseRecoveryCode", ctx, 1, security.HashToken("abcde12345")).Return(false, nil).Once()
+	_, err = service.LoginMFA(ctx, challenge.MFAToken, "ABCDE-12345", ClientInfo{})
+	assert.ErrorIs(t, err, errors_constant.InvalidMFACode)
+
+	repo.AssertExpectations(t)
+}
+
+func TestAuthService_MFAAttemptLimit(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockAuthRepository)
+	service := newTestService(repo, new(MockTokenRevoker), newTestKeys(t), &fakeMailer{})
+
+	enabledAt := time.Now()
+	tf := &TOTP{UserID: 1, Secret: "JBSWY3DPEHPK3PXP", EnabledAt: &enabledAt}
+	repo.On("CountAttempt", ctx, "mfa_attempts:1", mfaAttemptWindow).Return(int64(maxMFAAttempts+1), nil)
+
+	code, err := totp.GenerateCode(tf.Secret, time.Now())
+	require.NoError(t, err)
+	assert.ErrorIs(t, service.checkSecondFactor(ctx, tf, code), errors_constant.TooManyMFAAttempts)
+	assert.ErrorIs(t, service.checkSecondFactor(ctx, tf, "abcde-12345"), errors_constant.TooManyMFAAttempts)
+	repo.AssertNotCalled(t, "UseRecoveryCode", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,349 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image/png"
+	"mpb/internal/auth/dto"
+	model "mpb/internal/user"
+	"mpb/pkg/errors_constant"
+	"mpb/pkg/mailer"
+	"mpb/pkg/security"
+	"strings"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+const (
+	mfaPendingTokenType = "mfa_pending"
+
+	recoveryCodeCount = 10
+	qrCodeSize        = 256
+
+	// maxMFAAttempts кодов второго фактора за mfaAttemptWindow на пользователя: перебор 10^6 кодов становится бесполезным
+	maxMFAAttempts   = 10
+	mfaAttemptWindow = 15 * time.Minute
+	// totpReuseWindow покрывает окно проверки ±1 период, чтобы перехваченный код нельзя было предъявить повторно
+	totpReuseWindow = 90 * time.Second
+)
+
+var totpOpts = totp.ValidateOpts{Period: 30, Skew: 1, Digits: otp.DigitsSix, Algorithm: otp.AlgorithmSHA1}
+
+// TOTPEnrollment — данные для добавления аккаунта в приложение-аутентификатор
+type TOTPEnrollment struct {
+	Secret string
+	URL    string
+	QRCode []byte
+}
+
+// EnrollTOTP начинает подключение второго фактора: создаёт секрет, который заработает после ConfirmTOTP.
+// Повторный вызов до подтверждения выдаёт новый секрет.
+func (s *AuthService) EnrollTOTP(ctx context.Context, userID int, password string) (*TOTPEnrollment, error) {
+	user, err := s.repo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !security.CheckPasswordHash(password, user.PasswordHash) {
+		return nil, errors_constant.InvalidPassword
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      s.conf.TOTPIssuer,
+		AccountName: user.Username,
+		Period:      totpOpts.Period,
+		Digits:      totpOpts.Digits,
+		Algorithm:   totpOpts.Algorithm,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate totp secret: %w", err)
+	}
+
+	saved, err := s.repo.SaveTOTPSecret(ctx, userID, key.Secret())
+	if err != nil {
+		return nil, fmt.Errorf("failed to save totp secret: %w", err)
+	}
+	if !saved {
+		return nil, errors_constant.TOTPAlreadyEnabled
+	}
+
+	img, err := key.Image(qrCodeSize, qrCodeSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render qr code: %w", err)
+	}
+	var qr bytes.Buffer
+	if err := png.Encode(&qr, img); err != nil {
+		return nil, fmt.Errorf("failed to encode qr code: %w", err)
+	}
+
+	return &TOTPEnrollment{Secret: key.Secret(), URL: key.URL(), QRCode: qr.Bytes()}, nil
+}
+
+// ConfirmTOTP включает второй фактор по коду из приложения и возвращает коды восстановления.
+// Коды показываются один раз, в базе остаются только их хеши.
+func (s *AuthService) ConfirmTOTP(ctx context.Context, userID int, code string) ([]string, error) {
+	t, err := s.repo.FindTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if t.EnabledAt != nil {
+		return nil, errors_constant.TOTPAlreadyEnabled
+	}
+	if err := s.checkTOTP(ctx, t, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	enabled, err := s.repo.EnableTOTP(ctx, userID, hashes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enable totp: %w", err)
+	}
+	if !enabled {
+		return nil, errors_constant.TOTPAlreadyEnabled
+	}
+
+	s.notify(ctx, userID, "Two-factor authentication enabled",
+		"Two-factor authentication was enabled for your account. If this wasn't you, reset your password.")
+	return codes, nil
+}
+
+// DisableTOTP выключает второй фактор; нужен пароль и код из приложения или код восстановления
+func (s *AuthService) DisableTOTP(ctx context.Context, userID int, password, code string) error {
+	user, err := s.repo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if !security.CheckPasswordHash(password, user.PasswordHash) {
+		return errors_constant.InvalidPassword
+	}
+
+	t, err := s.enabledTOTP(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkSecondFactor(ctx, t, code); err != nil {
+		return err
+	}
+
+	if err := s.repo.DisableTOTP(ctx, userID); err != nil {
+		return fmt.Errorf("failed to disable totp: %w", err)
+	}
+
+	s.notify(ctx, userID, "Two-factor authentication disabled",
+		"Two-factor authentication was disabled for your account. If this wasn't you, reset your password and enable it again.")
+	return nil
+}
+
+// RegenerateRecoveryCodes заменяет коды восстановления; подтверждается только кодом из приложения
+func (s *AuthService) RegenerateRecoveryCodes(ctx context.Context, userID int, code string) ([]string, error) {
+	t, err := s.enabledTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkTOTP(ctx, t, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, fmt.Errorf("failed to replace recovery codes: %w", err)
+	}
+	return codes, nil
+}
+
+// LoginMFA завершает вход с включённым вторым фактором: обменивает токен mfa_pending и код на пару токенов.
+// Токен mfa_pending одноразовый.
+func (s *AuthService) LoginMFA(ctx context.Context, mfaToken, code string, client ClientInfo) (*dto.LoginResponse, error) {
+	claims, err := s.keys.Parse(mfaToken)
+	if err != nil || claims["typ"] != mfaPendingTokenType {
+		return nil, errors_constant.InvalidMFAToken
+	}
+	userIDFloat, okUser := claims["user_id"].(float64)
+	jti, okJTI := claims["jti"].(string)
+	if !okUser || !okJTI {
+		return nil, errors_constant.InvalidMFAToken
+	}
+	userID := int(userIDFloat)
+
+	t, err := s.enabledTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, errors_constant.TOTPNotEnabled) {
+			return nil, errors_constant.InvalidMFAToken
+		}
+		return nil, err
+	}
+	if err := s.checkSecondFactor(ctx, t, code); err != nil {
+		return nil, err
+	}
+
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return nil, errors_constant.InvalidMFAToken
+	}
+	fresh, err := s.repo.AcquireCooldown(ctx, "mfa_token:"+jti, time.Until(exp.Time)+time.Minute)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check mfa token: %w", err)
+	}
+	if !fresh {
+		return nil, errors_constant.InvalidMFAToken
+	}
+
+	user, err := s.repo.FindByID(userID)
+	if err != nil {
+		return nil, errors_constant.InvalidMFAToken
+	}
+	if !user.IsActive {
+		return nil, errors_constant.UserBanned
+	}
+
+	client.Device, _ = claims["device"].(string)
+	return s.startSession(ctx, user, client)
+}
+
+// mfaChallenge выдаёт вместо пары токенов короткоживущий токен mfa_pending
+func (s *AuthService) mfaChallenge(user *model.User, device string) (*dto.LoginResponse, error) {
+	jti, err := security.RandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := s.keys.Sign(jwt.MapClaims{
+		"typ":     mfaPendingTokenType,
+		"user_id": user.ID,
+		"device":  device,
+		"jti":     jti,
+		"exp":     time.Now().Add(s.conf.MFATokenTTL).Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &dto.LoginResponse{MFARequired: true, MFAToken: token}, nil
+}
+
+// mfaEnabled сообщает, включён ли у пользователя второй фактор
+func (s *AuthService) mfaEnabled(ctx context.Context, userID int) (bool, error) {
+	_, err := s.enabledTOTP(ctx, userID)
+	if errors.Is(err, errors_constant.TOTPNotEnabled) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *AuthService) enabledTOTP(ctx context.Context, userID int) (*TOTP, error) {
+	t, err := s.repo.FindTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if t.EnabledAt == nil {
+		return nil, errors_constant.TOTPNotEnabled
+	}
+	return t, nil
+}
+
+// checkSecondFactor принимает код из приложения или код восстановления
+func (s *AuthService) checkSecondFactor(ctx context.Context, t *TOTP, code string) error {
+	if isTOTPCode(code) {
+		return s.checkTOTP(ctx, t, code)
+	}
+
+	if err := s.countMFAAttempt(ctx, t.UserID); err != nil {
+		return err
+	}
+	used, err := s.repo.UseRecoveryCode(ctx, t.UserID, security.HashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return fmt.Errorf("failed to check recovery code: %w", err)
+	}
+	if !used {
+		return errors_constant.InvalidMFACode
+	}
+	return nil
+}
+
+// checkTOTP проверяет код из приложения; принятый код нельзя предъявить повторно
+func (s *AuthService) checkTOTP(ctx context.Context, t *TOTP, code string) error {
+	if err := s.countMFAAttempt(ctx, t.UserID); err != nil {
+		return err
+	}
+
+	code = strings.TrimSpace(code)
+	valid, err := totp.ValidateCustom(code, t.Secret, time.Now(), totpOpts)
+	if err != nil || !valid {
+		return errors_constant.InvalidMFACode
+	}
+
+	fresh, err := s.repo.AcquireCooldown(ctx, fmt.Sprintf("totp_used:%d:%s", t.UserID, code), totpReuseWindow)
+	if err != nil {
+		return fmt.Errorf("failed to check totp reuse: %w", err)
+	}
+	if !fresh {
+		return errors_constant.InvalidMFACode
+	}
+	return nil
+}
+
+func (s *AuthService) countMFAAttempt(ctx context.Context, userID int) error {
+	attempts, err := s.repo.CountAttempt(ctx, fmt.Sprintf("mfa_attempts:%d", userID), mfaAttemptWindow)
+	if err != nil {
+		return fmt.Errorf("failed to count mfa attempts: %w", err)
+	}
+	if attempts > maxMFAAttempts {
+		return errors_constant.TooManyMFAAttempts
+	}
+	return nil
+}
+
+// notify отправляет владельцу уведомление о смене настроек безопасности; сбой только логируется
+func (s *AuthService) notify(ctx context.Context, userID int, subject, body string) {
+	user, err := s.repo.FindByID(userID)
+	if err != nil || user.Email == nil {
+		return
+	}
+	if err := s.mailer.Send(ctx, mailer.Message{To: *user.Email, Subject: subject, Body: body}); err != nil {
+		s.logger.Error("failed to send security notice", err, watermill.LogFields{"user_id": userID})
+	}
+}
+
+func isTOTPCode(code string) bool {
+	code = strings.TrimSpace(code)
+	if len(code) != totpOpts.Digits.Length() {
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// newRecoveryCodes возвращает коды вида xxxxx-xxxxx и их хеши
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		raw, err := security.RandomToken(5)
+		if err != nil {
+			return nil, nil, err
+		}
+		codes[i] = raw[:5] + "-" + raw[5:]
+		hashes[i] = security.HashToken(raw)
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode прощает регистр, пробелы и дефис при вводе
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -0,0 +1,51 @@
+package comments
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"mpb/pkg/errors_constant"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// CommentCursor указывает на последний комментарий страницы; Score используется для сортировок top и controversial
+type CommentCursor struct {
+	Sort  string  `json:"s"`
+	Score float64 `json:"v,omitempty"`
+	ID    int     `json:"id"`
+}
+
+func NewCommentCursor(sort string, c *Comment) CommentCursor {
+	cursor := CommentCursor{Sort: sort, ID: c.ID}
+	switch sort {
+	case SortTop:
+		cursor.Score = c.ScoreTop
+	case SortControversial:
+		cursor.Score = c.ScoreControversial
+	}
+	return cursor
+}
+
+func (c CommentCursor) Encode() string {
+	payload, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(payload)
+}
+
+func DecodeCommentCursor(s string) (*CommentCursor, error) {
+	payload, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors_constant.InvalidCursor
+	}
+
+	var cursor CommentCursor
+	if err := json.Unmarshal(payload, &cursor); err != nil || cursor.ID <= 0 {
+		return nil, errors_constant.InvalidCursor
+	}
+	if _, ok := commentSorts[cursor.Sort]; !ok {
+		return nil, errors_constant.InvalidCursor
+	}
+	return &cursor, nil
+}
@@ -0,0 +1,40 @@
+package comments
+
+import (
+	"mpb/pkg/errors_constant"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommentCursor_EncodeDecode(t *testing.T) {
+	comment := &Comment{ID: 42, ScoreTop: 0.5314, ScoreControversial: 3.25}
+
+	tests := []struct {
+		sort  string
+		score float64
+	}{
+		{SortNew, 0},
+		{SortOld, 0},
+		{SortTop, 0.5314},
+		{SortControversial, 3.25},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			decoded, err := DecodeCommentCursor(NewCommentCursor(tt.sort, comment).Encode())
+			require.NoError(t, err)
+			assert.Equal(t, tt.sort, decoded.Sort)
+			assert.Equal(t, tt.score, decoded.Score)
+			assert.Equal(t, 42, decoded.ID)
+		})
+	}
+}
+
+func TestDecodeCommentCursor_Invalid(t *testing.T) {
+	for _, s := range []string{"", "%%%", CommentCursor{Sort: "best", ID: 1}.Encode(), CommentCursor{Sort: SortNew}.Encode()} {
+		_, err := DecodeCommentCursor(s)
+		assert.ErrorIs(t, err, errors_constant.InvalidCursor)
+	}
+}
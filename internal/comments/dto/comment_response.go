@@ -5,11 +5,24 @@ import "time"
 type CommentResponse struct {
 	ID        int        `json:"id"`
 	PostID    int        `json:"post_id"`
+	ParentID  *int       `json:"parent_id"`
+	Depth     int        `json:"depth"`
 	UserID    int        `json:"user_id"`
 	Text      string     `json:"text"`
 	Like      int        `json:"like"`
+	Dislike   int        `json:"dislike"`
+	IsLiked   bool       `json:"is_liked"`
+	MyVote    int        `json:"my_vote"`
 	Blocked   bool       `json:"blocked"`
 	CreatedAt time.Time  `json:"created_at"`
 	UpdatedAt time.Time  `json:"updated_at"`
 	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// ReplyCount — число ответов во всём поддереве; если Replies пуст при ReplyCount > 0, поддерево свёрнуто
+	ReplyCount int               `json:"reply_count"`
+	Replies    []CommentResponse `json:"replies,omitempty"`
+}
+
+type CommentListResponse struct {
+	Data       []CommentResponse `json:"data"`
+	NextCursor string            `json:"next_cursor,omitempty"`
 }
@@ -1,6 +1,27 @@
 package dto
 
+// CreateCommentRequest — комментарий к посту; для ответа задаётся parent_id, и тогда post_id можно опустить
 type CreateCommentRequest struct {
-	PostID int    `json:"post_id" validate:"required"`
-	Text   string `json:"text" validate:"required,min=1,max=500"`
+	PostID   int    `json:"post_id" validate:"required_without=ParentID,omitempty,gt=0"`
+	ParentID *int   `json:"parent_id" validate:"omitempty,gt=0"`
+	Text     string `json:"text" validate:"required,min=1,max=500"`
+}
+
+type ListCommentsQuery struct {
+	PostID int    `query:"post_id" validate:"required,gt=0"`
+	Sort   string `query:"sort" validate:"omitempty,oneof=new old top controversial"`
+	Limit  int    `query:"limit" validate:"omitempty,min=1,max=100"`
+	Cursor string `query:"cursor" validate:"omitempty,max=512"`
+	Depth  int    `query:"depth" validate:"omitempty,min=1,max=6"`
+}
+
+type RepliesQuery struct {
+	Sort   string `query:"sort" validate:"omitempty,oneof=new old top controversial"`
+	Limit  int    `query:"limit" validate:"omitempty,min=1,max=100"`
+	Cursor string `query:"cursor" validate:"omitempty,max=512"`
+	Depth  int    `query:"depth" validate:"omitempty,min=1,max=6"`
+}
+
+type VoteCommentRequest struct {
+	Value int `json:"value" validate:"required,oneof=-1 1"`
 }
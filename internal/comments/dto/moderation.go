@@ -0,0 +1,29 @@
+package dto
+
+import "time"
+
+type ReportCommentRequest struct {
+	Reason string `json:"reason" validate:"required,min=3,max=500"`
+}
+
+// ModerateCommentRequest — причина действия модератора, сохраняется в журнале
+type ModerateCommentRequest struct {
+	Reason string `json:"reason" validate:"required,min=3,max=500"`
+}
+
+type ModerationQueueQuery struct {
+	Limit  int    `query:"limit" validate:"omitempty,min=1,max=100"`
+	Cursor string `query:"cursor" validate:"omitempty,max=512"`
+}
+
+type QueuedCommentResponse struct {
+	CommentResponse
+	ReportsCount int       `json:"reports_count"`
+	Reasons      []string  `json:"reasons"`
+	QueuedAt     time.Time `json:"queued_at"`
+}
+
+type ModerationQueueResponse struct {
+	Data       []QueuedCommentResponse `json:"data"`
+	NextCursor string                  `json:"next_cursor,omitempty"`
+}
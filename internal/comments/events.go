@@ -0,0 +1,29 @@
+package comments
+
+import "time"
+
+// CommentLikedEvent — пользователь поставил комментарию «нравится» (в том числе сменив дизлайк)
+type CommentLikedEvent struct {
+	CommentID int `json:"comment_id"`
+	PostID    int `json:"post_id"`
+	AuthorID  int `json:"author_id"`
+	UserID    int `json:"user_id"`
+	Likes     int `json:"likes"`
+}
+
+// CommentUnlikedEvent — «нравится» снят или заменён дизлайком
+type CommentUnlikedEvent struct {
+	CommentID int `json:"comment_id"`
+	PostID    int `json:"post_id"`
+	AuthorID  int `json:"author_id"`
+	UserID    int `json:"user_id"`
+	Likes     int `json:"likes"`
+}
+
+type CommentCreatedEvent struct {
+	ID        int       `json:"id"`
+	PostID    int       `json:"post_id"`
+	ParentID  *int      `json:"parent_id,omitempty"`
+	UserID    int       `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -1,8 +1,10 @@
 package comments
 
 import (
+	"context"
 	"errors"
 	"mpb/internal/comments/dto"
+	"mpb/internal/posts"
 	"mpb/pkg/errors_constant"
 	"mpb/pkg/middleware"
 	"strconv"
@@ -19,13 +21,15 @@ func NewCommentsHandlers(service *CommentsService) *CommentsHandlers {
 }
 
 // CreateComment godoc
-// @Summary Create new comment
+// @Summary Create new comment or reply
+// @Description Set parent_id to reply to a comment; post_id may then be omitted.
 // @Tags Comments
 // @Accept json
 // @Produce json
 // @Param request body dto.CreateCommentRequest true "Comment data"
 // @Success 201 {object} dto.CommentResponse
 // @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
 // @Router /api/comments [post]
 func (h *CommentsHandlers) CreateComment(c *fiber.Ctx) error {
 	req := middleware.Body[dto.CreateCommentRequest](c)
@@ -38,12 +42,18 @@ func (h *CommentsHandlers) CreateComment(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "user not authenticated"})
 	}
 
-	comment, err := h.service.CreateComment(c.Context(), req.PostID, userID, req.Text)
+	comment, err := h.service.CreateComment(c.Context(), req.PostID, req.ParentID, userID, req.Text)
 	if err != nil {
-		if errors.Is(err, errors_constant.InvalidCommentText) {
+		switch {
+		case errors.Is(err, errors_constant.InvalidCommentText),
+			errors.Is(err, errors_constant.CommentTooDeep),
+			errors.Is(err, errors_constant.CommentPostMismatch):
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, errors_constant.CommentNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "parent comment not found"})
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(toCommentResponse(comment))
@@ -63,37 +73,240 @@ func (h *CommentsHandlers) GetComment(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid comment id"})
 	}
 
-	comment, err := h.service.repo.FindCommentByID(c.Context(), id)
+	comment, err := h.service.GetComment(c.Context(), id, viewer(c))
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "comment not found"})
+		if errors.Is(err, errors_constant.CommentNotFound) || errors.Is(err, errors_constant.CommentDeleted) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "comment not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
 	return c.JSON(toCommentResponse(comment))
 }
 
 // ListComments godoc
-// @Summary List all comments for post
+// @Summary Comments of a post
+// @Description Top-level comments page by sort with replies expanded depth levels, oldest first; deeper subtrees are collapsed (reply_count > 0 without replies).
 // @Tags Comments
 // @Produce json
 // @Param post_id query int true "Post ID"
-// @Success 200 {array} dto.CommentResponse
+// @Param sort query string false "new (default), old, top (Wilson lower bound), controversial"
+// @Param limit query int false "Page size (1-100, default 20)"
+// @Param cursor query string false "Cursor from next_cursor"
+// @Param depth query int false "Number of expanded levels (1-6, default all)"
+// @Success 200 {object} dto.CommentListResponse
+// @Failure 400 {object} map[string]string
 // @Router /api/comments [get]
 func (h *CommentsHandlers) ListComments(c *fiber.Ctx) error {
-	postID, err := strconv.Atoi(c.Query("post_id"))
-	if err != nil || postID <= 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid post_id"})
+	query := middleware.Query[dto.ListCommentsQuery](c)
+	if query == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid query parameters"})
+	}
+
+	opts, err := listOptions(query.Sort, query.Cursor, query.Limit, query.Depth)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	opts.Viewer = viewer(c)
+
+	page, err := h.service.ListComments(c.Context(), query.PostID, opts)
+	if err != nil {
+		return listError(c, err)
+	}
+
+	return sendPage(c, page)
+}
+
+// GetReplies godoc
+// @Summary Replies to a comment
+// @Description Loads a collapsed subtree page by page, oldest first by default.
+// @Tags Comments
+// @Produce json
+// @Param id path int true "Comment ID"
+// @Param sort query string false "old (default), new, top, controversial"
+// @Param limit query int false "Page size (1-100, default 20)"
+// @Param cursor query string false "Cursor from next_cursor"
+// @Param depth query int false "Number of expanded levels (1-6, default all)"
+// @Success 200 {object} dto.CommentListResponse
+// @Failure 404 {object} map[string]string
+// @Router /api/comments/{id}/replies [get]
+func (h *CommentsHandlers) GetReplies(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid comment id"})
+	}
+
+	query := middleware.Query[dto.RepliesQuery](c)
+	if query == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid query parameters"})
+	}
+
+	opts, err := listOptions(query.Sort, query.Cursor, query.Limit, query.Depth)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	opts.Viewer = viewer(c)
+
+	page, err := h.service.GetReplies(c.Context(), id, opts)
+	if err != nil {
+		return listError(c, err)
+	}
+
+	return sendPage(c, page)
+}
+
+// VoteComment godoc
+// @Summary Vote for a comment
+// @Description One vote per user: 1 likes the comment, -1 dislikes it; voting again switches the vote.
+// @Tags Comments
+// @Accept json
+// @Produce json
+// @Param id path int true "Comment ID"
+// @Param request body dto.VoteCommentRequest true "Vote"
+// @Success 200 {object} dto.CommentResponse
+// @Failure 404 {object} map[string]string
+// @Router /api/comments/{id}/vote [put]
+func (h *CommentsHandlers) VoteComment(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid comment id"})
+	}
+
+	req := middleware.Body[dto.VoteCommentRequest](c)
+	if req == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	userID, ok := c.Locals("user_id").(int)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "user not authenticated"})
+	}
+
+	comment, err := h.service.VoteComment(c.Context(), userID, id, req.Value)
+	if err != nil {
+		return voteError(c, err)
+	}
+
+	return c.JSON(toCommentResponse(comment))
+}
+
+// UnvoteComment godoc
+// @Summary Remove own vote from a comment
+// @Tags Comments
+// @Produce json
+// @Param id path int true "Comment ID"
+// @Success 200 {object} dto.CommentResponse
+// @Failure 404 {object} map[string]string
+// @Router /api/comments/{id}/vote [delete]
+func (h *CommentsHandlers) UnvoteComment(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid comment id"})
+	}
+
+	userID, ok := c.Locals("user_id").(int)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "user not authenticated"})
+	}
+
+	comment, err := h.service.UnvoteComment(c.Context(), userID, id)
+	if err != nil {
+		return voteError(c, err)
+	}
+
+	return c.JSON(toCommentResponse(comment))
+}
+
+// LikeComment godoc
+// @Summary Like a comment
+// @Description Idempotent; replaces the caller's dislike if there is one.
+// @Tags Comments
+// @Produce json
+// @Param id path int true "Comment ID"
+// @Success 200 {object} dto.CommentResponse
+// @Failure 404 {object} map[string]string
+// @Router /api/comments/{id}/like [post]
+func (h *CommentsHandlers) LikeComment(c *fiber.Ctx) error {
+	return h.changeVote(c, h.service.LikeComment)
+}
+
+// UnlikeComment godoc
+// @Summary Remove own like from a comment
+// @Tags Comments
+// @Produce json
+// @Param id path int true "Comment ID"
+// @Success 200 {object} dto.CommentResponse
+// @Failure 404 {object} map[string]string
+// @Router /api/comments/{id}/like [delete]
+func (h *CommentsHandlers) UnlikeComment(c *fiber.Ctx) error {
+	return h.changeVote(c, h.service.UnlikeComment)
+}
+
+func (h *CommentsHandlers) changeVote(c *fiber.Ctx, change func(context.Context, int, int) (*Comment, error)) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid comment id"})
 	}
 
-	comments, err := h.service.ListComments(c.Context(), postID)
+	userID, ok := c.Locals("user_id").(int)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "user not authenticated"})
+	}
+
+	comment, err := change(c.Context(), userID, id)
 	if err != nil {
+		return voteError(c, err)
+	}
+
+	return c.JSON(toCommentResponse(comment))
+}
+
+func listOptions(sort, cursor string, limit, depth int) (ListOptions, error) {
+	opts := ListOptions{Sort: sort, Limit: limit, Depth: depth}
+	if cursor != "" {
+		decoded, err := DecodeCommentCursor(cursor)
+		if err != nil {
+			return opts, err
+		}
+		opts.Cursor = decoded
+	}
+	return opts, nil
+}
+
+func listError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, errors_constant.InvalidCursor), errors.Is(err, errors_constant.InvalidCommentSort):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, errors_constant.CommentNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "comment not found"})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+}
+
+func voteError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, errors_constant.InvalidCommentVote):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, errors_constant.CommentNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "comment not found"})
+	case errors.Is(err, errors_constant.CommentDeleted):
+		return c.Status(fiber.StatusGone).JSON(fiber.Map{"error": "comment deleted"})
+	default:
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
+}
+
+func sendPage(c *fiber.Ctx, page *CommentsPage) error {
+	response := dto.CommentListResponse{
+		Data:       toTreeResponse(page.Comments),
+		NextCursor: page.NextCursor,
+	}
 
-	resp := make([]dto.CommentResponse, len(comments))
-	for i, cmt := range comments {
-		resp[i] = toCommentResponse(&cmt)
+	if page.NextCursor != "" {
+		c.Links(posts.NextPageURL(c, page.NextCursor), "next")
 	}
-	return c.JSON(resp)
+	return c.JSON(response)
 }
 
 // UpdateComment godoc
@@ -170,16 +383,45 @@ func (h *CommentsHandlers) DeleteComment(c *fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
+// viewer собирает зрителя из токена; для анонимного запроса возвращает нулевое значение
+func viewer(c *fiber.Ctx) Viewer {
+	userID, _ := c.Locals("user_id").(int)
+	role, _ := c.Locals("role").(string)
+	return Viewer{ID: userID, Moderator: IsModerator(role)}
+}
+
 func toCommentResponse(c *Comment) dto.CommentResponse {
-	return dto.CommentResponse{
-		ID:        c.ID,
-		PostID:    c.PostID,
-		UserID:    c.UserID,
-		Text:      c.Text,
-		Like:      c.Like,
-		Blocked:   c.Blocked,
-		CreatedAt: c.CreatedAt,
-		UpdatedAt: c.UpdatedAt,
-		DeletedAt: c.DeletedAt,
+	resp := dto.CommentResponse{
+		ID:         c.ID,
+		PostID:     c.PostID,
+		ParentID:   c.ParentID,
+		Depth:      c.Depth,
+		UserID:     c.UserID,
+		Text:       c.Text,
+		Like:       c.Like,
+		Dislike:    c.Dislike,
+		IsLiked:    c.MyVote == 1,
+		MyVote:     c.MyVote,
+		Blocked:    c.Blocked,
+		CreatedAt:  c.CreatedAt,
+		UpdatedAt:  c.UpdatedAt,
+		DeletedAt:  c.DeletedAt,
+		ReplyCount: c.ReplyCount,
+	}
+	// удалённый или скрытый от зрителя комментарий остаётся в дереве только как заглушка для ответов
+	if c.Hidden || c.DeletedAt != nil {
+		resp.Text = ""
+	}
+	return resp
+}
+
+func toTreeResponse(nodes []*CommentNode) []dto.CommentResponse {
+	resp := make([]dto.CommentResponse, len(nodes))
+	for i, node := range nodes {
+		resp[i] = toCommentResponse(&node.Comment)
+		if len(node.Replies) > 0 {
+			resp[i].Replies = toTreeResponse(node.Replies)
+		}
 	}
+	return resp
 }
@@ -2,14 +2,74 @@ package comments
 
 import "time"
 
+// MaxCommentDepth — максимальная глубина ответа (у комментариев верхнего уровня глубина 0)
+const MaxCommentDepth = 5
+
 type Comment struct {
 	ID        int        `db:"id"`
 	PostID    int        `db:"post_id"`
+	ParentID  *int       `db:"parent_comment_id"`
+	Depth     int        `db:"depth"`
+	Path      string     `db:"path"`
 	UserID    int        `db:"user_id"`
 	Text      string     `db:"text"`
 	Like      int        `db:"like"`
+	Dislike   int        `db:"dislike"`
 	Blocked   bool       `db:"blocked"`
 	CreatedAt time.Time  `db:"created_at"`
 	UpdatedAt time.Time  `db:"updated_at"`
 	DeletedAt *time.Time `db:"deleted_at"`
+	// ScoreTop и ScoreControversial вычисляет PostgreSQL по счётчикам оценок
+	ScoreTop           float64 `db:"score_top"`
+	ScoreControversial float64 `db:"score_controversial"`
+	// ReplyCount — число неудалённых и незаблокированных ответов во всём поддереве
+	ReplyCount int `db:"reply_count"`
+	// MyVote — оценка текущего пользователя: 1, -1 или 0
+	MyVote int `db:"-"`
+	// Hidden — комментарий удалён или заблокирован и показывается текущему пользователю только как заглушка
+	Hidden bool `db:"-"`
+}
+
+// Viewer — кто читает комментарии: заблокированные комментарии видны только автору и модераторам
+type Viewer struct {
+	ID        int
+	Moderator bool
+}
+
+// hides сообщает, что комментарий нужно показать зрителю заглушкой
+func (v Viewer) hides(c *Comment) bool {
+	return c.DeletedAt != nil || (c.Blocked && !v.Moderator && c.UserID != v.ID)
+}
+
+// Сортировки комментариев одного уровня
+const (
+	SortNew           = "new"
+	SortOld           = "old"
+	SortTop           = "top"
+	SortControversial = "controversial"
+)
+
+// commentSort — колонка очков (пусто — только по id) и направление сортировки
+type commentSort struct {
+	score string
+	desc  bool
+}
+
+var commentSorts = map[string]commentSort{
+	SortNew:           {desc: true},
+	SortOld:           {desc: false},
+	SortTop:           {score: "score_top", desc: true},
+	SortControversial: {score: "score_controversial", desc: true},
+}
+
+// CommentNode — комментарий с вложенными ответами; у свёрнутого поддерева Replies пуст, а ReplyCount > 0
+type CommentNode struct {
+	Comment
+	Replies []*CommentNode
+}
+
+// CommentsPage — страница комментариев одного уровня с раскрытыми ответами
+type CommentsPage struct {
+	Comments   []*CommentNode
+	NextCursor string
 }
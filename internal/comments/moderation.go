@@ -0,0 +1,154 @@
+package comments
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mpb/internal/user"
+	"mpb/pkg/errors_constant"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/lib/pq"
+)
+
+// Действия модератора
+const (
+	ModerationBlock   = "block"
+	ModerationUnblock = "unblock"
+	ModerationDelete  = "delete"
+	ModerationDismiss = "dismiss"
+)
+
+var linkPattern = regexp.MustCompile(`(?i)https?://|www\.`)
+
+// QueuedComment — комментарий в очереди модерации с открытыми жалобами
+type QueuedComment struct {
+	Comment
+	ReportsCount int            `db:"reports_count"`
+	Reasons      pq.StringArray `db:"reasons"`
+	QueuedAt     time.Time      `db:"queued_at"`
+}
+
+// QueueCursor указывает на последний комментарий страницы очереди: время первой открытой жалобы и id
+type QueueCursor struct {
+	Time time.Time `json:"t"`
+	ID   int       `json:"id"`
+}
+
+func (c QueueCursor) Encode() string {
+	payload, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(payload)
+}
+
+func DecodeQueueCursor(s string) (*QueueCursor, error) {
+	payload, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors_constant.InvalidCursor
+	}
+
+	var cursor QueueCursor
+	if err := json.Unmarshal(payload, &cursor); err != nil || cursor.ID <= 0 || cursor.Time.IsZero() {
+		return nil, errors_constant.InvalidCursor
+	}
+	return &cursor, nil
+}
+
+type ModerationQueuePage struct {
+	Comments   []QueuedComment
+	NextCursor string
+}
+
+// IsModerator сообщает, что роль может модерировать комментарии
+func IsModerator(role string) bool {
+	return role == user.RoleModerator || role == user.RoleAdmin
+}
+
+// ReportComment добавляет жалобу пользователя; повторная жалоба на тот же комментарий ничего не меняет
+func (s *CommentsService) ReportComment(ctx context.Context, userID, commentID int, reason string) error {
+	if _, err := s.GetCommentByID(ctx, commentID); err != nil {
+		return err
+	}
+
+	if _, err := s.repo.Report(ctx, commentID, &userID, reason); err != nil {
+		return fmt.Errorf("failed to report comment: %w", err)
+	}
+	return nil
+}
+
+// ModerationQueue возвращает страницу очереди модерации
+func (s *CommentsService) ModerationQueue(ctx context.Context, limit int, cursor *QueueCursor) (*ModerationQueuePage, error) {
+	limit = clampLimit(limit)
+
+	comments, err := s.repo.ModerationQueue(ctx, cursor, limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read moderation queue: %w", err)
+	}
+
+	page := &ModerationQueuePage{Comments: comments}
+	if len(comments) > limit {
+		page.Comments = comments[:limit]
+		last := page.Comments[limit-1]
+		page.NextCursor = QueueCursor{Time: last.QueuedAt, ID: last.ID}.Encode()
+	}
+	return page, nil
+}
+
+// ModerateComment блокирует, разблокирует, удаляет комментарий (текст стирается, ответы остаются) или отклоняет жалобы.
+// Причина сохраняется в журнале действий, открытые жалобы закрываются.
+func (s *CommentsService) ModerateComment(ctx context.Context, moderatorID, commentID int, action, reason string) error {
+	switch action {
+	case ModerationBlock, ModerationUnblock, ModerationDelete, ModerationDismiss:
+	default:
+		return errors_constant.InvalidModerationAction
+	}
+	if strings.TrimSpace(reason) == "" {
+		return errors_constant.InvalidModerationReason
+	}
+
+	if err := s.repo.Moderate(ctx, commentID, moderatorID, action, reason); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors_constant.CommentNotFound
+		}
+		return fmt.Errorf("failed to moderate comment: %w", err)
+	}
+
+	s.logger.Info("comment moderated", watermill.LogFields{
+		"comment_id": commentID, "moderator_id": moderatorID, "action": action,
+	})
+	return nil
+}
+
+// flagReason возвращает причину автоматической пометки текста или пустую строку
+func (s *CommentsService) flagReason(text string) string {
+	lower := strings.ToLower(text)
+	for _, word := range s.conf.FlagWords {
+		if strings.Contains(lower, word) {
+			return fmt.Sprintf("auto: contains %q", word)
+		}
+	}
+
+	if s.conf.FlagMaxLinks > 0 {
+		if links := len(linkPattern.FindAllStringIndex(text, -1)); links > s.conf.FlagMaxLinks {
+			return fmt.Sprintf("auto: %d links", links)
+		}
+	}
+	return ""
+}
+
+// autoFlag ставит новый комментарий в очередь модерации, если он подходит под правила; ошибка только логируется
+func (s *CommentsService) autoFlag(ctx context.Context, comment *Comment) {
+	reason := s.flagReason(comment.Text)
+	if reason == "" {
+		return
+	}
+
+	if _, err := s.repo.Report(ctx, comment.ID, nil, reason); err != nil {
+		s.logger.Error("failed to flag comment", err, watermill.LogFields{"comment_id": comment.ID})
+	}
+}
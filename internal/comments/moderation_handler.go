@@ -0,0 +1,192 @@
+package comments
+
+import (
+	"errors"
+	"mpb/internal/comments/dto"
+	"mpb/internal/posts"
+	"mpb/pkg/errors_constant"
+	"mpb/pkg/middleware"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ReportComment godoc
+// @Summary Report a comment
+// @Description Puts the comment into the moderation queue; a repeated report from the same user is ignored.
+// @Tags Comments
+// @Accept json
+// @Param id path int true "Comment ID"
+// @Param request body dto.ReportCommentRequest true "Report reason"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/comments/{id}/report [post]
+func (h *CommentsHandlers) ReportComment(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid comment id"})
+	}
+
+	req := middleware.Body[dto.ReportCommentRequest](c)
+	if req == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	userID, ok := c.Locals("user_id").(int)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "user not authenticated"})
+	}
+
+	if err := h.service.ReportComment(c.Context(), userID, id, req.Reason); err != nil {
+		return moderationError(c, err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ModerationQueue godoc
+// @Summary Comment moderation queue
+// @Description Comments with open reports, oldest report first. Available to moderators and admins.
+// @Tags Moderation
+// @Produce json
+// @Param limit query int false "Page size (1-100, default 20)"
+// @Param cursor query string false "Cursor from next_cursor"
+// @Success 200 {object} dto.ModerationQueueResponse
+// @Failure 403 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/moderation/comments [get]
+func (h *CommentsHandlers) ModerationQueue(c *fiber.Ctx) error {
+	query := middleware.Query[dto.ModerationQueueQuery](c)
+	if query == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid query parameters"})
+	}
+
+	var cursor *QueueCursor
+	if query.Cursor != "" {
+		decoded, err := DecodeQueueCursor(query.Cursor)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		cursor = decoded
+	}
+
+	page, err := h.service.ModerationQueue(c.Context(), query.Limit, cursor)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	response := dto.ModerationQueueResponse{
+		Data:       make([]dto.QueuedCommentResponse, len(page.Comments)),
+		NextCursor: page.NextCursor,
+	}
+	for i := range page.Comments {
+		queued := &page.Comments[i]
+		response.Data[i] = dto.QueuedCommentResponse{
+			CommentResponse: toCommentResponse(&queued.Comment),
+			ReportsCount:    queued.ReportsCount,
+			Reasons:         queued.Reasons,
+			QueuedAt:        queued.QueuedAt,
+		}
+	}
+
+	if page.NextCursor != "" {
+		c.Links(posts.NextPageURL(c, page.NextCursor), "next")
+	}
+	return c.JSON(response)
+}
+
+// BlockComment godoc
+// @Summary Block a comment
+// @Description Hides the comment from everyone except its author and moderators, closes open reports.
+// @Tags Moderation
+// @Accept json
+// @Param id path int true "Comment ID"
+// @Param request body dto.ModerateCommentRequest true "Reason"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/moderation/comments/{id}/block [post]
+func (h *CommentsHandlers) BlockComment(c *fiber.Ctx) error {
+	return h.moderate(c, ModerationBlock)
+}
+
+// UnblockComment godoc
+// @Summary Unblock a comment
+// @Tags Moderation
+// @Accept json
+// @Param id path int true "Comment ID"
+// @Param request body dto.ModerateCommentRequest true "Reason"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/moderation/comments/{id}/unblock [post]
+func (h *CommentsHandlers) UnblockComment(c *fiber.Ctx) error {
+	return h.moderate(c, ModerationUnblock)
+}
+
+// DismissReports godoc
+// @Summary Dismiss reports
+// @Description Closes open reports and leaves the comment as is.
+// @Tags Moderation
+// @Accept json
+// @Param id path int true "Comment ID"
+// @Param request body dto.ModerateCommentRequest true "Reason"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/moderation/comments/{id}/dismiss [post]
+func (h *CommentsHandlers) DismissReports(c *fiber.Ctx) error {
+	return h.moderate(c, ModerationDismiss)
+}
+
+// PurgeComment godoc
+// @Summary Delete a comment
+// @Description Erases the comment text and marks it deleted; replies stay in the thread under a placeholder. The action stays in the moderation log.
+// @Tags Moderation
+// @Accept json
+// @Param id path int true "Comment ID"
+// @Param request body dto.ModerateCommentRequest true "Reason"
+// @Success 204
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /api/moderation/comments/{id} [delete]
+func (h *CommentsHandlers) PurgeComment(c *fiber.Ctx) error {
+	return h.moderate(c, ModerationDelete)
+}
+
+func (h *CommentsHandlers) moderate(c *fiber.Ctx, action string) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid comment id"})
+	}
+
+	req := middleware.Body[dto.ModerateCommentRequest](c)
+	if req == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+
+	moderatorID, ok := c.Locals("user_id").(int)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "user not authenticated"})
+	}
+
+	if err := h.service.ModerateComment(c.Context(), moderatorID, id, action, req.Reason); err != nil {
+		return moderationError(c, err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func moderationError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, errors_constant.InvalidModerationAction), errors.Is(err, errors_constant.InvalidModerationReason):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, errors_constant.CommentNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "comment not found"})
+	case errors.Is(err, errors_constant.CommentDeleted):
+		return c.Status(fiber.StatusGone).JSON(fiber.Map{"error": "comment deleted"})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+}
@@ -5,6 +5,9 @@ import (
 	"database/sql"
 	"fmt"
 	"mpb/pkg/db"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type CommentsRepository struct {
@@ -15,16 +18,22 @@ func NewCommentsRepository(db *db.Db) *CommentsRepository {
 	return &CommentsRepository{db: db}
 }
 
+// Create сохраняет комментарий; глубина и path вычисляются по родителю в том же запросе
 func (r *CommentsRepository) Create(ctx context.Context, c *Comment) error {
 	const query = `
-		INSERT INTO comments (post_id, user_id, text, blocked, "like")
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, created_at, updated_at
+		INSERT INTO comments (id, post_id, parent_comment_id, depth, path, user_id, text, blocked)
+		SELECT n.id, $1, $2,
+			COALESCE(p.depth + 1, 0),
+			COALESCE(p.path || '.', '') || lpad(n.id::text, 10, '0'),
+			$3, $4, $5
+		FROM (SELECT nextval('comments_id_seq') AS id) n
+		LEFT JOIN comments p ON p.id = $2
+		RETURNING id, depth, path, created_at, updated_at
 	`
 
 	return r.db.Conn.QueryRowContext(ctx, query,
-		c.PostID, c.UserID, c.Text, c.Blocked, c.Like,
-	).Scan(&c.ID, &c.CreatedAt, &c.UpdatedAt)
+		c.PostID, c.ParentID, c.UserID, c.Text, c.Blocked,
+	).Scan(&c.ID, &c.Depth, &c.Path, &c.CreatedAt, &c.UpdatedAt)
 }
 
 func (r *CommentsRepository) Update(ctx context.Context, c *Comment) error {
@@ -32,14 +41,13 @@ func (r *CommentsRepository) Update(ctx context.Context, c *Comment) error {
 		UPDATE comments
 		SET text = $1,
 		    blocked = $2,
-		    "like" = $3,
 		    updated_at = NOW()
-		WHERE id = $4 AND deleted_at IS NULL
+		WHERE id = $3 AND deleted_at IS NULL
 		RETURNING updated_at
 	`
 
 	return r.db.Conn.QueryRowContext(ctx, query,
-		c.Text, c.Blocked, c.Like, c.ID,
+		c.Text, c.Blocked, c.ID,
 	).Scan(&c.UpdatedAt)
 }
 
@@ -65,26 +73,309 @@ func (r *CommentsRepository) Delete(ctx context.Context, commentID int) error {
 	return nil
 }
 
-func (r *CommentsRepository) List(ctx context.Context, postID int) ([]Comment, error) {
-	query := `
-		SELECT * FROM comments
-		WHERE deleted_at IS NULL AND post_id = $1
-		ORDER BY created_at DESC
-	`
+// commentColumns — колонки комментария и число неудалённых и незаблокированных ответов во всём его поддереве.
+// Поддерево выбирается диапазоном по path, а не LIKE с префиксом из соседней колонки: так коррелированный
+// подзапрос идёт по индексу (post_id, path). В collation "C" за '.' сразу следует '/'.
+const commentColumns = `
+	c.*,
+	(SELECT COUNT(*) FROM comments r
+	 WHERE r.post_id = c.post_id AND r.path > c.path || '.' AND r.path < c.path || '/'
+	   AND r.deleted_at IS NULL AND NOT r.blocked) AS reply_count`
+
+// visibleCondition — скрытые от зрителя комментарии (удалённые, а для всех, кроме автора и модераторов,
+// ещё и заблокированные) остаются в выборке, только если под ними есть видимые ответы
+func visibleCondition(viewer Viewer, args *[]interface{}) string {
+	hidden := func(alias string) string {
+		return alias + ".deleted_at IS NOT NULL"
+	}
+	if !viewer.Moderator {
+		*args = append(*args, viewer.ID)
+		n := len(*args)
+		hidden = func(alias string) string {
+			return fmt.Sprintf("(%[1]s.deleted_at IS NOT NULL OR (%[1]s.blocked AND %[1]s.user_id <> $%[2]d))", alias, n)
+		}
+	}
+
+	return fmt.Sprintf(`
+	(NOT %s OR EXISTS (
+		SELECT 1 FROM comments d
+		WHERE d.post_id = c.post_id AND d.path > c.path || '.' AND d.path < c.path || '/' AND NOT %s
+	))`, hidden("c"), hidden("d"))
+}
+
+// CommentFilter выбирает страницу комментариев одного уровня: верхнего уровня поста или ответов на ParentID
+type CommentFilter struct {
+	PostID   int
+	ParentID *int
+	Sort     string
+	Cursor   *CommentCursor
+	Limit    int
+	Viewer   Viewer
+}
+
+// ListPage возвращает страницу комментариев одного уровня в порядке сортировки
+func (r *CommentsRepository) ListPage(ctx context.Context, f CommentFilter) ([]Comment, error) {
+	var args []interface{}
+	query := `SELECT` + commentColumns + ` FROM comments c WHERE` + visibleCondition(f.Viewer, &args)
+
+	if f.ParentID != nil {
+		args = append(args, *f.ParentID)
+		query += fmt.Sprintf(" AND c.parent_comment_id = $%d", len(args))
+	} else {
+		args = append(args, f.PostID)
+		query += fmt.Sprintf(" AND c.post_id = $%d AND c.parent_comment_id IS NULL", len(args))
+	}
+
+	order := commentSorts[f.Sort]
+	if f.Cursor != nil {
+		switch {
+		case order.score == "" && order.desc:
+			args = append(args, f.Cursor.ID)
+			query += fmt.Sprintf(" AND c.id < $%d", len(args))
+		case order.score == "":
+			args = append(args, f.Cursor.ID)
+			query += fmt.Sprintf(" AND c.id > $%d", len(args))
+		default:
+			args = append(args, f.Cursor.Score, f.Cursor.ID)
+			query += fmt.Sprintf(" AND (c.%s, c.id) < ($%d, $%d)", order.score, len(args)-1, len(args))
+		}
+	}
+
+	direction := "ASC"
+	if order.desc {
+		direction = "DESC"
+	}
+	if order.score != "" {
+		query += fmt.Sprintf(" ORDER BY c.%s %s,", order.score, direction)
+	} else {
+		query += " ORDER BY"
+	}
+	args = append(args, f.Limit)
+	query += fmt.Sprintf(" c.id %s LIMIT $%d", direction, len(args))
 
 	var comments []Comment
-	if err := r.db.Conn.SelectContext(ctx, &comments, query, postID); err != nil {
+	if err := r.db.Conn.SelectContext(ctx, &comments, query, args...); err != nil {
 		return nil, fmt.Errorf("failed to list comments: %w", err)
 	}
 
 	return comments, nil
 }
 
+// ListDescendants возвращает ответы на комментарии parents до глубины maxDepth в порядке обхода дерева
+func (r *CommentsRepository) ListDescendants(ctx context.Context, parents []Comment, maxDepth int, viewer Viewer) ([]Comment, error) {
+	if len(parents) == 0 {
+		return nil, nil
+	}
+
+	patterns := make([]string, len(parents))
+	for i, p := range parents {
+		patterns[i] = p.Path + ".%"
+	}
+
+	args := []interface{}{parents[0].PostID, pq.Array(patterns), maxDepth}
+	query := `SELECT` + commentColumns + `
+		FROM comments c
+		WHERE c.post_id = $1 AND c.path LIKE ANY($2) AND c.depth <= $3 AND` + visibleCondition(viewer, &args) + `
+		ORDER BY c.path
+	`
+
+	var comments []Comment
+	if err := r.db.Conn.SelectContext(ctx, &comments, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list replies: %w", err)
+	}
+
+	return comments, nil
+}
+
+// SetLike ставит или меняет оценку пользователя; счётчики комментария обновляет триггер.
+// Возвращает false, если такая оценка уже стояла.
+func (r *CommentsRepository) SetLike(ctx context.Context, commentID, userID, value int) (bool, error) {
+	const query = `
+		INSERT INTO comment_likes (comment_id, user_id, value)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (comment_id, user_id) DO UPDATE SET value = EXCLUDED.value
+		WHERE comment_likes.value <> EXCLUDED.value
+	`
+
+	res, err := r.db.Conn.ExecContext(ctx, query, commentID, userID, value)
+	if err != nil {
+		return false, fmt.Errorf("failed to set comment like: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// VotesByUser возвращает оценки пользователя для комментариев из списка
+func (r *CommentsRepository) VotesByUser(ctx context.Context, userID int, commentIDs []int) (map[int]int, error) {
+	votes := make(map[int]int)
+	if userID == 0 || len(commentIDs) == 0 {
+		return votes, nil
+	}
+
+	const query = `SELECT comment_id, value FROM comment_likes WHERE user_id = $1 AND comment_id = ANY($2)`
+
+	rows, err := r.db.Conn.QueryContext(ctx, query, userID, pq.Array(commentIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read comment votes: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var commentID, value int
+		if err := rows.Scan(&commentID, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan comment vote: %w", err)
+		}
+		votes[commentID] = value
+	}
+	return votes, rows.Err()
+}
+
+// RemoveLike снимает оценку пользователя; возвращает false, если оценки не было
+func (r *CommentsRepository) RemoveLike(ctx context.Context, commentID, userID int) (bool, error) {
+	const query = `DELETE FROM comment_likes WHERE comment_id = $1 AND user_id = $2`
+
+	res, err := r.db.Conn.ExecContext(ctx, query, commentID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove comment like: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return rows > 0, nil
+}
+
 func (r *CommentsRepository) FindCommentByID(ctx context.Context, commentID int) (*Comment, error) {
 	var comment Comment
-	const query = `SELECT * FROM comments WHERE id = $1 AND deleted_at IS NULL`
+	const query = `SELECT` + commentColumns + ` FROM comments c WHERE c.id = $1 AND c.deleted_at IS NULL`
 	if err := r.db.Conn.GetContext(ctx, &comment, query, commentID); err != nil {
 		return nil, fmt.Errorf("failed to find comment by id: %w", err)
 	}
 	return &comment, nil
 }
+
+// Report добавляет жалобу на комментарий; reporterID = nil — автоматическая пометка.
+// Возвращает false, если у пользователя уже есть открытая жалоба на этот комментарий.
+func (r *CommentsRepository) Report(ctx context.Context, commentID int, reporterID *int, reason string) (bool, error) {
+	const query = `
+		INSERT INTO comment_reports (comment_id, reporter_id, reason)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING
+	`
+
+	res, err := r.db.Conn.ExecContext(ctx, query, commentID, reporterID, reason)
+	if err != nil {
+		return false, fmt.Errorf("failed to report comment: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// ModerationQueue возвращает комментарии с открытыми жалобами, начиная с давно ожидающих
+func (r *CommentsRepository) ModerationQueue(ctx context.Context, cursor *QueueCursor, limit int) ([]QueuedComment, error) {
+	query := `
+		SELECT` + commentColumns + `, q.reports_count, q.reasons, q.queued_at
+		FROM (
+			SELECT comment_id, COUNT(*) AS reports_count,
+				array_agg(reason ORDER BY created_at) AS reasons, MIN(created_at) AS queued_at
+			FROM comment_reports
+			WHERE resolved_at IS NULL
+			GROUP BY comment_id
+		) q
+		JOIN comments c ON c.id = q.comment_id
+		WHERE c.deleted_at IS NULL`
+	var args []interface{}
+
+	if cursor != nil {
+		args = append(args, cursor.Time, cursor.ID)
+		query += ` AND (q.queued_at, c.id) > ($1, $2)`
+	}
+
+	args = append(args, limit)
+	query += fmt.Sprintf(" ORDER BY q.queued_at, c.id LIMIT $%d", len(args))
+
+	var comments []QueuedComment
+	if err := r.db.Conn.SelectContext(ctx, &comments, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to read moderation queue: %w", err)
+	}
+	return comments, nil
+}
+
+// Moderate применяет действие модератора, записывает его в журнал и закрывает открытые жалобы.
+// Возвращает sql.ErrNoRows, если комментария нет.
+func (r *CommentsRepository) Moderate(ctx context.Context, commentID, moderatorID int, action, reason string) error {
+	tx, err := r.db.Conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var found bool
+	switch action {
+	case ModerationDismiss:
+		const exists = `SELECT EXISTS (SELECT 1 FROM comments WHERE id = $1 AND deleted_at IS NULL)`
+		if err := tx.GetContext(ctx, &found, exists, commentID); err != nil {
+			return fmt.Errorf("failed to find comment: %w", err)
+		}
+	default:
+		found, err = applyModeration(ctx, tx, commentID, action)
+		if err != nil {
+			return err
+		}
+	}
+	if !found {
+		return sql.ErrNoRows
+	}
+
+	const resolve = `UPDATE comment_reports SET resolved_at = NOW() WHERE comment_id = $1 AND resolved_at IS NULL`
+	if _, err := tx.ExecContext(ctx, resolve, commentID); err != nil {
+		return fmt.Errorf("failed to resolve reports: %w", err)
+	}
+
+	const logAction = `
+		INSERT INTO comment_moderation_actions (comment_id, moderator_id, action, reason)
+		VALUES ($1, $2, $3, $4)
+	`
+	if _, err := tx.ExecContext(ctx, logAction, commentID, moderatorID, action, reason); err != nil {
+		return fmt.Errorf("failed to log moderation action: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit moderation action: %w", err)
+	}
+	return nil
+}
+
+func applyModeration(ctx context.Context, tx *sqlx.Tx, commentID int, action string) (bool, error) {
+	var query string
+	switch action {
+	case ModerationBlock:
+		query = `UPDATE comments SET blocked = TRUE WHERE id = $1 AND deleted_at IS NULL`
+	case ModerationUnblock:
+		query = `UPDATE comments SET blocked = FALSE WHERE id = $1 AND deleted_at IS NULL`
+	case ModerationDelete:
+		// текст стирается, а ответы остаются: в дереве комментарий становится заглушкой, как при удалении автором
+		query = `UPDATE comments SET text = '', deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL`
+	default:
+		return false, fmt.Errorf("unknown moderation action %q", action)
+	}
+
+	res, err := tx.ExecContext(ctx, query, commentID)
+	if err != nil {
+		return false, fmt.Errorf("failed to %s comment: %w", action, err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return rows > 0, nil
+}
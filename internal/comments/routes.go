@@ -2,30 +2,34 @@ package comments
 
 import (
 	"mpb/internal/comments/dto"
+	"mpb/internal/user"
+	"mpb/pkg/jwtkeys"
 	"mpb/pkg/middleware"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type CommentsRoutes struct {
-	router    fiber.Router
-	handler   *CommentsHandlers
-	jwtSecret []byte
+	router  fiber.Router
+	handler *CommentsHandlers
+	jwtKeys *jwtkeys.KeySet
 }
 
-func NewCommentsRoutes(router fiber.Router, handler *CommentsHandlers, jwtSecret []byte) *CommentsRoutes {
-	return &CommentsRoutes{router: router, handler: handler, jwtSecret: jwtSecret}
+func NewCommentsRoutes(router fiber.Router, handler *CommentsHandlers, jwtKeys *jwtkeys.KeySet) *CommentsRoutes {
+	return &CommentsRoutes{router: router, handler: handler, jwtKeys: jwtKeys}
 }
 
 func (r *CommentsRoutes) Register() {
 	comments := r.router.Group("/comments")
 
-	comments.Get("/", r.handler.ListComments)
-	comments.Get("/:id", r.handler.GetComment)
+	comments.Get("/", middleware.OptionalJWTAuth(r.jwtKeys), middleware.ValidateQuery[dto.ListCommentsQuery](), r.handler.ListComments)
+	comments.Get("/:id", middleware.OptionalJWTAuth(r.jwtKeys), r.handler.GetComment)
+	comments.Get("/:id/replies", middleware.OptionalJWTAuth(r.jwtKeys), middleware.ValidateQuery[dto.RepliesQuery](), r.handler.GetReplies)
 
-	commentsAuth := comments.Group("/", middleware.JWTAuth(r.jwtSecret))
+	commentsAuth := comments.Group("/", middleware.JWTAuth(r.jwtKeys))
 
 	commentsAuth.Post("/",
+		middleware.RequireVerifiedEmail(),
 		middleware.ValidateBody[dto.CreateCommentRequest](),
 		r.handler.CreateComment,
 	)
@@ -36,4 +40,28 @@ func (r *CommentsRoutes) Register() {
 	)
 
 	commentsAuth.Delete("/:id", r.handler.DeleteComment)
+
+	commentsAuth.Put("/:id/vote",
+		middleware.ValidateBody[dto.VoteCommentRequest](),
+		r.handler.VoteComment,
+	)
+	commentsAuth.Delete("/:id/vote", r.handler.UnvoteComment)
+	commentsAuth.Post("/:id/like", r.handler.LikeComment)
+	commentsAuth.Delete("/:id/like", r.handler.UnlikeComment)
+	commentsAuth.Post("/:id/report",
+		middleware.ValidateBody[dto.ReportCommentRequest](),
+		r.handler.ReportComment,
+	)
+
+	// очередь модерации и действия над комментариями доступны модераторам и администраторам
+	moderation := r.router.Group("/moderation/comments",
+		middleware.JWTAuth(r.jwtKeys),
+		middleware.RequireRole(user.RoleModerator, user.RoleAdmin),
+	)
+
+	moderation.Get("/", middleware.ValidateQuery[dto.ModerationQueueQuery](), r.handler.ModerationQueue)
+	moderation.Post("/:id/block", middleware.ValidateBody[dto.ModerateCommentRequest](), r.handler.BlockComment)
+	moderation.Post("/:id/unblock", middleware.ValidateBody[dto.ModerateCommentRequest](), r.handler.UnblockComment)
+	moderation.Post("/:id/dismiss", middleware.ValidateBody[dto.ModerateCommentRequest](), r.handler.DismissReports)
+	moderation.Delete("/:id", middleware.ValidateBody[dto.ModerateCommentRequest](), r.handler.PurgeComment)
 }
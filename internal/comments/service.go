@@ -2,34 +2,73 @@ package comments
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"mpb/configs"
 	"mpb/pkg/errors_constant"
 	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
 )
 
 type CommentsRepositoryInterface interface {
 	Create(ctx context.Context, c *Comment) error
 	Update(ctx context.Context, c *Comment) error
 	Delete(ctx context.Context, commentID int) error
-	List(ctx context.Context, postID int) ([]Comment, error)
+	ListPage(ctx context.Context, f CommentFilter) ([]Comment, error)
+	ListDescendants(ctx context.Context, parents []Comment, maxDepth int, viewer Viewer) ([]Comment, error)
+	SetLike(ctx context.Context, commentID, userID, value int) (bool, error)
+	RemoveLike(ctx context.Context, commentID, userID int) (bool, error)
+	VotesByUser(ctx context.Context, userID int, commentIDs []int) (map[int]int, error)
+	Report(ctx context.Context, commentID int, reporterID *int, reason string) (bool, error)
+	ModerationQueue(ctx context.Context, cursor *QueueCursor, limit int) ([]QueuedComment, error)
+	Moderate(ctx context.Context, commentID, moderatorID int, action, reason string) error
 	FindCommentByID(ctx context.Context, commentID int) (*Comment, error)
 }
 
 type CommentsService struct {
-	repo CommentsRepositoryInterface
+	repo      CommentsRepositoryInterface
+	publisher message.Publisher
+	logger    watermill.LoggerAdapter
+	conf      configs.CommentsConfig
 }
 
-func NewCommentsService(repo CommentsRepositoryInterface) *CommentsService {
-	return &CommentsService{repo: repo}
+func NewCommentsService(repo CommentsRepositoryInterface, publisher message.Publisher, logger watermill.LoggerAdapter, conf configs.CommentsConfig) *CommentsService {
+	return &CommentsService{repo: repo, publisher: publisher, logger: logger, conf: conf}
 }
 
-func (s *CommentsService) CreateComment(ctx context.Context, postID, userID int, text string) (*Comment, error) {
+// CreateComment создаёт комментарий к посту или, если задан parentID, ответ на другой комментарий
+func (s *CommentsService) CreateComment(ctx context.Context, postID int, parentID *int, userID int, text string) (*Comment, error) {
 	if len(text) == 0 {
 		return nil, errors_constant.InvalidCommentText
 	}
 
+	if parentID != nil {
+		parent, err := s.repo.FindCommentByID(ctx, *parentID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, errors_constant.CommentNotFound
+			}
+			return nil, fmt.Errorf("failed to find parent comment: %w", err)
+		}
+		if parent.Blocked {
+			return nil, errors_constant.CommentNotFound
+		}
+		if postID != 0 && postID != parent.PostID {
+			return nil, errors_constant.CommentPostMismatch
+		}
+		if parent.Depth+1 > MaxCommentDepth {
+			return nil, errors_constant.CommentTooDeep
+		}
+		postID = parent.PostID
+	}
+
 	comment := &Comment{
 		PostID:    postID,
+		ParentID:  parentID,
 		UserID:    userID,
 		Text:      text,
 		Like:      0,
@@ -42,9 +81,36 @@ func (s *CommentsService) CreateComment(ctx context.Context, postID, userID int,
 		return nil, fmt.Errorf("failed to create comment: %w", err)
 	}
 
+	s.autoFlag(ctx, comment)
+	s.publishCreated(comment)
 	return comment, nil
 }
 
+func (s *CommentsService) publishCreated(comment *Comment) {
+	event := CommentCreatedEvent{
+		ID:        comment.ID,
+		PostID:    comment.PostID,
+		ParentID:  comment.ParentID,
+		UserID:    comment.UserID,
+		CreatedAt: comment.CreatedAt,
+	}
+
+	s.publish("comment.created", event)
+}
+
+func (s *CommentsService) publish(topic string, event interface{}) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		s.logger.Error(fmt.Sprintf("failed to marshal %s event", topic), err, nil)
+		return
+	}
+
+	msg := message.NewMessage(watermill.NewUUID(), payload)
+	if err := s.publisher.Publish(topic, msg); err != nil {
+		s.logger.Error(fmt.Sprintf("failed to publish %s event", topic), err, nil)
+	}
+}
+
 func (s *CommentsService) UpdateComment(ctx context.Context, userID, commentID int, newText string) (*Comment, error) {
 	comment, err := s.repo.FindCommentByID(ctx, commentID)
 	if err != nil {
@@ -90,18 +156,162 @@ func (s *CommentsService) DeleteComment(ctx context.Context, userID, commentID i
 	return nil
 }
 
-func (s *CommentsService) ListComments(ctx context.Context, postID int) ([]Comment, error) {
-	comments, err := s.repo.List(ctx, postID)
+// ListOptions — параметры страницы: сортировка, курсор, размер и число раскрываемых уровней ответов.
+// Viewer определяет видимость заблокированных комментариев и чьи оценки проставляются в MyVote.
+type ListOptions struct {
+	Sort   string
+	Cursor *CommentCursor
+	Limit  int
+	Depth  int
+	Viewer Viewer
+}
+
+// ListComments возвращает страницу комментариев верхнего уровня поста с ответами, раскрытыми на Depth уровней.
+// Ответы внутри страницы идут в порядке обхода дерева (от старых к новым), глубже — свёрнуты.
+func (s *CommentsService) ListComments(ctx context.Context, postID int, opts ListOptions) (*CommentsPage, error) {
+	if opts.Sort == "" {
+		opts.Sort = SortNew
+	}
+	return s.listPage(ctx, CommentFilter{PostID: postID}, opts)
+}
+
+// GetReplies возвращает страницу ответов на комментарий; по умолчанию от старых к новым
+func (s *CommentsService) GetReplies(ctx context.Context, commentID int, opts ListOptions) (*CommentsPage, error) {
+	if opts.Sort == "" {
+		opts.Sort = SortOld
+	}
+
+	page, err := s.listPage(ctx, CommentFilter{ParentID: &commentID}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// пустая первая страница — либо у комментария нет ответов, либо его нет совсем
+	if len(page.Comments) == 0 && opts.Cursor == nil {
+		if _, err := s.repo.FindCommentByID(ctx, commentID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, errors_constant.CommentNotFound
+			}
+			return nil, fmt.Errorf("failed to find comment: %w", err)
+		}
+	}
+	return page, nil
+}
+
+func (s *CommentsService) listPage(ctx context.Context, f CommentFilter, opts ListOptions) (*CommentsPage, error) {
+	if _, ok := commentSorts[opts.Sort]; !ok {
+		return nil, errors_constant.InvalidCommentSort
+	}
+	if opts.Cursor != nil && opts.Cursor.Sort != opts.Sort {
+		return nil, errors_constant.InvalidCursor
+	}
+
+	limit := clampLimit(opts.Limit)
+	f.Sort = opts.Sort
+	f.Cursor = opts.Cursor
+	f.Limit = limit + 1
+	f.Viewer = opts.Viewer
+
+	comments, err := s.repo.ListPage(ctx, f)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list comments: %w", err)
 	}
 
-	return comments, nil
+	page := &CommentsPage{Comments: []*CommentNode{}}
+	if len(comments) > limit {
+		comments = comments[:limit]
+		page.NextCursor = NewCommentCursor(opts.Sort, &comments[limit-1]).Encode()
+	}
+	if len(comments) == 0 {
+		return page, nil
+	}
+
+	maxDepth := comments[0].Depth + clampDepth(opts.Depth) - 1
+	if maxDepth > comments[0].Depth {
+		replies, err := s.repo.ListDescendants(ctx, comments, maxDepth, opts.Viewer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list replies: %w", err)
+		}
+		comments = append(comments, replies...)
+	}
+
+	s.applyViewer(ctx, opts.Viewer, comments)
+	page.Comments = buildTree(comments)
+	return page, nil
+}
+
+// applyViewer отмечает скрытые от зрителя комментарии и проставляет его оценки одним запросом на всю страницу;
+// ошибка чтения оценок только логируется
+func (s *CommentsService) applyViewer(ctx context.Context, viewer Viewer, comments []Comment) {
+	for i := range comments {
+		comments[i].Hidden = viewer.hides(&comments[i])
+	}
+
+	viewerID := viewer.ID
+	if viewerID == 0 || len(comments) == 0 {
+		return
+	}
+
+	ids := make([]int, len(comments))
+	for i := range comments {
+		ids[i] = comments[i].ID
+	}
+
+	votes, err := s.repo.VotesByUser(ctx, viewerID, ids)
+	if err != nil {
+		s.logger.Error("failed to load comment votes", err, watermill.LogFields{"user_id": viewerID})
+		return
+	}
+	for i := range comments {
+		comments[i].MyVote = votes[comments[i].ID]
+	}
+}
+
+func clampLimit(limit int) int {
+	if limit <= 0 {
+		return defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		return maxPageLimit
+	}
+	return limit
+}
+
+// clampDepth приводит число раскрываемых уровней к диапазону [1, MaxCommentDepth+1]; 0 — все уровни
+func clampDepth(depth int) int {
+	if depth <= 0 || depth > MaxCommentDepth+1 {
+		return MaxCommentDepth + 1
+	}
+	return depth
+}
+
+// buildTree собирает дерево из комментариев, в которых родитель всегда идёт раньше ответа.
+// Комментарии, чей родитель не попал в выборку, становятся корнями в исходном порядке.
+func buildTree(comments []Comment) []*CommentNode {
+	nodes := make(map[int]*CommentNode, len(comments))
+	roots := make([]*CommentNode, 0)
+
+	for i := range comments {
+		node := &CommentNode{Comment: comments[i]}
+		nodes[node.ID] = node
+
+		if node.ParentID != nil {
+			if parent, ok := nodes[*node.ParentID]; ok {
+				parent.Replies = append(parent.Replies, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+	return roots
 }
 
 func (s *CommentsService) GetCommentByID(ctx context.Context, commentID int) (*Comment, error) {
 	comment, err := s.repo.FindCommentByID(ctx, commentID)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors_constant.CommentNotFound
+		}
 		return nil, fmt.Errorf("failed to find comment: %w", err)
 	}
 
@@ -112,38 +322,89 @@ func (s *CommentsService) GetCommentByID(ctx context.Context, commentID int) (*C
 	return comment, nil
 }
 
-func (s *CommentsService) LikeComment(ctx context.Context, userID, commentID int) (*Comment, error) {
-	comment, err := s.repo.FindCommentByID(ctx, commentID)
+// GetComment возвращает комментарий с оценкой зрителя; заблокированный комментарий видят только автор и модераторы
+func (s *CommentsService) GetComment(ctx context.Context, commentID int, viewer Viewer) (*Comment, error) {
+	comment, err := s.GetCommentByID(ctx, commentID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to find comment: %w", err)
+		return nil, err
 	}
-
-	if comment.DeletedAt != nil {
-		return nil, errors_constant.CommentDeleted
+	if viewer.hides(comment) {
+		return nil, errors_constant.CommentNotFound
 	}
 
-	comment.Like += 1
-	comment.UpdatedAt = time.Now()
+	comments := []Comment{*comment}
+	s.applyViewer(ctx, viewer, comments)
+	return &comments[0], nil
+}
 
-	if err := s.repo.Update(ctx, comment); err != nil {
-		return nil, fmt.Errorf("failed to like comment: %w", err)
+// VoteComment ставит или меняет оценку пользователя (1 — нравится, -1 — не нравится) и возвращает комментарий
+// с обновлёнными счётчиками
+func (s *CommentsService) VoteComment(ctx context.Context, userID, commentID, value int) (*Comment, error) {
+	if value != 1 && value != -1 {
+		return nil, errors_constant.InvalidCommentVote
 	}
+	return s.vote(ctx, userID, commentID, value, false)
+}
 
-	return comment, nil
+// UnvoteComment снимает оценку пользователя
+func (s *CommentsService) UnvoteComment(ctx context.Context, userID, commentID int) (*Comment, error) {
+	return s.vote(ctx, userID, commentID, 0, false)
 }
 
-func (s *CommentsService) BlockComment(ctx context.Context, commentID int, block bool) (*Comment, error) {
-	comment, err := s.repo.FindCommentByID(ctx, commentID)
+// LikeComment ставит «нравится»; повторный вызов ничего не меняет
+func (s *CommentsService) LikeComment(ctx context.Context, userID, commentID int) (*Comment, error) {
+	return s.vote(ctx, userID, commentID, 1, false)
+}
+
+// UnlikeComment снимает «нравится»; дизлайк пользователя при этом не трогается
+func (s *CommentsService) UnlikeComment(ctx context.Context, userID, commentID int) (*Comment, error) {
+	return s.vote(ctx, userID, commentID, 0, true)
+}
+
+// vote выставляет оценку value (0 — снять) и публикует comment.liked / comment.unliked при смене «нравится».
+// onlyLike ограничивает снятие оценки случаем, когда стоит «нравится».
+func (s *CommentsService) vote(ctx context.Context, userID, commentID, value int, onlyLike bool) (*Comment, error) {
+	if _, err := s.GetCommentByID(ctx, commentID); err != nil {
+		return nil, err
+	}
+
+	votes, err := s.repo.VotesByUser(ctx, userID, []int{commentID})
 	if err != nil {
-		return nil, fmt.Errorf("failed to find comment: %w", err)
+		return nil, fmt.Errorf("failed to read comment vote: %w", err)
 	}
+	previous := votes[commentID]
 
-	comment.Blocked = block
-	comment.UpdatedAt = time.Now()
+	if onlyLike && previous != 1 {
+		value = previous
+	}
 
-	if err := s.repo.Update(ctx, comment); err != nil {
-		return nil, fmt.Errorf("failed to update blocked state: %w", err)
+	var changed bool
+	switch {
+	case value == previous:
+	case value == 0:
+		changed, err = s.repo.RemoveLike(ctx, commentID, userID)
+	default:
+		changed, err = s.repo.SetLike(ctx, commentID, userID, value)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to vote comment: %w", err)
 	}
 
+	comment, err := s.GetCommentByID(ctx, commentID)
+	if err != nil {
+		return nil, err
+	}
+	comment.MyVote = value
+
+	if changed && previous != 1 && value == 1 {
+		s.publish("comment.liked", CommentLikedEvent{
+			CommentID: comment.ID, PostID: comment.PostID, AuthorID: comment.UserID, UserID: userID, Likes: comment.Like,
+		})
+	}
+	if changed && previous == 1 && value != 1 {
+		s.publish("comment.unliked", CommentUnlikedEvent{
+			CommentID: comment.ID, PostID: comment.PostID, AuthorID: comment.UserID, UserID: userID, Likes: comment.Like,
+		})
+	}
 	return comment, nil
 }
@@ -0,0 +1,230 @@
+package comments
+
+import (
+	"context"
+	"database/sql"
+	"mpb/configs"
+	"mpb/pkg/errors_constant"
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildTree(t *testing.T) {
+	parent := func(id int) *int { return &id }
+
+	// порядок по path: 1, 1.2, 1.2.4, 1.3, 5; у 7 родитель не попал в выборку
+	comments := []Comment{
+		{ID: 1},
+		{ID: 2, ParentID: parent(1), Depth: 1},
+		{ID: 4, ParentID: parent(2), Depth: 2},
+		{ID: 3, ParentID: parent(1), Depth: 1},
+		{ID: 5},
+		{ID: 7, ParentID: parent(6), Depth: 1},
+	}
+
+	roots := buildTree(comments)
+	require.Len(t, roots, 3)
+	assert.Equal(t, []int{1, 5, 7}, []int{roots[0].ID, roots[1].ID, roots[2].ID})
+
+	require.Len(t, roots[0].Replies, 2)
+	assert.Equal(t, 2, roots[0].Replies[0].ID)
+	assert.Equal(t, 3, roots[0].Replies[1].ID)
+	require.Len(t, roots[0].Replies[0].Replies, 1)
+	assert.Equal(t, 4, roots[0].Replies[0].Replies[0].ID)
+	assert.Empty(t, roots[1].Replies)
+}
+
+func TestClampDepth(t *testing.T) {
+	assert.Equal(t, MaxCommentDepth+1, clampDepth(0))
+	assert.Equal(t, 2, clampDepth(2))
+	assert.Equal(t, MaxCommentDepth+1, clampDepth(100))
+}
+
+type MockCommentsRepository struct {
+	mock.Mock
+}
+
+func (m *MockCommentsRepository) Create(ctx context.Context, c *Comment) error {
+	return m.Called(ctx, c).Error(0)
+}
+
+func (m *MockCommentsRepository) Update(ctx context.Context, c *Comment) error {
+	return m.Called(ctx, c).Error(0)
+}
+
+func (m *MockCommentsRepository) Delete(ctx context.Context, commentID int) error {
+	return m.Called(ctx, commentID).Error(0)
+}
+
+func (m *MockCommentsRepository) ListPage(ctx context.Context, f CommentFilter) ([]Comment, error) {
+	args := m.Called(ctx, f)
+	return args.Get(0).([]Comment), args.Error(1)
+}
+
+func (m *MockCommentsRepository) ListDescendants(ctx context.Context, parents []Comment, maxDepth int, viewer Viewer) ([]Comment, error) {
+	args := m.Called(ctx, parents, maxDepth, viewer)
+	return args.Get(0).([]Comment), args.Error(1)
+}
+
+func (m *MockCommentsRepository) Report(ctx context.Context, commentID int, reporterID *int, reason string) (bool, error) {
+	args := m.Called(ctx, commentID, reporterID, reason)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockCommentsRepository) ModerationQueue(ctx context.Context, cursor *QueueCursor, limit int) ([]QueuedComment, error) {
+	args := m.Called(ctx, cursor, limit)
+	return args.Get(0).([]QueuedComment), args.Error(1)
+}
+
+func (m *MockCommentsRepository) Moderate(ctx context.Context, commentID, moderatorID int, action, reason string) error {
+	args := m.Called(ctx, commentID, moderatorID, action, reason)
+	return args.Error(0)
+}
+
+func (m *MockCommentsRepository) FindCommentByID(ctx context.Context, commentID int) (*Comment, error) {
+	args := m.Called(ctx, commentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Comment), args.Error(1)
+}
+
+func (m *MockCommentsRepository) SetLike(ctx context.Context, commentID, userID, value int) (bool, error) {
+	args := m.Called(ctx, commentID, userID, value)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockCommentsRepository) RemoveLike(ctx context.Context, commentID, userID int) (bool, error) {
+	args := m.Called(ctx, commentID, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockCommentsRepository) VotesByUser(ctx context.Context, userID int, commentIDs []int) (map[int]int, error) {
+	args := m.Called(ctx, userID, commentIDs)
+	return args.Get(0).(map[int]int), args.Error(1)
+}
+
+type MockPublisher struct {
+	mock.Mock
+}
+
+func (m *MockPublisher) Publish(topic string, messages ...*message.Message) error {
+	return m.Called(topic, messages).Error(0)
+}
+
+func (m *MockPublisher) Close() error {
+	return m.Called().Error(0)
+}
+
+func TestLikeComment_PublishesOnlyOnChange(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		previous int
+		publish  bool
+	}{
+		{"new like", 0, true},
+		{"switch from dislike", -1, true},
+		{"already liked", 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(MockCommentsRepository)
+			pub := new(MockPublisher)
+			service := NewCommentsService(repo, pub, watermill.NopLogger{}, configs.CommentsConfig{})
+
+			repo.On("FindCommentByID", ctx, 10).Return(&Comment{ID: 10, PostID: 1, UserID: 2, Like: 3}, nil)
+			repo.On("VotesByUser", ctx, 7, []int{10}).Return(map[int]int{10: tt.previous}, nil)
+			if tt.publish {
+				repo.On("SetLike", ctx, 10, 7, 1).Return(true, nil)
+				pub.On("Publish", "comment.liked", mock.Anything).Return(nil)
+			}
+
+			comment, err := service.LikeComment(ctx, 7, 10)
+			require.NoError(t, err)
+			assert.Equal(t, 1, comment.MyVote)
+			repo.AssertExpectations(t)
+			pub.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUnlikeComment_KeepsDislike(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockCommentsRepository)
+	pub := new(MockPublisher)
+	service := NewCommentsService(repo, pub, watermill.NopLogger{}, configs.CommentsConfig{})
+
+	repo.On("FindCommentByID", ctx, 10).Return(&Comment{ID: 10, Dislike: 1}, nil)
+	repo.On("VotesByUser", ctx, 7, []int{10}).Return(map[int]int{10: -1}, nil)
+
+	comment, err := service.UnlikeComment(ctx, 7, 10)
+	require.NoError(t, err)
+	assert.Equal(t, -1, comment.MyVote)
+	repo.AssertNotCalled(t, "RemoveLike", mock.Anything, mock.Anything, mock.Anything)
+	pub.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+}
+
+func TestViewerHides(t *testing.T) {
+	now := time.Now()
+	blocked := &Comment{UserID: 2, Blocked: true}
+
+	assert.True(t, Viewer{}.hides(blocked))
+	assert.True(t, Viewer{ID: 3}.hides(blocked))
+	assert.False(t, Viewer{ID: 2}.hides(blocked), "author sees own blocked comment")
+	assert.False(t, Viewer{ID: 3, Moderator: true}.hides(blocked))
+	assert.True(t, Viewer{Moderator: true}.hides(&Comment{DeletedAt: &now}))
+	assert.False(t, Viewer{}.hides(&Comment{UserID: 2}))
+}
+
+func TestFlagReason(t *testing.T) {
+	service := NewCommentsService(nil, nil, watermill.NopLogger{}, configs.CommentsConfig{
+		FlagWords:    []string{"casino"},
+		FlagMaxLinks: 2,
+	})
+
+	assert.Empty(t, service.flagReason("just a comment with https://example.com"))
+	assert.Equal(t, `auto: contains "casino"`, service.flagReason("Best CASINO ever"))
+	assert.Equal(t, "auto: 3 links", service.flagReason("http://a.io https://b.io www.c.io"))
+}
+
+func TestCreateComment_AutoFlags(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockCommentsRepository)
+	pub := new(MockPublisher)
+	service := NewCommentsService(repo, pub, watermill.NopLogger{}, configs.CommentsConfig{FlagWords: []string{"casino"}})
+
+	repo.On("Create", ctx, mock.AnythingOfType("*comments.Comment")).Run(func(args mock.Arguments) {
+		args.Get(1).(*Comment).ID = 42
+	}).Return(nil)
+	repo.On("Report", ctx, 42, (*int)(nil), `auto: contains "casino"`).Return(true, nil)
+	pub.On("Publish", "comment.created", mock.Anything).Return(nil)
+
+	_, err := service.CreateComment(ctx, 1, nil, 7, "visit my casino")
+	require.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestModerateComment(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockCommentsRepository)
+	service := NewCommentsService(repo, nil, watermill.NopLogger{}, configs.CommentsConfig{})
+
+	err := service.ModerateComment(ctx, 1, 10, "ban", "spam")
+	assert.ErrorIs(t, err, errors_constant.InvalidModerationAction)
+
+	err = service.ModerateComment(ctx, 1, 10, ModerationBlock, "  ")
+	assert.ErrorIs(t, err, errors_constant.InvalidModerationReason)
+
+	repo.On("Moderate", ctx, 10, 1, ModerationBlock, "spam").Return(sql.ErrNoRows)
+	err = service.ModerateComment(ctx, 1, 10, ModerationBlock, "spam")
+	assert.ErrorIs(t, err, errors_constant.CommentNotFound)
+}
@@ -1,19 +1,20 @@
 package comments_attachments
 
 import (
+	"mpb/pkg/jwtkeys"
 	"mpb/pkg/middleware"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type CommentAttachmentsRoutes struct {
-	router    fiber.Router
-	handler   *CommentAttachmentsHandlers
-	jwtSecret []byte
+	router  fiber.Router
+	handler *CommentAttachmentsHandlers
+	jwtKeys *jwtkeys.KeySet
 }
 
-func NewCommentAttachmentsRoutes(router fiber.Router, handler *CommentAttachmentsHandlers, jwtSecret []byte) *CommentAttachmentsRoutes {
-	return &CommentAttachmentsRoutes{router: router, handler: handler, jwtSecret: jwtSecret}
+func NewCommentAttachmentsRoutes(router fiber.Router, handler *CommentAttachmentsHandlers, jwtKeys *jwtkeys.KeySet) *CommentAttachmentsRoutes {
+	return &CommentAttachmentsRoutes{router: router, handler: handler, jwtKeys: jwtKeys}
 }
 
 func (r *CommentAttachmentsRoutes) Register() {
@@ -21,7 +22,7 @@ func (r *CommentAttachmentsRoutes) Register() {
 
 	group.Get("/:id/attachments", r.handler.GetAttachments)
 
-	authGroup := group.Group("/", middleware.JWTAuth(r.jwtSecret))
+	authGroup := group.Group("/", middleware.JWTAuth(r.jwtKeys))
 	authGroup.Post("/:id/attachments", r.handler.UploadAttachments)
 	authGroup.Delete("/attachments/:id", r.handler.DeleteAttachment)
 }
@@ -0,0 +1,133 @@
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mpb/configs"
+	"mpb/internal/posts"
+	"mpb/internal/users"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+// errMalformedEvent — событие нельзя разобрать; повторная доставка этого не исправит
+var errMalformedEvent = errors.New("malformed feed event")
+
+// fanoutBatch — сколько подписчиков читается из БД и обновляется в Redis за один проход
+const fanoutBatch = 1000
+
+// FanoutConsumer поддерживает ленты подписчиков в актуальном состоянии
+type FanoutConsumer struct {
+	repo         *FeedRepository
+	timeline     *TimelineService
+	logger       watermill.LoggerAdapter
+	maxFollowers int
+}
+
+func NewFanoutConsumer(repo *FeedRepository, timeline *TimelineService, logger watermill.LoggerAdapter, conf configs.FeedConfig) *FanoutConsumer {
+	return &FanoutConsumer{
+		repo:         repo,
+		timeline:     timeline,
+		logger:       logger,
+		maxFollowers: conf.FanoutMaxFollowers,
+	}
+}
+
+func (c *FanoutConsumer) Start(subscriber message.Subscriber) error {
+	handlers := map[string]func(context.Context, []byte) error{
+		"post.created":    c.handleCreated,
+		"post.deleted":    c.handleDeleted,
+		"user.followed":   c.handleFollowChanged,
+		"user.unfollowed": c.handleFollowChanged,
+	}
+
+	for topic, handle := range handlers {
+		messages, err := subscriber.Subscribe(context.Background(), topic)
+		if err != nil {
+			return fmt.Errorf("failed to subscribe to %s: %w", topic, err)
+		}
+		go c.consume(topic, messages, handle)
+	}
+	return nil
+}
+
+func (c *FanoutConsumer) consume(topic string, messages <-chan *message.Message, handle func(context.Context, []byte) error) {
+	for msg := range messages {
+		if err := handle(msg.Context(), msg.Payload); err != nil {
+			if errors.Is(err, errMalformedEvent) {
+				c.logger.Error("dropping malformed feed event", err, watermill.LogFields{"topic": topic, "message_uuid": msg.UUID})
+				msg.Ack()
+				continue
+			}
+			c.logger.Error("failed to process feed event", err, watermill.LogFields{"topic": topic})
+			msg.Nack()
+			continue
+		}
+		msg.Ack()
+	}
+}
+
+// handleCreated раскладывает пост по лентам подписчиков; посты авторов с большим числом подписчиков
+// не раскладываются и подмешиваются в ленту при чтении
+func (c *FanoutConsumer) handleCreated(ctx context.Context, payload []byte) error {
+	var event posts.PostCreatedEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return fmt.Errorf("%w: post.created: %v", errMalformedEvent, err)
+	}
+
+	followers, err := c.repo.CountFollowers(ctx, event.UserID)
+	if err != nil {
+		return err
+	}
+	if followers > c.maxFollowers {
+		return nil
+	}
+
+	entry := Entry{PostID: event.ID, PublishedAt: event.PublishedAt}
+	return c.eachFollowersBatch(ctx, event.UserID, func(ids []int) error {
+		return c.timeline.Push(ctx, ids, entry)
+	})
+}
+
+func (c *FanoutConsumer) handleDeleted(ctx context.Context, payload []byte) error {
+	var event posts.PostDeletedEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return fmt.Errorf("%w: post.deleted: %v", errMalformedEvent, err)
+	}
+
+	return c.eachFollowersBatch(ctx, event.UserID, func(ids []int) error {
+		return c.timeline.Remove(ctx, ids, event.ID)
+	})
+}
+
+// handleFollowChanged сбрасывает ленту подписчика — она соберётся заново с учётом новых подписок
+func (c *FanoutConsumer) handleFollowChanged(ctx context.Context, payload []byte) error {
+	var event users.UserUnfollowedEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return fmt.Errorf("%w: follow event: %v", errMalformedEvent, err)
+	}
+	return c.timeline.Drop(ctx, event.FollowerID)
+}
+
+func (c *FanoutConsumer) eachFollowersBatch(ctx context.Context, authorID int, fn func([]int) error) error {
+	afterID := 0
+	for {
+		ids, err := c.repo.FollowersBatch(ctx, authorID, afterID, fanoutBatch)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		if err := fn(ids); err != nil {
+			return err
+		}
+		if len(ids) < fanoutBatch {
+			return nil
+		}
+		afterID = ids[len(ids)-1]
+	}
+}
@@ -0,0 +1,67 @@
+package feed
+
+import (
+	"mpb/internal/feed/dto"
+	"mpb/internal/posts"
+	postsdto "mpb/internal/posts/dto"
+	"mpb/pkg/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type FeedHandlers struct {
+	service *FeedService
+}
+
+func NewFeedHandlers(service *FeedService) *FeedHandlers {
+	return &FeedHandlers{service: service}
+}
+
+// GetFeed godoc
+// @Summary Home feed of the current user
+// @Description Posts of followed authors, newest first.
+// @Tags Feed
+// @Produce json
+// @Param limit query int false "Page size (1-100, default 20)"
+// @Param cursor query string false "Cursor from next_cursor"
+// @Success 200 {object} postsdto.PostListResponse
+// @Failure 400 {object} map[string]string
+// @Router /api/feed [get]
+func (h *FeedHandlers) GetFeed(c *fiber.Ctx) error {
+	query := middleware.Query[dto.FeedQuery](c)
+	if query == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid query parameters"})
+	}
+
+	userID, ok := c.Locals("user_id").(int)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "user not authenticated"})
+	}
+
+	var cursor *Cursor
+	if query.Cursor != "" {
+		decoded, err := DecodeCursor(query.Cursor)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		cursor = decoded
+	}
+
+	page, err := h.service.GetFeed(c.Context(), userID, query.Limit, cursor)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	response := postsdto.PostListResponse{
+		Data:       make([]postsdto.PostResponse, len(page.Posts)),
+		NextCursor: page.NextCursor,
+	}
+	for i := range page.Posts {
+		response.Data[i] = posts.PostToResponse(&page.Posts[i])
+	}
+
+	if page.NextCursor != "" {
+		c.Links(posts.NextPageURL(c, page.NextCursor), "next")
+	}
+	return c.JSON(response)
+}
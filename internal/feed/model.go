@@ -0,0 +1,52 @@
+package feed
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"mpb/internal/posts"
+	"mpb/pkg/errors_constant"
+	"time"
+)
+
+// Entry — запись ленты: пост и время его публикации (с точностью до миллисекунд, как в Redis)
+type Entry struct {
+	PostID      int       `db:"id"`
+	PublishedAt time.Time `db:"published_at"`
+}
+
+// before сообщает, что запись идёт в ленте после курсора (лента упорядочена по (время, id) по убыванию)
+func (e Entry) before(c *Cursor) bool {
+	if c == nil {
+		return true
+	}
+	return e.PublishedAt.Before(c.Time) || (e.PublishedAt.Equal(c.Time) && e.PostID < c.ID)
+}
+
+// Cursor указывает на последнюю запись страницы ленты
+type Cursor struct {
+	Time time.Time `json:"t"`
+	ID   int       `json:"id"`
+}
+
+func (c Cursor) Encode() string {
+	payload, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(payload)
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	payload, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors_constant.InvalidCursor
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(payload, &cursor); err != nil || cursor.ID <= 0 || cursor.Time.IsZero() {
+		return nil, errors_constant.InvalidCursor
+	}
+	return &cursor, nil
+}
+
+type Page struct {
+	Posts      []posts.Post
+	NextCursor string
+}
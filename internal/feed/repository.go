@@ -0,0 +1,96 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"mpb/pkg/db"
+
+	"github.com/lib/pq"
+)
+
+type FeedRepository struct {
+	db *db.Db
+}
+
+func NewFeedRepository(db *db.Db) *FeedRepository {
+	return &FeedRepository{db: db}
+}
+
+// CountFollowers возвращает число подписчиков автора
+func (r *FeedRepository) CountFollowers(ctx context.Context, authorID int) (int, error) {
+	var count int
+	const query = `SELECT COUNT(*) FROM follows WHERE followee_id = $1`
+	if err := r.db.Conn.GetContext(ctx, &count, query, authorID); err != nil {
+		return 0, fmt.Errorf("failed to count followers: %w", err)
+	}
+	return count, nil
+}
+
+// FollowersBatch читает id подписчиков автора по возрастанию после afterID
+func (r *FeedRepository) FollowersBatch(ctx context.Context, authorID, afterID, limit int) ([]int, error) {
+	const query = `
+		SELECT follower_id FROM follows
+		WHERE followee_id = $1 AND follower_id > $2
+		ORDER BY follower_id
+		LIMIT $3
+	`
+
+	var ids []int
+	if err := r.db.Conn.SelectContext(ctx, &ids, query, authorID, afterID, limit); err != nil {
+		return nil, fmt.Errorf("failed to read followers: %w", err)
+	}
+	return ids, nil
+}
+
+// Followees делит подписки пользователя на обычных авторов и авторов, у которых больше maxFollowers подписчиков
+func (r *FeedRepository) Followees(ctx context.Context, userID, maxFollowers int) (regular, heavy []int, err error) {
+	const query = `
+		SELECT f.followee_id,
+			(SELECT COUNT(*) FROM follows x WHERE x.followee_id = f.followee_id) > $2 AS heavy
+		FROM follows f
+		WHERE f.follower_id = $1
+	`
+
+	rows, err := r.db.Conn.QueryContext(ctx, query, userID, maxFollowers)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read followees: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var isHeavy bool
+		if err := rows.Scan(&id, &isHeavy); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan followee: %w", err)
+		}
+		if isHeavy {
+			heavy = append(heavy, id)
+		} else {
+			regular = append(regular, id)
+		}
+	}
+	return regular, heavy, rows.Err()
+}
+
+// RecentEntries возвращает последние опубликованные посты авторов, идущие в ленте после курсора
+func (r *FeedRepository) RecentEntries(ctx context.Context, authorIDs []int, cursor *Cursor, limit int) ([]Entry, error) {
+	query := `
+		SELECT id, date_trunc('milliseconds', COALESCE(publish_at, created_at)) AS published_at
+		FROM posts
+		WHERE user_id = ANY($1) AND status = 'published' AND deleted_at IS NULL`
+	args := []interface{}{pq.Array(authorIDs)}
+
+	if cursor != nil {
+		args = append(args, cursor.Time, cursor.ID)
+		query += ` AND (date_trunc('milliseconds', COALESCE(publish_at, created_at)), id) < ($2, $3)`
+	}
+
+	args = append(args, limit)
+	query += fmt.Sprintf(" ORDER BY published_at DESC, id DESC LIMIT $%d", len(args))
+
+	var entries []Entry
+	if err := r.db.Conn.SelectContext(ctx, &entries, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to read feed entries: %w", err)
+	}
+	return entries, nil
+}
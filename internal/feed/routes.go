@@ -0,0 +1,25 @@
+package feed
+
+import (
+	"mpb/internal/feed/dto"
+	"mpb/pkg/jwtkeys"
+	"mpb/pkg/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type FeedRoutes struct {
+	router  fiber.Router
+	handler *FeedHandlers
+	jwtKeys *jwtkeys.KeySet
+}
+
+func NewFeedRoutes(router fiber.Router, handler *FeedHandlers, jwtKeys *jwtkeys.KeySet) *FeedRoutes {
+	return &FeedRoutes{router: router, handler: handler, jwtKeys: jwtKeys}
+}
+
+func (r *FeedRoutes) Register() {
+	feed := r.router.Group("/feed", middleware.JWTAuth(r.jwtKeys))
+
+	feed.Get("/", middleware.ValidateQuery[dto.FeedQuery](), r.handler.GetFeed)
+}
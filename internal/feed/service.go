@@ -0,0 +1,160 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"mpb/configs"
+	"mpb/internal/posts"
+	"sort"
+
+	"github.com/ThreeDotsLabs/watermill"
+)
+
+const (
+	defaultFeedLimit = 20
+	maxFeedLimit     = 100
+)
+
+// FeedService собирает домашнюю ленту: посты обычных авторов разносятся по лентам подписчиков при публикации,
+// посты авторов с большим числом подписчиков подмешиваются при чтении
+type FeedService struct {
+	repo         *FeedRepository
+	timeline     *TimelineService
+	postsService *posts.PostsService
+	conf         configs.FeedConfig
+	logger       watermill.LoggerAdapter
+}
+
+func NewFeedService(repo *FeedRepository, timeline *TimelineService, postsService *posts.PostsService, conf configs.FeedConfig, logger watermill.LoggerAdapter) *FeedService {
+	return &FeedService{
+		repo:         repo,
+		timeline:     timeline,
+		postsService: postsService,
+		conf:         conf,
+		logger:       logger,
+	}
+}
+
+func (s *FeedService) GetFeed(ctx context.Context, userID, limit int, cursor *Cursor) (*Page, error) {
+	if limit <= 0 {
+		limit = defaultFeedLimit
+	}
+	if limit > maxFeedLimit {
+		limit = maxFeedLimit
+	}
+
+	regular, heavy, err := s.repo.Followees(ctx, userID, s.conf.FanoutMaxFollowers)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	if len(regular) > 0 {
+		if entries, err = s.timelineEntries(ctx, userID, regular, cursor, limit+1); err != nil {
+			return nil, err
+		}
+	}
+	if len(heavy) > 0 {
+		pulled, err := s.repo.RecentEntries(ctx, heavy, cursor, limit+1)
+		if err != nil {
+			return nil, err
+		}
+		entries = mergeEntries(cursor, limit+1, append(entries, pulled...))
+	}
+
+	page := &Page{Posts: []posts.Post{}}
+	if len(entries) > limit {
+		entries = entries[:limit]
+		last := entries[limit-1]
+		page.NextCursor = Cursor{Time: last.PublishedAt, ID: last.PostID}.Encode()
+	}
+	if len(entries) == 0 {
+		return page, nil
+	}
+
+	page.Posts, err = s.loadPosts(ctx, userID, entries)
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// timelineEntries читает ленту из Redis, при необходимости собирая её заново из PostgreSQL
+func (s *FeedService) timelineEntries(ctx context.Context, userID int, authorIDs []int, cursor *Cursor, limit int) ([]Entry, error) {
+	exists, err := s.timeline.Exists(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		entries, err := s.repo.RecentEntries(ctx, authorIDs, nil, s.conf.Size)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.timeline.Rebuild(ctx, userID, entries); err != nil {
+			s.logger.Error("failed to rebuild timeline", err, watermill.LogFields{"user_id": userID})
+		}
+		return mergeEntries(cursor, limit, entries), nil
+	}
+
+	return s.timeline.Page(ctx, userID, cursor, limit)
+}
+
+// loadPosts загружает посты в порядке записей ленты; удалённые и снятые с публикации посты убираются из ленты
+func (s *FeedService) loadPosts(ctx context.Context, userID int, entries []Entry) ([]posts.Post, error) {
+	ids := make([]int, len(entries))
+	for i, e := range entries {
+		ids[i] = e.PostID
+	}
+
+	found, err := s.postsService.ListPosts(ctx, posts.PostFilter{IDs: ids, OnlyActive: true, ViewerID: userID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load feed posts: %w", err)
+	}
+
+	byID := make(map[int]posts.Post, len(found))
+	for _, p := range found {
+		byID[p.ID] = p
+	}
+
+	result := make([]posts.Post, 0, len(entries))
+	var stale []int
+	for _, id := range ids {
+		if p, ok := byID[id]; ok {
+			result = append(result, p)
+		} else {
+			stale = append(stale, id)
+		}
+	}
+
+	if len(stale) > 0 {
+		if err := s.timeline.Remove(ctx, []int{userID}, stale...); err != nil {
+			s.logger.Error("failed to remove stale feed entries", err, watermill.LogFields{"user_id": userID})
+		}
+	}
+	return result, nil
+}
+
+// mergeEntries упорядочивает записи по (время, id) по убыванию, убирает дубли и записи до курсора
+func mergeEntries(cursor *Cursor, limit int, entries []Entry) []Entry {
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].PublishedAt.Equal(entries[j].PublishedAt) {
+			return entries[i].PublishedAt.After(entries[j].PublishedAt)
+		}
+		return entries[i].PostID > entries[j].PostID
+	})
+
+	result := make([]Entry, 0, min(len(entries), limit))
+	seen := make(map[int]struct{}, len(entries))
+	for _, e := range entries {
+		if len(result) == limit {
+			break
+		}
+		if _, ok := seen[e.PostID]; ok || !e.before(cursor) {
+			continue
+		}
+		seen[e.PostID] = struct{}{}
+		result = append(result, e)
+	}
+	return result
+}
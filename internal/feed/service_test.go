@@ -0,0 +1,49 @@
+package feed
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeEntries(t *testing.T) {
+	base := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{PostID: 1, PublishedAt: base},
+		{PostID: 5, PublishedAt: base.Add(time.Minute)},
+		{PostID: 3, PublishedAt: base},
+		{PostID: 5, PublishedAt: base.Add(time.Minute)},
+		{PostID: 2, PublishedAt: base.Add(-time.Minute)},
+	}
+
+	got := mergeEntries(nil, 10, entries)
+	assert.Equal(t, []int{5, 3, 1, 2}, entryIDs(got))
+
+	got = mergeEntries(&Cursor{Time: base, ID: 3}, 10, entries)
+	assert.Equal(t, []int{1, 2}, entryIDs(got))
+
+	got = mergeEntries(nil, 2, entries)
+	assert.Equal(t, []int{5, 3}, entryIDs(got))
+}
+
+func TestCursor_RoundTrip(t *testing.T) {
+	cursor := Cursor{Time: time.Date(2025, 3, 1, 12, 0, 0, 123000000, time.UTC), ID: 42}
+
+	decoded, err := DecodeCursor(cursor.Encode())
+	require.NoError(t, err)
+	assert.True(t, cursor.Time.Equal(decoded.Time))
+	assert.Equal(t, cursor.ID, decoded.ID)
+
+	_, err = DecodeCursor("not-a-cursor")
+	assert.Error(t, err)
+}
+
+func entryIDs(entries []Entry) []int {
+	ids := make([]int, len(entries))
+	for i, e := range entries {
+		ids[i] = e.PostID
+	}
+	return ids
+}
@@ -0,0 +1,149 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// keyTimeline — sorted set ленты пользователя: post_id с очками = время публикации в миллисекундах
+const keyTimeline = "feed:%d"
+
+// pushScript добавляет пост в существующую ленту и обрезает её до ARGV[3] записей.
+// Ленты неактивных пользователей (ключ истёк) не создаются — они соберутся заново при чтении.
+var pushScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+redis.call('ZADD', KEYS[1], ARGV[1], ARGV[2])
+redis.call('ZREMRANGEBYRANK', KEYS[1], 0, -tonumber(ARGV[3]) - 1)
+return 1
+`)
+
+// TimelineService хранит ленты пользователей в Redis
+type TimelineService struct {
+	redis *redis.Client
+	size  int
+	ttl   time.Duration
+}
+
+func NewTimelineService(redisClient *redis.Client, size int, ttl time.Duration) *TimelineService {
+	return &TimelineService{redis: redisClient, size: size, ttl: ttl}
+}
+
+func timelineKey(userID int) string {
+	return fmt.Sprintf(keyTimeline, userID)
+}
+
+func (t *TimelineService) Exists(ctx context.Context, userID int) (bool, error) {
+	n, err := t.redis.Exists(ctx, timelineKey(userID)).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check timeline: %w", err)
+	}
+	return n > 0, nil
+}
+
+// Rebuild заменяет ленту пользователя записями из PostgreSQL
+func (t *TimelineService) Rebuild(ctx context.Context, userID int, entries []Entry) error {
+	key := timelineKey(userID)
+
+	members := make([]redis.Z, len(entries))
+	for i, e := range entries {
+		members[i] = redis.Z{Score: float64(e.PublishedAt.UnixMilli()), Member: e.PostID}
+	}
+
+	pipe := t.redis.TxPipeline()
+	pipe.Del(ctx, key)
+	if len(members) > 0 {
+		pipe.ZAdd(ctx, key, members...)
+		pipe.ZRemRangeByRank(ctx, key, 0, int64(-t.size-1))
+		pipe.Expire(ctx, key, t.ttl)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to rebuild timeline: %w", err)
+	}
+	return nil
+}
+
+// Push добавляет пост в существующие ленты подписчиков
+func (t *TimelineService) Push(ctx context.Context, userIDs []int, entry Entry) error {
+	pipe := t.redis.Pipeline()
+	for _, userID := range userIDs {
+		pushScript.Eval(ctx, pipe, []string{timelineKey(userID)}, entry.PublishedAt.UnixMilli(), entry.PostID, t.size)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to push to timelines: %w", err)
+	}
+	return nil
+}
+
+// Remove убирает посты из лент пользователей
+func (t *TimelineService) Remove(ctx context.Context, userIDs []int, postIDs ...int) error {
+	if len(postIDs) == 0 {
+		return nil
+	}
+
+	members := make([]interface{}, len(postIDs))
+	for i, id := range postIDs {
+		members[i] = id
+	}
+
+	pipe := t.redis.Pipeline()
+	for _, userID := range userIDs {
+		pipe.ZRem(ctx, timelineKey(userID), members...)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to remove from timelines: %w", err)
+	}
+	return nil
+}
+
+// Drop удаляет ленту, чтобы она собралась заново при следующем чтении
+func (t *TimelineService) Drop(ctx context.Context, userID int) error {
+	if err := t.redis.Del(ctx, timelineKey(userID)).Err(); err != nil {
+		return fmt.Errorf("failed to drop timeline: %w", err)
+	}
+	return nil
+}
+
+// Page возвращает до limit записей после курсора и продлевает жизнь ленты
+func (t *TimelineService) Page(ctx context.Context, userID int, cursor *Cursor, limit int) ([]Entry, error) {
+	key := timelineKey(userID)
+	pipe := t.redis.Pipeline()
+
+	var tiesCmd *redis.ZSliceCmd
+	max := "+inf"
+	if cursor != nil {
+		score := strconv.FormatInt(cursor.Time.UnixMilli(), 10)
+		tiesCmd = pipe.ZRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{Min: score, Max: score})
+		max = "(" + score
+	}
+	olderCmd := pipe.ZRevRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{Min: "-inf", Max: max, Count: int64(limit)})
+	pipe.Expire(ctx, key, t.ttl)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to read timeline: %w", err)
+	}
+
+	var entries []Entry
+	if tiesCmd != nil {
+		entries = append(entries, toEntries(tiesCmd.Val())...)
+	}
+	entries = append(entries, toEntries(olderCmd.Val())...)
+	return mergeEntries(cursor, limit, entries), nil
+}
+
+func toEntries(members []redis.Z) []Entry {
+	entries := make([]Entry, 0, len(members))
+	for _, m := range members {
+		id, err := strconv.Atoi(fmt.Sprint(m.Member))
+		if err != nil {
+			continue
+		}
+		entries = append(entries, Entry{PostID: id, PublishedAt: time.UnixMilli(int64(m.Score)).UTC()})
+	}
+	return entries
+}
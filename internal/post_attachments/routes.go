@@ -1,19 +1,20 @@
 package post_attachments
 
 import (
+	"mpb/pkg/jwtkeys"
 	"mpb/pkg/middleware"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type PostAttachmentsRoutes struct {
-	router    fiber.Router
-	handler   *PostAttachmentsHandlers
-	jwtSecret []byte
+	router  fiber.Router
+	handler *PostAttachmentsHandlers
+	jwtKeys *jwtkeys.KeySet
 }
 
-func NewPostAttachmentsRoutes(router fiber.Router, handler *PostAttachmentsHandlers, jwtSecret []byte) *PostAttachmentsRoutes {
-	return &PostAttachmentsRoutes{router: router, handler: handler, jwtSecret: jwtSecret}
+func NewPostAttachmentsRoutes(router fiber.Router, handler *PostAttachmentsHandlers, jwtKeys *jwtkeys.KeySet) *PostAttachmentsRoutes {
+	return &PostAttachmentsRoutes{router: router, handler: handler, jwtKeys: jwtKeys}
 }
 
 func (r *PostAttachmentsRoutes) Register() {
@@ -21,7 +22,7 @@ func (r *PostAttachmentsRoutes) Register() {
 
 	group.Get("/:id/attachments", r.handler.GetAttachments)
 
-	authGroup := group.Group("/", middleware.JWTAuth(r.jwtSecret))
+	authGroup := group.Group("/", middleware.JWTAuth(r.jwtKeys))
 	authGroup.Post("/:id/attachments", r.handler.UploadAttachments)
 	authGroup.Delete("/attachments/:id", r.handler.DeleteAttachment)
 }
@@ -0,0 +1,99 @@
+package posts
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"mpb/pkg/errors_constant"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// postOrderColumns сопоставляет допустимые ключи сортировки с SQL-выражениями
+var postOrderColumns = map[string]string{
+	"created_at":    "created_at",
+	"updated_at":    "updated_at",
+	"like":          `"like"`,
+	"count_viewers": "count_viewers",
+}
+
+// PostCursor указывает на последнюю строку страницы для keyset-пагинации по паре (колонка сортировки, id)
+type PostCursor struct {
+	OrderBy string    `json:"o"`
+	Desc    bool      `json:"d"`
+	Time    time.Time `json:"t,omitempty"`
+	Count   int       `json:"c,omitempty"`
+	ID      int       `json:"id"`
+}
+
+func NewPostCursor(post *Post, orderBy string, desc bool) PostCursor {
+	cursor := PostCursor{OrderBy: orderBy, Desc: desc, ID: post.ID}
+	switch orderBy {
+	case "updated_at":
+		cursor.Time = post.UpdatedAt
+	case "like":
+		cursor.Count = post.Like
+	case "count_viewers":
+		cursor.Count = post.CountViewers
+	default:
+		cursor.Time = post.CreatedAt
+	}
+	return cursor
+}
+
+// Encode возвращает непрозрачное представление курсора для передачи клиенту
+func (c PostCursor) Encode() string {
+	payload, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(payload)
+}
+
+func DecodePostCursor(s string) (*PostCursor, error) {
+	payload, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors_constant.InvalidCursor
+	}
+
+	var cursor PostCursor
+	if err := json.Unmarshal(payload, &cursor); err != nil {
+		return nil, errors_constant.InvalidCursor
+	}
+	if _, ok := postOrderColumns[cursor.OrderBy]; !ok || cursor.ID <= 0 {
+		return nil, errors_constant.InvalidCursor
+	}
+
+	return &cursor, nil
+}
+
+func (c *PostCursor) value() interface{} {
+	switch c.OrderBy {
+	case "like", "count_viewers":
+		return c.Count
+	default:
+		return c.Time
+	}
+}
+
+// parsePostOrder разбирает строку вида "like DESC" в ключ сортировки и направление.
+// Неизвестные ключи заменяются на created_at, направление по умолчанию — DESC.
+func parsePostOrder(orderBy string) (string, bool) {
+	parts := strings.Fields(orderBy)
+	if len(parts) == 0 {
+		return "created_at", true
+	}
+
+	column := parts[0]
+	if _, ok := postOrderColumns[column]; !ok {
+		column = "created_at"
+	}
+
+	desc := true
+	if len(parts) > 1 && strings.EqualFold(parts[1], "ASC") {
+		desc = false
+	}
+
+	return column, desc
+}
@@ -0,0 +1,71 @@
+package posts
+
+import (
+	"mpb/pkg/errors_constant"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostCursor_EncodeDecode(t *testing.T) {
+	createdAt := time.Date(2025, 3, 14, 15, 9, 26, 535000000, time.UTC)
+	post := &Post{ID: 42, CreatedAt: createdAt, Like: 7, CountViewers: 100}
+
+	tests := []struct {
+		name    string
+		orderBy string
+		desc    bool
+		value   interface{}
+	}{
+		{name: "created_at", orderBy: "created_at", desc: true, value: createdAt},
+		{name: "like", orderBy: "like", desc: true, value: 7},
+		{name: "count_viewers asc", orderBy: "count_viewers", desc: false, value: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded := NewPostCursor(post, tt.orderBy, tt.desc).Encode()
+
+			cursor, err := DecodePostCursor(encoded)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.orderBy, cursor.OrderBy)
+			assert.Equal(t, tt.desc, cursor.Desc)
+			assert.Equal(t, 42, cursor.ID)
+			if want, ok := tt.value.(time.Time); ok {
+				assert.True(t, want.Equal(cursor.value().(time.Time)))
+			} else {
+				assert.Equal(t, tt.value, cursor.value())
+			}
+		})
+	}
+}
+
+func TestDecodePostCursor_Invalid(t *testing.T) {
+	for _, raw := range []string{"not base64!", "bm90IGpzb24", "eyJvIjoidGl0bGUiLCJpZCI6MX0"} {
+		_, err := DecodePostCursor(raw)
+		assert.ErrorIs(t, err, errors_constant.InvalidCursor, raw)
+	}
+}
+
+func TestParsePostOrder(t *testing.T) {
+	column, desc := parsePostOrder("like ASC")
+	assert.Equal(t, "like", column)
+	assert.False(t, desc)
+
+	column, desc = parsePostOrder("title; DROP TABLE posts")
+	assert.Equal(t, "created_at", column)
+	assert.True(t, desc)
+}
+
+func TestLikeCursor_RoundTrip(t *testing.T) {
+	likedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	cursor, err := DecodeLikeCursor(LikeCursor{Time: likedAt, ID: 7}.Encode())
+	assert.NoError(t, err)
+	assert.True(t, likedAt.Equal(cursor.Time))
+	assert.Equal(t, 7, cursor.ID)
+
+	_, err = DecodeLikeCursor(LikeCursor{ID: 7}.Encode())
+	assert.ErrorIs(t, err, errors_constant.InvalidCursor)
+}
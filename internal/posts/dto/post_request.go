@@ -1,13 +1,64 @@
 package dto
 
+import "time"
+
 type CreatePostRequest struct {
-	Title       string `json:"title" validate:"required,min=3,max=200"`
-	Description string `json:"description" validate:"required,min=10"`
-	Tag         string `json:"tag" validate:"omitempty,max=50"`
+	Title       string     `json:"title" validate:"required,min=3,max=200"`
+	Description string     `json:"description" validate:"required,min=10,max=50000"`
+	Tag         string     `json:"tag" validate:"omitempty,max=50"`
+	Tags        []string   `json:"tags" validate:"omitempty,max=10,dive,min=1,max=50"`
+	Status      string     `json:"status" validate:"omitempty,oneof=draft scheduled published"`
+	PublishAt   *time.Time `json:"publish_at" validate:"required_if=Status scheduled"`
 }
 
 type UpdatePostRequest struct {
-	Title       *string `json:"title" validate:"omitempty,min=3,max=200"`
-	Description *string `json:"description" validate:"omitempty,min=10"`
-	Tag         *string `json:"tag" validate:"omitempty,max=50"`
+	Title       *string    `json:"title" validate:"omitempty,min=3,max=200"`
+	Description *string    `json:"description" validate:"omitempty,min=10,max=50000"`
+	Tag         *string    `json:"tag" validate:"omitempty,max=50"`
+	Tags        []string   `json:"tags" validate:"omitempty,max=10,dive,min=1,max=50"`
+	Status      *string    `json:"status" validate:"omitempty,oneof=draft scheduled published archived"`
+	PublishAt   *time.Time `json:"publish_at"`
+}
+
+type ReactionRequest struct {
+	Reaction string `json:"reaction" validate:"required,max=32"`
+}
+
+type ListPostsQuery struct {
+	UserID  *int    `query:"user_id" validate:"omitempty,gt=0"`
+	Tag     *string `query:"tag" validate:"omitempty,max=50"`
+	Title   *string `query:"title" validate:"omitempty,min=1,max=200"`
+	From    *string `query:"from" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	To      *string `query:"to" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	Limit   int     `query:"limit" validate:"omitempty,min=1,max=100"`
+	Offset  int     `query:"offset" validate:"omitempty,min=0"`
+	OrderBy string  `query:"order_by" validate:"omitempty,oneof=created_at updated_at like count_viewers"`
+	Order   string  `query:"order" validate:"omitempty,oneof=asc desc"`
+	Cursor  string  `query:"cursor" validate:"omitempty,max=512"`
+}
+
+type DraftsQuery struct {
+	Limit  int    `query:"limit" validate:"omitempty,min=1,max=100"`
+	Cursor string `query:"cursor" validate:"omitempty,max=512"`
+}
+
+type LikesQuery struct {
+	Limit  int    `query:"limit" validate:"omitempty,min=1,max=100"`
+	Cursor string `query:"cursor" validate:"omitempty,max=512"`
+}
+
+type SearchPostsQuery struct {
+	Q      string `query:"q" validate:"required,min=1,max=200"`
+	Limit  int    `query:"limit" validate:"omitempty,min=1,max=100"`
+	Offset int    `query:"offset" validate:"omitempty,min=0"`
+}
+
+type TrendingQuery struct {
+	Window string `query:"window" validate:"omitempty,oneof=24h 7d"`
+	Limit  int    `query:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+type RevisionDiffQuery struct {
+	From int `query:"from" validate:"required,min=1"`
+	To   int `query:"to" validate:"required,min=1"`
 }
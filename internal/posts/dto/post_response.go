@@ -3,13 +3,110 @@ package dto
 import "time"
 
 type PostResponse struct {
-	ID           int       `json:"id"`
-	UserID       int       `json:"user_id"`
-	Title        string    `json:"title"`
-	Description  string    `json:"description"`
-	Tag          string    `json:"tag"`
-	Like         int       `json:"like"`
-	CountViewers int       `json:"count_viewers"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID                 int            `json:"id"`
+	UserID             int            `json:"user_id"`
+	Title              string         `json:"title"`
+	Description        string         `json:"description"`
+	DescriptionHTML    string         `json:"description_html"`
+	Excerpt            string         `json:"excerpt"`
+	WordCount          int            `json:"word_count"`
+	ReadingTimeMinutes int            `json:"reading_time_minutes"`
+	Tag                string         `json:"tag"`
+	Tags               []string       `json:"tags"`
+	Like               int            `json:"like"`
+	CountViewers       int            `json:"count_viewers"`
+	UniqueViewers      int            `json:"unique_viewers"`
+	CommentsCount      int            `json:"comments_count"`
+	LikedByMe          bool           `json:"liked_by_me"`
+	Reactions          map[string]int `json:"reactions"`
+	MyReaction         string         `json:"my_reaction,omitempty"`
+	Status             string         `json:"status"`
+	PublishAt          *time.Time     `json:"publish_at,omitempty"`
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+}
+
+type PostMetricsResponse struct {
+	PostID        int            `json:"post_id"`
+	Likes         int            `json:"likes"`
+	Views         int            `json:"views"`
+	UniqueViewers int            `json:"unique_viewers"`
+	LikedByMe     bool           `json:"liked_by_me"`
+	Reactions     map[string]int `json:"reactions"`
+	MyReaction    string         `json:"my_reaction,omitempty"`
+}
+
+type PostReactionsResponse struct {
+	PostID     int            `json:"post_id"`
+	Reactions  map[string]int `json:"reactions"`
+	MyReaction string         `json:"my_reaction,omitempty"`
+}
+
+type PostListResponse struct {
+	Data       []PostResponse `json:"data"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+type PostLikerResponse struct {
+	UserID   int       `json:"user_id"`
+	Username string    `json:"username"`
+	LikedAt  time.Time `json:"liked_at"`
+}
+
+type PostLikersResponse struct {
+	Data       []PostLikerResponse `json:"data"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+}
+
+type LikedPostResponse struct {
+	PostResponse
+	LikedAt time.Time `json:"liked_at"`
+}
+
+type LikedPostsResponse struct {
+	Data       []LikedPostResponse `json:"data"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+}
+
+type TrendingPostsResponse struct {
+	Window string         `json:"window"`
+	Data   []PostResponse `json:"data"`
+}
+
+type PostSearchItem struct {
+	PostResponse
+	Rank           float64 `json:"rank"`
+	TitleHighlight string  `json:"title_highlight"`
+	Snippet        string  `json:"snippet"`
+}
+
+type PostSearchResponse struct {
+	Data []PostSearchItem `json:"data"`
+}
+
+type PostRevisionResponse struct {
+	ID          int       `json:"id"`
+	PostID      int       `json:"post_id"`
+	Revision    int       `json:"revision"`
+	EditorID    *int      `json:"editor_id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	Tag         string    `json:"tag"`
+	Tags        []string  `json:"tags"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+type PostRevisionDiffResponse struct {
+	PostID      int        `json:"post_id"`
+	From        int        `json:"from"`
+	To          int        `json:"to"`
+	Title       []DiffLine `json:"title"`
+	Description []DiffLine `json:"description"`
+	TagsAdded   []string   `json:"tags_added"`
+	TagsRemoved []string   `json:"tags_removed"`
 }
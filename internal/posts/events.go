@@ -2,11 +2,18 @@ package posts
 
 import "time"
 
+// PostCreatedEvent — пост опубликован (сразу или по расписанию); PublishedAt задаёт его место в лентах
 type PostCreatedEvent struct {
-	ID        int       `json:"id"`
-	UserID    int       `json:"user_id"`
-	Title     string    `json:"title"`
-	CreatedAt time.Time `json:"created_at"`
+	ID          int       `json:"id"`
+	UserID      int       `json:"user_id"`
+	Title       string    `json:"title"`
+	CreatedAt   time.Time `json:"created_at"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+type PostDeletedEvent struct {
+	ID     int `json:"id"`
+	UserID int `json:"user_id"`
 }
 
 type PostViewedEvent struct {
@@ -25,3 +32,12 @@ type PostUnlikedEvent struct {
 	UserID int `json:"user_id"`
 	Likes  int `json:"likes"`
 }
+
+// PostReactedEvent — реакция пользователя поставлена, изменена или снята (Reaction пустая)
+type PostReactedEvent struct {
+	PostID    int            `json:"post_id"`
+	UserID    int            `json:"user_id"`
+	Reaction  string         `json:"reaction"`
+	Previous  string         `json:"previous"`
+	Reactions map[string]int `json:"reactions"`
+}
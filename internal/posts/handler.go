@@ -2,10 +2,14 @@ package posts
 
 import (
 	"errors"
+	"fmt"
 	"mpb/internal/posts/dto"
 	"mpb/pkg/errors_constant"
 	"mpb/pkg/middleware"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -42,20 +46,27 @@ func (h *PostsHandlers) CreatePost(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "user not authenticated"})
 	}
 
-	post, err := h.service.CreatePost(c.Context(), userID, req.Title, req.Description, req.Tag)
+	tags := req.Tags
+	if req.Tag != "" {
+		tags = append([]string{req.Tag}, tags...)
+	}
+
+	post, err := h.service.CreatePost(c.Context(), userID, req.Title, req.Description, tags, req.Status, req.PublishAt)
 	if err != nil {
-		if errors.Is(err, errors_constant.InvalidTitle) {
+		if errors.Is(err, errors_constant.InvalidTitle) || errors.Is(err, errors_constant.InvalidTag) || errors.Is(err, errors_constant.TooManyTags) ||
+			errors.Is(err, errors_constant.InvalidPostStatus) || errors.Is(err, errors_constant.InvalidPublishAt) {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	response := postToResponse(post)
+	response := PostToResponse(post)
 	return c.Status(fiber.StatusCreated).JSON(response)
 }
 
 // GetPost godoc
 // @Summary Get post by ID
+// @Description Drafts and scheduled posts are returned only to their author.
 // @Tags Posts
 // @Produce json
 // @Param id path int true "Post ID"
@@ -68,7 +79,10 @@ func (h *PostsHandlers) GetPost(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid post id"})
 	}
 
-	post, err := h.service.GetPostByID(c.Context(), id)
+	viewer := Viewer{IP: c.IP(), UserAgent: c.Get(fiber.HeaderUserAgent)}
+	viewer.UserID, _ = c.Locals("user_id").(int)
+
+	post, err := h.service.ViewPost(c.Context(), id, viewer)
 	if err != nil {
 		if errors.Is(err, errors_constant.PostNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "post not found"})
@@ -76,31 +90,235 @@ func (h *PostsHandlers) GetPost(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	response := postToResponse(post)
+	response := PostToResponse(post)
+	return c.JSON(response)
+}
+
+// GetPostMetrics godoc
+// @Summary Get post metrics
+// @Description Views are deduplicated per viewer within VIEW_DEDUPE_WINDOW; unique_viewers is a HyperLogLog estimate. Author and crawler views are not counted.
+// @Tags Posts
+// @Produce json
+// @Param id path int true "Post ID"
+// @Success 200 {object} dto.PostMetricsResponse
+// @Failure 404 {object} map[string]string
+// @Router /api/posts/{id}/metrics [get]
+func (h *PostsHandlers) GetPostMetrics(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid post id"})
+	}
+	viewerID, _ := c.Locals("user_id").(int)
+
+	post, err := h.service.GetPostByID(c.Context(), id, viewerID)
+	if err != nil {
+		if errors.Is(err, errors_constant.PostNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "post not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(dto.PostMetricsResponse{
+		PostID:        post.ID,
+		Likes:         post.Like,
+		Views:         post.CountViewers,
+		UniqueViewers: post.UniqueViewers,
+		LikedByMe:     post.LikedByMe,
+		Reactions:     post.Reactions,
+		MyReaction:    post.MyReaction,
+	})
+}
+
+// GetMyDrafts godoc
+// @Summary List drafts and scheduled posts of the current user
+// @Tags Posts
+// @Produce json
+// @Param limit query int false "Page size (1-100, default 20)"
+// @Param cursor query string false "Cursor from next_cursor"
+// @Success 200 {object} dto.PostListResponse
+// @Failure 400 {object} map[string]string
+// @Router /api/me/drafts [get]
+func (h *PostsHandlers) GetMyDrafts(c *fiber.Ctx) error {
+	query := middleware.Query[dto.DraftsQuery](c)
+	if query == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid query parameters"})
+	}
+
+	userID, ok := c.Locals("user_id").(int)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "user not authenticated"})
+	}
+
+	filter := PostFilter{
+		UserID:     &userID,
+		OnlyActive: true,
+		Statuses:   []string{StatusDraft, StatusScheduled},
+		OrderBy:    "updated_at DESC",
+		Limit:      query.Limit,
+		ViewerID:   userID,
+	}
+	if query.Cursor != "" {
+		cursor, err := DecodePostCursor(query.Cursor)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		filter.Cursor = cursor
+	}
+
+	page, err := h.service.ListPostsPage(c.Context(), filter)
+	if err != nil {
+		if errors.Is(err, errors_constant.InvalidCursor) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	response := dto.PostListResponse{
+		Data:       make([]dto.PostResponse, len(page.Posts)),
+		NextCursor: page.NextCursor,
+	}
+	for i := range page.Posts {
+		response.Data[i] = PostToResponse(&page.Posts[i])
+	}
+
+	if page.NextCursor != "" {
+		c.Links(NextPageURL(c, page.NextCursor), "next")
+	}
 	return c.JSON(response)
 }
 
 // GetAllPosts godoc
-// @Summary List all posts
+// @Summary List posts
+// @Description Keyset-paginated list of posts. Pass next_cursor from the previous page as cursor to get the next one.
 // @Tags Posts
 // @Produce json
-// @Success 200 {array} dto.PostResponse
+// @Param user_id query int false "Author ID"
+// @Param tag query string false "Tag"
+// @Param title query string false "Substring of the title"
+// @Param from query string false "Created at or after (RFC 3339)"
+// @Param to query string false "Created at or before (RFC 3339)"
+// @Param limit query int false "Page size (1-100, default 20)"
+// @Param offset query int false "Offset, ignored when cursor is set"
+// @Param order_by query string false "Sort key" Enums(created_at, updated_at, like, count_viewers)
+// @Param order query string false "Sort direction" Enums(asc, desc)
+// @Param cursor query string false "Cursor from next_cursor"
+// @Success 200 {object} dto.PostListResponse
+// @Failure 400 {object} map[string]string
 // @Router /api/posts [get]
 func (h *PostsHandlers) GetAllPosts(c *fiber.Ctx) error {
-	posts, err := h.service.ListPosts(c.Context(), PostFilter{OnlyActive: true})
+	query := middleware.Query[dto.ListPostsQuery](c)
+	if query == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid query parameters"})
+	}
+
+	filter, err := listQueryToFilter(query)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	filter.ViewerID, _ = c.Locals("user_id").(int)
+
+	page, err := h.service.ListPostsPage(c.Context(), filter)
+	if err != nil {
+		if errors.Is(err, errors_constant.InvalidCursor) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	response := dto.PostListResponse{
+		Data:       make([]dto.PostResponse, len(page.Posts)),
+		NextCursor: page.NextCursor,
+	}
+	for i := range page.Posts {
+		response.Data[i] = PostToResponse(&page.Posts[i])
+	}
+
+	if page.NextCursor != "" {
+		c.Links(NextPageURL(c, page.NextCursor), "next")
+	}
+	return c.JSON(response)
+}
+
+// SearchPosts godoc
+// @Summary Full-text search over posts
+// @Description Supports websearch syntax: quoted phrases, OR and -exclusion. Highlights are HTML-escaped, matches are wrapped in <mark>.
+// @Tags Posts
+// @Produce json
+// @Param q query string true "Search query"
+// @Param limit query int false "Page size (1-100, default 20)"
+// @Param offset query int false "Offset"
+// @Success 200 {object} dto.PostSearchResponse
+// @Failure 400 {object} map[string]string
+// @Router /api/posts/search [get]
+func (h *PostsHandlers) SearchPosts(c *fiber.Ctx) error {
+	query := middleware.Query[dto.SearchPostsQuery](c)
+	if query == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid query parameters"})
+	}
+
+	results, err := h.service.SearchPosts(c.Context(), query.Q, query.Limit, query.Offset)
 	if err != nil {
+		if errors.Is(err, errors_constant.InvalidSearchQuery) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	response := make([]dto.PostResponse, len(posts))
-	for i, post := range posts {
-		response[i] = postToResponse(&post)
+	response := dto.PostSearchResponse{Data: make([]dto.PostSearchItem, len(results))}
+	for i := range results {
+		response.Data[i] = dto.PostSearchItem{
+			PostResponse:   PostToResponse(&results[i].Post),
+			Rank:           results[i].Rank,
+			TitleHighlight: results[i].TitleHighlight,
+			Snippet:        results[i].Snippet,
+		}
 	}
 	return c.JSON(response)
 }
 
+// GetTrendingPosts godoc
+// @Summary Trending posts
+// @Description Ranked by likes, views and comments with exponential time decay; the 24h window halves a score every 6 hours, the 7d window every 36 hours.
+// @Tags Posts
+// @Produce json
+// @Param window query string false "Window: 24h (default) or 7d"
+// @Param limit query int false "Page size (1-100, default 20)"
+// @Success 200 {object} dto.TrendingPostsResponse
+// @Failure 400 {object} map[string]string
+// @Router /api/posts/trending [get]
+func (h *PostsHandlers) GetTrendingPosts(c *fiber.Ctx) error {
+	query := middleware.Query[dto.TrendingQuery](c)
+	if query == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid query parameters"})
+	}
+	viewerID, _ := c.Locals("user_id").(int)
+
+	posts, err := h.service.TrendingPosts(c.Context(), query.Window, "", query.Limit, viewerID)
+	if err != nil {
+		if errors.Is(err, errors_constant.InvalidTrendingWindow) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(TrendingToResponse(query.Window, posts))
+}
+
+// TrendingToResponse собирает ответ со списком трендовых постов
+func TrendingToResponse(window string, posts []Post) dto.TrendingPostsResponse {
+	if window == "" {
+		window = defaultTrendingWindow
+	}
+	response := dto.TrendingPostsResponse{Window: window, Data: make([]dto.PostResponse, len(posts))}
+	for i := range posts {
+		response.Data[i] = PostToResponse(&posts[i])
+	}
+	return response
+}
+
 // UpdatePost godoc
 // @Summary Update existing post
+// @Description Passing status (and publish_at for scheduled) changes the post lifecycle: draft, scheduled, published, archived.
 // @Tags Posts
 // @Accept json
 // @Produce json
@@ -125,7 +343,7 @@ func (h *PostsHandlers) UpdatePost(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "user not authenticated"})
 	}
 
-	currentPost, err := h.service.GetPostByID(c.Context(), id)
+	currentPost, err := h.service.GetPostByID(c.Context(), id, userID)
 	if err != nil {
 		if errors.Is(err, errors_constant.PostNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "post not found"})
@@ -145,26 +363,48 @@ func (h *PostsHandlers) UpdatePost(c *fiber.Ctx) error {
 	if req.Description != nil {
 		description = *req.Description
 	}
-	tag := currentPost.Tag
-	if req.Tag != nil {
-		tag = *req.Tag
+	tags := req.Tags
+	if tags == nil && req.Tag != nil {
+		tags = []string{*req.Tag}
 	}
 
-	post, err := h.service.UpdatePost(c.Context(), userID, id, title, description, tag)
-	if err != nil {
-		if errors.Is(err, errors_constant.PostNotFound) {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "post not found"})
+	post := currentPost
+	if req.Title != nil || req.Description != nil || tags != nil {
+		post, err = h.service.UpdatePost(c.Context(), userID, id, title, description, tags)
+		if err != nil {
+			return updatePostError(c, err)
 		}
-		if errors.Is(err, errors_constant.UserNotAuthorized) {
-			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "you can update only your own posts"})
+	}
+
+	if req.Status != nil || req.PublishAt != nil {
+		status := post.Status
+		if req.Status != nil {
+			status = *req.Status
+		}
+		post, err = h.service.ChangeStatus(c.Context(), userID, id, status, req.PublishAt)
+		if err != nil {
+			return updatePostError(c, err)
 		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	response := postToResponse(post)
+	response := PostToResponse(post)
 	return c.JSON(response)
 }
 
+func updatePostError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, errors_constant.PostNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "post not found"})
+	case errors.Is(err, errors_constant.InvalidTag), errors.Is(err, errors_constant.TooManyTags),
+		errors.Is(err, errors_constant.InvalidPostStatus), errors.Is(err, errors_constant.InvalidPublishAt):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, errors_constant.UserNotAuthorized):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "you can update only your own posts"})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+}
+
 // DeletePost godoc
 // @Summary Delete post
 // @Tags Posts
@@ -203,6 +443,7 @@ func (h *PostsHandlers) DeletePost(c *fiber.Ctx) error {
 // @Param id path int true "Post ID"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
 // @Router /api/posts/{id}/like [post]
 func (h *PostsHandlers) LikePost(c *fiber.Ctx) error {
 	id, err := strconv.Atoi(c.Params("id"))
@@ -215,18 +456,17 @@ func (h *PostsHandlers) LikePost(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "user not authenticated"})
 	}
 
-	if err := h.metricsService.LikePost(c.Context(), userID, id); err != nil {
+	likes, err := h.service.LikePost(c.Context(), id, userID)
+	if err != nil {
+		if errors.Is(err, errors_constant.PostNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "post not found"})
+		}
 		if err.Error() == "user already liked this post" {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	likes, err := h.metricsService.GetLikes(c.Context(), id)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
-	}
-
 	return c.JSON(fiber.Map{
 		"post_id": id,
 		"likes":   likes,
@@ -240,6 +480,7 @@ func (h *PostsHandlers) LikePost(c *fiber.Ctx) error {
 // @Param id path int true "Post ID"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
 // @Router /api/posts/{id}/unlike [delete]
 func (h *PostsHandlers) UnlikePost(c *fiber.Ctx) error {
 	id, err := strconv.Atoi(c.Params("id"))
@@ -252,18 +493,17 @@ func (h *PostsHandlers) UnlikePost(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "user not authenticated"})
 	}
 
-	if err := h.metricsService.UnlikePost(c.Context(), userID, id); err != nil {
+	likes, err := h.service.UnlikePost(c.Context(), id, userID)
+	if err != nil {
+		if errors.Is(err, errors_constant.PostNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "post not found"})
+		}
 		if err.Error() == "user hasn't liked this post" {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	likes, err := h.metricsService.GetLikes(c.Context(), id)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
-	}
-
 	return c.JSON(fiber.Map{
 		"post_id": id,
 		"likes":   likes,
@@ -271,16 +511,91 @@ func (h *PostsHandlers) UnlikePost(c *fiber.Ctx) error {
 	})
 }
 
-func postToResponse(post *Post) dto.PostResponse {
+func PostToResponse(post *Post) dto.PostResponse {
+	tags := post.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
 	return dto.PostResponse{
-		ID:           post.ID,
-		UserID:       post.UserID,
-		Title:        post.Title,
-		Description:  post.Description,
-		Tag:          post.Tag,
-		Like:         post.Like,
-		CountViewers: post.CountViewers,
-		CreatedAt:    post.CreatedAt,
-		UpdatedAt:    post.UpdatedAt,
+		ID:                 post.ID,
+		UserID:             post.UserID,
+		Title:              post.Title,
+		Description:        post.Description,
+		DescriptionHTML:    post.DescriptionHTML,
+		Excerpt:            post.Excerpt,
+		WordCount:          post.WordCount,
+		ReadingTimeMinutes: post.ReadingTimeMinutes(),
+		Tag:                post.Tag,
+		Tags:               tags,
+		Like:               post.Like,
+		CountViewers:       post.CountViewers,
+		UniqueViewers:      post.UniqueViewers,
+		CommentsCount:      post.CommentsCount,
+		LikedByMe:          post.LikedByMe,
+		Reactions:          post.Reactions,
+		MyReaction:         post.MyReaction,
+		Status:             post.Status,
+		PublishAt:          post.PublishAt,
+		CreatedAt:          post.CreatedAt,
+		UpdatedAt:          post.UpdatedAt,
+	}
+}
+
+func listQueryToFilter(q *dto.ListPostsQuery) (PostFilter, error) {
+	filter := PostFilter{
+		UserID:     q.UserID,
+		Tag:        q.Tag,
+		Title:      q.Title,
+		OnlyActive: true,
+		Limit:      q.Limit,
+		Offset:     q.Offset,
+	}
+
+	if q.From != nil {
+		from, err := time.Parse(time.RFC3339, *q.From)
+		if err != nil {
+			return filter, fmt.Errorf("invalid from: %w", err)
+		}
+		filter.FromDate = &from
+	}
+	if q.To != nil {
+		to, err := time.Parse(time.RFC3339, *q.To)
+		if err != nil {
+			return filter, fmt.Errorf("invalid to: %w", err)
+		}
+		filter.ToDate = &to
+	}
+
+	orderBy := q.OrderBy
+	if orderBy == "" {
+		orderBy = "created_at"
 	}
+	order := q.Order
+	if order == "" {
+		order = "desc"
+	}
+	filter.OrderBy = orderBy + " " + strings.ToUpper(order)
+
+	if q.Cursor != "" {
+		cursor, err := DecodePostCursor(q.Cursor)
+		if err != nil {
+			return filter, err
+		}
+		filter.Cursor = cursor
+	}
+
+	return filter, nil
+}
+
+// NextPageURL повторяет текущий запрос с новым курсором для заголовка Link (RFC 8288)
+func NextPageURL(c *fiber.Ctx, cursor string) string {
+	values := url.Values{}
+	c.Request().URI().QueryArgs().VisitAll(func(key, value []byte) {
+		values.Add(string(key), string(value))
+	})
+	values.Del("offset")
+	values.Set("cursor", cursor)
+
+	return c.BaseURL() + c.Path() + "?" + values.Encode()
 }
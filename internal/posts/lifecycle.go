@@ -0,0 +1,114 @@
+package posts
+
+import (
+	"context"
+	"fmt"
+	"mpb/pkg/errors_constant"
+	"time"
+)
+
+const publishBatchSize = 100
+
+// statusTransitions перечисляет допустимые переходы между статусами поста
+var statusTransitions = map[string][]string{
+	StatusDraft:     {StatusScheduled, StatusPublished},
+	StatusScheduled: {StatusDraft, StatusScheduled, StatusPublished},
+	StatusPublished: {StatusArchived},
+	StatusArchived:  {StatusPublished},
+}
+
+func canTransition(from, to string) bool {
+	for _, s := range statusTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// publishTime приводит время к UTC с точностью до микросекунд.
+// publish_at — TIMESTAMP без часового пояса, поэтому в базу и в сравнения попадает только UTC,
+// а точность Postgres гарантирует, что событие post.created и значение из базы совпадают.
+func publishTime(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Microsecond)
+}
+
+// resolvePublishAt вычисляет publish_at для нового статуса поста
+func resolvePublishAt(status string, publishAt *time.Time, now time.Time) (*time.Time, error) {
+	switch status {
+	case StatusDraft:
+		return nil, nil
+	case StatusScheduled:
+		if publishAt == nil || !publishAt.After(now) {
+			return nil, errors_constant.InvalidPublishAt
+		}
+		t := publishTime(*publishAt)
+		return &t, nil
+	case StatusPublished:
+		t := publishTime(now)
+		return &t, nil
+	default:
+		return nil, errors_constant.InvalidPostStatus
+	}
+}
+
+// ChangeStatus переводит пост автора в новый статус.
+// post.created отправляется только при первой публикации черновика или запланированного поста.
+func (s *PostsService) ChangeStatus(ctx context.Context, userID, postID int, status string, publishAt *time.Time) (*Post, error) {
+	post, err := s.repo.FindByID(ctx, postID)
+	if err != nil {
+		return nil, errors_constant.PostNotFound
+	}
+
+	if post.UserID != userID {
+		return nil, errors_constant.UserNotAuthorized
+	}
+
+	if !canTransition(post.Status, status) {
+		return nil, errors_constant.InvalidPostStatus
+	}
+
+	previous := post.Status
+	switch {
+	case status == StatusArchived:
+		// время публикации сохраняется, чтобы пост можно было вернуть из архива
+	case status == StatusPublished && previous == StatusArchived:
+	default:
+		post.PublishAt, err = resolvePublishAt(status, publishAt, time.Now())
+		if err != nil {
+			return nil, err
+		}
+	}
+	post.Status = status
+
+	if err := s.repo.UpdateStatus(ctx, post); err != nil {
+		return nil, fmt.Errorf("failed to change post status: %w", err)
+	}
+
+	if status == StatusPublished && previous != StatusArchived {
+		s.publishCreated(post)
+	}
+
+	s.loadTags(ctx, post)
+	return post, nil
+}
+
+// PublishDuePosts публикует запланированные посты, время которых наступило, и возвращает их количество
+func (s *PostsService) PublishDuePosts(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		posts, err := s.repo.PublishDue(ctx, publishTime(time.Now()), publishBatchSize)
+		if err != nil {
+			return total, err
+		}
+
+		for i := range posts {
+			s.publishCreated(&posts[i])
+		}
+		total += len(posts)
+
+		if len(posts) < publishBatchSize {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package posts
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mpb/pkg/errors_constant"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+)
+
+const likesBatchSize = 1000
+
+// LikeCursor указывает на последнюю строку страницы лайков: время лайка и id пользователя или поста
+type LikeCursor struct {
+	Time time.Time `json:"t"`
+	ID   int       `json:"id"`
+}
+
+func (c LikeCursor) Encode() string {
+	payload, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(payload)
+}
+
+func DecodeLikeCursor(s string) (*LikeCursor, error) {
+	payload, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors_constant.InvalidCursor
+	}
+
+	var cursor LikeCursor
+	if err := json.Unmarshal(payload, &cursor); err != nil || cursor.ID <= 0 || cursor.Time.IsZero() {
+		return nil, errors_constant.InvalidCursor
+	}
+	return &cursor, nil
+}
+
+type LikersPage struct {
+	Likers     []PostLiker
+	NextCursor string
+}
+
+type LikedPostsPage struct {
+	Posts      []LikedPost
+	NextCursor string
+}
+
+// ListPostLikers возвращает страницу пользователей, лайкнувших опубликованный пост
+func (s *PostsService) ListPostLikers(ctx context.Context, postID, limit int, cursor *LikeCursor) (*LikersPage, error) {
+	post, err := s.repo.FindByID(ctx, postID)
+	if err != nil || post.Status != StatusPublished {
+		return nil, errors_constant.PostNotFound
+	}
+
+	limit = clampLimit(limit)
+	likers, err := s.repo.ListLikers(ctx, postID, cursor, limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list likers: %w", err)
+	}
+
+	page := &LikersPage{Likers: likers}
+	if len(likers) > limit {
+		page.Likers = likers[:limit]
+		last := page.Likers[limit-1]
+		page.NextCursor = LikeCursor{Time: last.LikedAt, ID: last.UserID}.Encode()
+	}
+	return page, nil
+}
+
+// ListUserLikes возвращает страницу опубликованных постов, которые лайкнул пользователь
+func (s *PostsService) ListUserLikes(ctx context.Context, userID, limit int, cursor *LikeCursor) (*LikedPostsPage, error) {
+	limit = clampLimit(limit)
+	liked, err := s.repo.ListLikedPosts(ctx, userID, cursor, limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list liked posts: %w", err)
+	}
+
+	page := &LikedPostsPage{Posts: liked}
+	if len(liked) > limit {
+		page.Posts = liked[:limit]
+		last := page.Posts[limit-1]
+		page.NextCursor = LikeCursor{Time: last.LikedAt, ID: last.ID}.Encode()
+	}
+
+	posts := make([]Post, len(page.Posts))
+	for i := range page.Posts {
+		posts[i] = page.Posts[i].Post
+	}
+	s.applyMetrics(ctx, posts, 0)
+	s.attachTags(ctx, posts)
+	for i := range page.Posts {
+		page.Posts[i].Post = posts[i]
+	}
+
+	return page, nil
+}
+
+// WarmLikeCache восстанавливает лайки в Redis из post_likes после потери данных Redis.
+// Если таблица ещё пуста, наоборот переносит в неё лайки, которые до этого жили только в Redis.
+func (s *PostsService) WarmLikeCache(ctx context.Context) error {
+	ready, err := s.metricsService.LikesCacheReady(ctx)
+	if err != nil || ready {
+		return err
+	}
+
+	first, err := s.repo.LikesBatch(ctx, 0, 0, 1)
+	if err != nil {
+		return err
+	}
+
+	if len(first) == 0 {
+		err := s.metricsService.ScanLikes(ctx, likesBatchSize, func(likes []PostLike) error {
+			return s.repo.ImportLikes(ctx, likes)
+		})
+		if err != nil {
+			return err
+		}
+		return s.metricsService.MarkLikesCacheReady(ctx)
+	}
+
+	counts := make(map[int]int)
+	afterPostID, afterUserID := 0, 0
+	for {
+		likes, err := s.repo.LikesBatch(ctx, afterPostID, afterUserID, likesBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(likes) == 0 {
+			break
+		}
+
+		if err := s.metricsService.RestoreLikes(ctx, likes); err != nil {
+			return err
+		}
+		for _, like := range likes {
+			counts[like.PostID]++
+		}
+
+		last := likes[len(likes)-1]
+		afterPostID, afterUserID = last.PostID, last.UserID
+		if len(likes) < likesBatchSize {
+			break
+		}
+	}
+
+	if err := s.metricsService.SetLikeCounts(ctx, counts); err != nil {
+		return err
+	}
+	s.logger.Info("restored likes cache from post_likes", watermill.LogFields{"posts": len(counts)})
+	return s.metricsService.MarkLikesCacheReady(ctx)
+}
+
+func clampLimit(limit int) int {
+	if limit <= 0 {
+		return defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		return maxPageLimit
+	}
+	return limit
+}
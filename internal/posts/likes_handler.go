@@ -0,0 +1,73 @@
+package posts
+
+import (
+	"errors"
+	"mpb/internal/posts/dto"
+	"mpb/pkg/errors_constant"
+	"mpb/pkg/middleware"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetPostLikers godoc
+// @Summary List users who liked a post
+// @Tags Posts
+// @Produce json
+// @Param id path int true "Post ID"
+// @Param limit query int false "Page size (1-100, default 20)"
+// @Param cursor query string false "Cursor from next_cursor"
+// @Success 200 {object} dto.PostLikersResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/posts/{id}/likes [get]
+func (h *PostsHandlers) GetPostLikers(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid post id"})
+	}
+
+	query := middleware.Query[dto.LikesQuery](c)
+	if query == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid query parameters"})
+	}
+
+	var cursor *LikeCursor
+	if query.Cursor != "" {
+		if cursor, err = DecodeLikeCursor(query.Cursor); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+
+	page, err := h.service.ListPostLikers(c.Context(), id, query.Limit, cursor)
+	if err != nil {
+		if errors.Is(err, errors_constant.PostNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "post not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	response := dto.PostLikersResponse{
+		Data:       make([]dto.PostLikerResponse, len(page.Likers)),
+		NextCursor: page.NextCursor,
+	}
+	for i, liker := range page.Likers {
+		response.Data[i] = dto.PostLikerResponse{
+			UserID:   liker.UserID,
+			Username: liker.Username,
+			LikedAt:  liker.LikedAt,
+		}
+	}
+
+	if page.NextCursor != "" {
+		c.Links(NextPageURL(c, page.NextCursor), "next")
+	}
+	return c.JSON(response)
+}
+
+func LikedPostToResponse(post *LikedPost) dto.LikedPostResponse {
+	return dto.LikedPostResponse{
+		PostResponse: PostToResponse(&post.Post),
+		LikedAt:      post.LikedAt,
+	}
+}
@@ -3,8 +3,10 @@ package posts
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"mpb/configs"
 	"runtime"
 	"time"
 
@@ -13,14 +15,18 @@ import (
 )
 
 type MetricsSyncConsumer struct {
-	repo   PostsRepositoryInterface
-	logger watermill.LoggerAdapter
+	repo          PostsRepositoryInterface
+	logger        watermill.LoggerAdapter
+	flushInterval time.Duration
+	flushBatch    int
 }
 
-func NewMetricsSyncConsumer(repo PostsRepositoryInterface, logger watermill.LoggerAdapter) *MetricsSyncConsumer {
+func NewMetricsSyncConsumer(repo PostsRepositoryInterface, logger watermill.LoggerAdapter, conf configs.PostsConfig) *MetricsSyncConsumer {
 	return &MetricsSyncConsumer{
-		repo:   repo,
-		logger: logger,
+		repo:          repo,
+		logger:        logger,
+		flushInterval: conf.ViewsFlushInterval,
+		flushBatch:    conf.ViewsFlushBatch,
 	}
 }
 
@@ -40,13 +46,16 @@ func (c *MetricsSyncConsumer) StartConsumers(subscriber message.Subscriber) erro
 		return fmt.Errorf("failed to subscribe to post.unliked: %w", err)
 	}
 
+	messagesReacted, err := subscriber.Subscribe(context.Background(), "post.reacted")
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to post.reacted: %w", err)
+	}
+
 	c.logger.Info("Subscriptions created, starting consumers...", nil)
 
 	go func() {
 		c.logger.Info("Consumer for post.viewed started, waiting for messages...", nil)
-		for msg := range messagesViewed {
-			c.processViewedEvent(msg)
-		}
+		c.consumeViews(messagesViewed)
 	}()
 
 	go func() {
@@ -63,6 +72,13 @@ func (c *MetricsSyncConsumer) StartConsumers(subscriber message.Subscriber) erro
 		}
 	}()
 
+	go func() {
+		c.logger.Info("Consumer for post.reacted started, waiting for messages...", nil)
+		for msg := range messagesReacted {
+			c.processReactedEvent(msg)
+		}
+	}()
+
 	for i := 0; i < 50; i++ {
 		runtime.Gosched()
 	}
@@ -72,97 +88,150 @@ func (c *MetricsSyncConsumer) StartConsumers(subscriber message.Subscriber) erro
 	return nil
 }
 
-func (c *MetricsSyncConsumer) processViewedEvent(msg *message.Message) {
-	var event PostViewedEvent
-	if err := json.Unmarshal(msg.Payload, &event); err != nil {
-		c.logger.Error("failed to unmarshal viewed event", err, nil)
-		msg.Nack()
-		return
+// consumeViews копит приросты просмотров по постам и сбрасывает их в PostgreSQL
+// раз в flushInterval или после flushBatch событий. Событие подтверждается сразу после
+// попадания в буфер: потерянный при падении прирост исправит сверка метрик.
+func (c *MetricsSyncConsumer) consumeViews(messages <-chan *message.Message) {
+	ticker := time.NewTicker(c.flushInterval)
+	defer ticker.Stop()
+
+	pending := make(map[int]int)
+	events := 0
+
+	for {
+		select {
+		case msg, ok := <-messages:
+			if !ok {
+				c.flushViews(pending)
+				return
+			}
+
+			var event PostViewedEvent
+			if err := json.Unmarshal(msg.Payload, &event); err != nil {
+				c.dropMalformed(msg, "viewed", err)
+				continue
+			}
+			if event.PostID == 0 {
+				c.dropMalformed(msg, "viewed", errMissingPostID)
+				continue
+			}
+			pending[event.PostID]++
+			events++
+			msg.Ack()
+
+			if events >= c.flushBatch && c.flushViews(pending) {
+				events = 0
+			}
+		case <-ticker.C:
+			if c.flushViews(pending) {
+				events = 0
+			}
+		}
+	}
+}
+
+// flushViews записывает накопленные приросты; при ошибке они остаются в буфере до следующей попытки
+func (c *MetricsSyncConsumer) flushViews(pending map[int]int) bool {
+	if len(pending) == 0 {
+		return true
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	if err := c.syncViews(ctx, event.PostID, event.Views); err != nil {
-		c.logger.Error("failed to sync views", err, nil)
-		msg.Nack()
-		return
+	if err := c.repo.AddViews(ctx, pending); err != nil {
+		c.logger.Error("failed to flush views", err, watermill.LogFields{"posts": len(pending)})
+		return false
 	}
 
+	log.Printf("Flushed views for %d posts", len(pending))
+	clear(pending)
+	return true
+}
+
+var errMissingPostID = errors.New("post_id is missing")
+
+// dropMalformed подтверждает и отбрасывает битое событие: повторная доставка его не исправит,
+// а Nack крутил бы его в подписке бесконечно
+func (c *MetricsSyncConsumer) dropMalformed(msg *message.Message, kind string, err error) {
+	c.logger.Error("dropping malformed "+kind+" event", err, watermill.LogFields{"message_uuid": msg.UUID})
 	msg.Ack()
-	log.Printf("Synced views for post %d: %d", event.PostID, event.Views)
 }
 
 func (c *MetricsSyncConsumer) processLikedEvent(msg *message.Message) {
 	var event PostLikedEvent
 	if err := json.Unmarshal(msg.Payload, &event); err != nil {
-		c.logger.Error("failed to unmarshal liked event", err, nil)
-		msg.Nack()
+		c.dropMalformed(msg, "liked", err)
+		return
+	}
+	if event.PostID == 0 {
+		c.dropMalformed(msg, "liked", errMissingPostID)
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	if err := c.syncLikes(ctx, event.PostID, event.Likes); err != nil {
+	if err := c.repo.AddLike(ctx, event.PostID, event.UserID); err != nil {
 		c.logger.Error("failed to sync likes", err, nil)
 		msg.Nack()
 		return
 	}
 
 	msg.Ack()
-	log.Printf("Synced likes for post %d: %d", event.PostID, event.Likes)
+	log.Printf("Synced like of user %d for post %d", event.UserID, event.PostID)
 }
 
 func (c *MetricsSyncConsumer) processUnlikedEvent(msg *message.Message) {
 	var event PostUnlikedEvent
 	if err := json.Unmarshal(msg.Payload, &event); err != nil {
-		c.logger.Error("failed to unmarshal unliked event", err, nil)
-		msg.Nack()
+		c.dropMalformed(msg, "unliked", err)
+		return
+	}
+	if event.PostID == 0 {
+		c.dropMalformed(msg, "unliked", errMissingPostID)
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	if err := c.syncLikes(ctx, event.PostID, event.Likes); err != nil {
+	if err := c.repo.RemoveLike(ctx, event.PostID, event.UserID); err != nil {
 		c.logger.Error("failed to sync likes", err, nil)
 		msg.Nack()
 		return
 	}
 
 	msg.Ack()
-	log.Printf("Synced likes for post %d: %d (unliked)", event.PostID, event.Likes)
+	log.Printf("Synced unlike of user %d for post %d", event.UserID, event.PostID)
 }
 
-func (c *MetricsSyncConsumer) syncViews(ctx context.Context, postID, views int) error {
-	post, err := c.repo.FindByID(ctx, postID)
-	if err != nil {
-		return fmt.Errorf("post not found: %w", err)
+func (c *MetricsSyncConsumer) processReactedEvent(msg *message.Message) {
+	var event PostReactedEvent
+	if err := json.Unmarshal(msg.Payload, &event); err != nil {
+		c.dropMalformed(msg, "reacted", err)
+		return
 	}
-
-	post.CountViewers = views
-	post.UpdatedAt = time.Now()
-
-	if err := c.repo.Update(ctx, post); err != nil {
-		return fmt.Errorf("failed to update views: %w", err)
+	if event.PostID == 0 {
+		c.dropMalformed(msg, "reacted", errMissingPostID)
+		return
 	}
 
-	return nil
-}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
 
-func (c *MetricsSyncConsumer) syncLikes(ctx context.Context, postID, likes int) error {
-	post, err := c.repo.FindByID(ctx, postID)
-	if err != nil {
-		return fmt.Errorf("post not found: %w", err)
+	var err error
+	if event.Reaction == "" {
+		err = c.repo.DeleteReaction(ctx, event.PostID, event.UserID)
+	} else {
+		err = c.repo.UpsertReaction(ctx, event.PostID, event.UserID, event.Reaction)
 	}
-
-	post.Like = likes
-	post.UpdatedAt = time.Now()
-
-	if err := c.repo.Update(ctx, post); err != nil {
-		return fmt.Errorf("failed to update likes: %w", err)
+	if err != nil {
+		c.logger.Error("failed to sync reaction", err, nil)
+		msg.Nack()
+		return
 	}
 
-	return nil
+	msg.Ack()
+	log.Printf("Synced reaction of user %d on post %d: %q", event.UserID, event.PostID, event.Reaction)
 }
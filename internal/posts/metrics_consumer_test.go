@@ -0,0 +1,97 @@
+package posts
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/stretchr/testify/mock"
+)
+
+func viewedMessages(t *testing.T, postIDs ...int) chan *message.Message {
+	t.Helper()
+
+	messages := make(chan *message.Message, len(postIDs))
+	for _, id := range postIDs {
+		payload, err := json.Marshal(PostViewedEvent{PostID: id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		messages <- message.NewMessage(watermill.NewUUID(), payload)
+	}
+	close(messages)
+	return messages
+}
+
+func TestMetricsSyncConsumer_ConsumeViews(t *testing.T) {
+	repo := new(MockPostsRepository)
+	repo.On("AddViews", mock.Anything, mock.MatchedBy(func(d map[int]int) bool {
+		return len(d) == 2 && d[1] == 2 && d[2] == 1
+	})).Return(nil).Once()
+	repo.On("AddViews", mock.Anything, mock.MatchedBy(func(d map[int]int) bool {
+		return len(d) == 1 && d[3] == 1
+	})).Return(nil).Once()
+
+	consumer := &MetricsSyncConsumer{repo: repo, logger: new(MockLogger), flushInterval: time.Hour, flushBatch: 3}
+	consumer.consumeViews(viewedMessages(t, 1, 2, 1, 3))
+
+	repo.AssertExpectations(t)
+}
+
+func TestMetricsSyncConsumer_ConsumeViewsKeepsDeltasOnError(t *testing.T) {
+	repo := new(MockPostsRepository)
+	single := mock.MatchedBy(func(d map[int]int) bool { return len(d) == 1 && d[7] == 1 })
+	repo.On("AddViews", mock.Anything, single).Return(errors.New("db down")).Once()
+	repo.On("AddViews", mock.Anything, single).Return(nil).Once()
+
+	consumer := &MetricsSyncConsumer{repo: repo, logger: new(MockLogger), flushInterval: time.Hour, flushBatch: 1}
+	consumer.consumeViews(viewedMessages(t, 7))
+
+	repo.AssertNumberOfCalls(t, "AddViews", 2)
+}
+
+func TestMetricsSyncConsumer_AcksMalformedEvents(t *testing.T) {
+	repo := new(MockPostsRepository)
+	consumer := &MetricsSyncConsumer{repo: repo, logger: new(MockLogger), flushInterval: time.Hour, flushBatch: 10}
+
+	process := map[string]func(*message.Message){
+		"liked":   consumer.processLikedEvent,
+		"unliked": consumer.processUnlikedEvent,
+		"reacted": consumer.processReactedEvent,
+	}
+	for name, handle := range process {
+		for _, payload := range []string{`not json`, `{"user_id":1}`} {
+			msg := message.NewMessage(watermill.NewUUID(), []byte(payload))
+			handle(msg)
+			select {
+			case <-msg.Acked():
+			default:
+				t.Fatalf("%s: malformed payload %q was not acked", name, payload)
+			}
+		}
+	}
+
+	viewed := make(chan *message.Message, 2)
+	bad := []*message.Message{
+		message.NewMessage(watermill.NewUUID(), []byte(`not json`)),
+		message.NewMessage(watermill.NewUUID(), []byte(`{}`)),
+	}
+	for _, msg := range bad {
+		viewed <- msg
+	}
+	close(viewed)
+	consumer.consumeViews(viewed)
+	for _, msg := range bad {
+		select {
+		case <-msg.Acked():
+		default:
+			t.Fatalf("viewed: malformed payload %q was not acked", msg.Payload)
+		}
+	}
+
+	repo.AssertNotCalled(t, "AddLike", mock.Anything, mock.Anything, mock.Anything)
+	repo.AssertNotCalled(t, "AddViews", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,56 @@
+package posts
+
+import (
+	"context"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+)
+
+// MetricsReconciler периодически сверяет счётчики постов в Redis и PostgreSQL
+type MetricsReconciler struct {
+	service  *PostsService
+	interval time.Duration
+	logger   watermill.LoggerAdapter
+}
+
+func NewMetricsReconciler(service *PostsService, interval time.Duration, logger watermill.LoggerAdapter) *MetricsReconciler {
+	return &MetricsReconciler{
+		service:  service,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Start запускает сверку в отдельной горутине до отмены ctx
+func (r *MetricsReconciler) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.tick(ctx)
+			}
+		}
+	}()
+}
+
+func (r *MetricsReconciler) tick(ctx context.Context) {
+	report, err := r.service.ReconcileMetrics(ctx, false, nil)
+	if err != nil {
+		r.logger.Error("failed to reconcile post metrics", err, nil)
+		return
+	}
+	if report.Drifted > 0 {
+		r.logger.Info("reconciled post metrics", watermill.LogFields{
+			"scanned":        report.Scanned,
+			"drifted":        report.Drifted,
+			"redis_repaired": report.RedisRepaired,
+			"db_repaired":    report.DBRepaired,
+		})
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"mpb/configs"
+	"mpb/pkg/errors_constant"
 	"strconv"
+	"time"
 
 	"github.com/ThreeDotsLabs/watermill"
 	"github.com/ThreeDotsLabs/watermill/message"
@@ -15,34 +18,147 @@ const (
 	keyPostLikes     = "post:likes:%d"
 	keyPostViews     = "post:views:%d"
 	keyUserLikedPost = "user:%d:liked:%d"
+	// keyPostViewer — отметка просмотра зрителем на время окна дедупликации, keyPostUniq — HyperLogLog зрителей
+	keyPostViewer = "post:viewed:%d:%s"
+	keyPostUniq   = "post:uniq:%d"
+	// keyPostReactions — hash реакция → количество, keyPostReactors — hash user_id → реакция
+	keyPostReactions = "post:reactions:%d"
+	keyPostReactors  = "post:reactors:%d"
+	// keyTrending и keyTrendingTag — sorted set очков тренда по окну (и тегу)
+	keyTrending    = "trending:%s"
+	keyTrendingTag = "trending:%s:tag:%s"
+	// keyLikesRestored отмечает, что связи лайков в Redis совпадают с post_likes
+	keyLikesRestored = "metrics:likes:restored"
 )
 
+// recordViewScript засчитывает просмотр, если зритель не смотрел пост в течение окна,
+// и добавляет его в HyperLogLog. Отсутствующий счётчик сначала заполняется значением из PostgreSQL (ARGV[3]).
+// Возвращает новое число просмотров или -1 для повтора.
+var recordViewScript = redis.NewScript(`
+redis.call('PFADD', KEYS[3], ARGV[2])
+if redis.call('SET', KEYS[1], '1', 'NX', 'EX', ARGV[1]) then
+	redis.call('SET', KEYS[2], ARGV[3], 'NX')
+	return redis.call('INCR', KEYS[2])
+end
+return -1
+`)
+
+// likeScript отмечает лайк пользователя и увеличивает счётчик одной операцией, чтобы параллельные
+// запросы не засчитали лайк дважды. Отсутствующий счётчик сначала заполняется значением из PostgreSQL (ARGV[1]).
+// Возвращает новое число лайков или -1, если лайк уже стоял.
+var likeScript = redis.NewScript(`
+if redis.call('SET', KEYS[1], '1', 'NX') then
+	redis.call('SET', KEYS[2], ARGV[1], 'NX')
+	return redis.call('INCR', KEYS[2])
+end
+return -1
+`)
+
+// unlikeScript снимает отметку лайка и уменьшает счётчик, заполнив отсутствующий значением из PostgreSQL (ARGV[1]);
+// -1 — лайка не было
+var unlikeScript = redis.NewScript(`
+if redis.call('DEL', KEYS[1]) == 1 then
+	redis.call('SET', KEYS[2], ARGV[1], 'NX')
+	return redis.call('DECR', KEYS[2])
+end
+return -1
+`)
+
+// raiseCounterScript поднимает счётчик до ARGV[1], если он отсутствует или меньше
+var raiseCounterScript = redis.NewScript(`
+local current = tonumber(redis.call('GET', KEYS[1]) or '-1')
+if current < tonumber(ARGV[1]) then
+	redis.call('SET', KEYS[1], ARGV[1])
+	return 1
+end
+return 0
+`)
+
+// setReactionScript ставит или меняет реакцию пользователя и возвращает предыдущую
+var setReactionScript = redis.NewScript(`
+local prev = redis.call('HGET', KEYS[1], ARGV[1])
+if prev == ARGV[2] then
+	return prev
+end
+if prev then
+	redis.call('HINCRBY', KEYS[2], prev, -1)
+else
+	prev = ''
+end
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+redis.call('HINCRBY', KEYS[2], ARGV[2], 1)
+return prev
+`)
+
+// removeReactionScript снимает реакцию пользователя и возвращает её (пустая строка — реакции не было)
+var removeReactionScript = redis.NewScript(`
+local prev = redis.call('HGET', KEYS[1], ARGV[1])
+if not prev then
+	return ''
+end
+redis.call('HDEL', KEYS[1], ARGV[1])
+if redis.call('HINCRBY', KEYS[2], prev, -1) <= 0 then
+	redis.call('HDEL', KEYS[2], prev)
+end
+return prev
+`)
+
+// addLogScoreScript прибавляет к логарифмическим очкам участника ARGV[2] (ln(eᵃ + eᵇ))
+var addLogScoreScript = redis.NewScript(`
+local add = tonumber(ARGV[2])
+local current = redis.call('ZSCORE', KEYS[1], ARGV[1])
+if current then
+	current = tonumber(current)
+	local hi, lo = math.max(current, add), math.min(current, add)
+	add = hi + math.log(1 + math.exp(lo - hi))
+end
+redis.call('ZADD', KEYS[1], add, ARGV[1])
+return tostring(add)
+`)
+
 type MetricsService struct {
-	redis     *redis.Client
-	publisher message.Publisher
-	logger    watermill.LoggerAdapter
+	redis       *redis.Client
+	publisher   message.Publisher
+	logger      watermill.LoggerAdapter
+	reactions   []string
+	dedupWindow time.Duration
 }
 
-func NewMetricsService(redisClient *redis.Client, publisher message.Publisher, logger watermill.LoggerAdapter) *MetricsService {
+func NewMetricsService(redisClient *redis.Client, publisher message.Publisher, logger watermill.LoggerAdapter, conf configs.PostsConfig) *MetricsService {
 	return &MetricsService{
-		redis:     redisClient,
-		publisher: publisher,
-		logger:    logger,
+		redis:       redisClient,
+		publisher:   publisher,
+		logger:      logger,
+		reactions:   conf.Reactions,
+		dedupWindow: conf.ViewDedupeWindow,
 	}
 }
 
-// IncrementViews увеличивает счетчик просмотров поста
-func (s *MetricsService) IncrementViews(ctx context.Context, postID int) error {
-	key := fmt.Sprintf(keyPostViews, postID)
-	views, err := s.redis.Incr(ctx, key).Result()
+// RecordView засчитывает просмотр поста с учётом окна дедупликации.
+// Просмотры автора и краулеров не учитываются ни в счётчике, ни в оценке уникальных зрителей.
+func (s *MetricsService) RecordView(ctx context.Context, post *Post, viewer Viewer) error {
+	if viewer.IsCrawler() || (viewer.UserID > 0 && viewer.UserID == post.UserID) {
+		return nil
+	}
+
+	fingerprint := viewer.Fingerprint()
+	keys := []string{
+		fmt.Sprintf(keyPostViewer, post.ID, fingerprint),
+		fmt.Sprintf(keyPostViews, post.ID),
+		fmt.Sprintf(keyPostUniq, post.ID),
+	}
+	window := max(int(s.dedupWindow.Seconds()), 1)
+	views, err := recordViewScript.Run(ctx, s.redis, keys, window, fingerprint, post.CountViewers).Int()
 	if err != nil {
-		return fmt.Errorf("failed to increment views: %w", err)
+		return fmt.Errorf("failed to record view: %w", err)
+	}
+	if views < 0 {
+		return nil
 	}
 
-	// Публикуем событие просмотра
 	event := PostViewedEvent{
-		PostID: postID,
-		Views:  int(views),
+		PostID: post.ID,
+		Views:  views,
 	}
 	if err := s.publishEvent("post.viewed", event); err != nil {
 		s.logger.Error("failed to publish post.viewed event", err, nil)
@@ -65,26 +181,15 @@ func (s *MetricsService) GetViews(ctx context.Context, postID int) (int, error)
 }
 
 // LikePost ставит лайк посту от пользователя
-func (s *MetricsService) LikePost(ctx context.Context, userID, postID int) error {
-	userLikedKey := fmt.Sprintf(keyUserLikedPost, userID, postID)
-	exists, err := s.redis.Exists(ctx, userLikedKey).Result()
+func (s *MetricsService) LikePost(ctx context.Context, userID int, post *Post) error {
+	postID := post.ID
+	keys := []string{fmt.Sprintf(keyUserLikedPost, userID, postID), fmt.Sprintf(keyPostLikes, postID)}
+	likes, err := likeScript.Run(ctx, s.redis, keys, post.Like).Int64()
 	if err != nil {
-		return fmt.Errorf("failed to check like: %w", err)
-	}
-	if exists > 0 {
-		return fmt.Errorf("user already liked this post")
-	}
-
-	// Помечаем, что пользователь лайкнул
-	if err := s.redis.Set(ctx, userLikedKey, "1", 0).Err(); err != nil {
 		return fmt.Errorf("failed to set like: %w", err)
 	}
-
-	// Увеличиваем счетчик лайков
-	likesKey := fmt.Sprintf(keyPostLikes, postID)
-	likes, err := s.redis.Incr(ctx, likesKey).Result()
-	if err != nil {
-		return fmt.Errorf("failed to increment likes: %w", err)
+	if likes < 0 {
+		return fmt.Errorf("user already liked this post")
 	}
 
 	// Публикуем событие лайка
@@ -101,26 +206,15 @@ func (s *MetricsService) LikePost(ctx context.Context, userID, postID int) error
 }
 
 // UnlikePost убирает лайк с поста
-func (s *MetricsService) UnlikePost(ctx context.Context, userID, postID int) error {
-	userLikedKey := fmt.Sprintf(keyUserLikedPost, userID, postID)
-	exists, err := s.redis.Exists(ctx, userLikedKey).Result()
+func (s *MetricsService) UnlikePost(ctx context.Context, userID int, post *Post) error {
+	postID := post.ID
+	keys := []string{fmt.Sprintf(keyUserLikedPost, userID, postID), fmt.Sprintf(keyPostLikes, postID)}
+	likes, err := unlikeScript.Run(ctx, s.redis, keys, post.Like).Int64()
 	if err != nil {
-		return fmt.Errorf("failed to check like: %w", err)
-	}
-	if exists == 0 {
-		return fmt.Errorf("user hasn't liked this post")
-	}
-
-	// Удаляем отметку лайка
-	if err := s.redis.Del(ctx, userLikedKey).Err(); err != nil {
 		return fmt.Errorf("failed to remove like: %w", err)
 	}
-
-	// Уменьшаем счетчик лайков
-	likesKey := fmt.Sprintf(keyPostLikes, postID)
-	likes, err := s.redis.Decr(ctx, likesKey).Result()
-	if err != nil {
-		return fmt.Errorf("failed to decrement likes: %w", err)
+	if likes < 0 {
+		return fmt.Errorf("user hasn't liked this post")
 	}
 
 	// Публикуем событие удаления лайка
@@ -159,28 +253,367 @@ func (s *MetricsService) IsLiked(ctx context.Context, userID, postID int) (bool,
 	return exists > 0, nil
 }
 
-// GetMetrics возвращает метрики поста (лайки и просмотры)
-func (s *MetricsService) GetMetrics(ctx context.Context, postID int) (likes int, views int, err error) {
-	likesKey := fmt.Sprintf(keyPostLikes, postID)
-	viewsKey := fmt.Sprintf(keyPostViews, postID)
+// LikesCacheReady сообщает, восстановлены ли лайки в Redis после холодного старта
+func (s *MetricsService) LikesCacheReady(ctx context.Context) (bool, error) {
+	exists, err := s.redis.Exists(ctx, keyLikesRestored).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check likes cache: %w", err)
+	}
+	return exists > 0, nil
+}
+
+func (s *MetricsService) MarkLikesCacheReady(ctx context.Context) error {
+	if err := s.redis.Set(ctx, keyLikesRestored, "1", 0).Err(); err != nil {
+		return fmt.Errorf("failed to mark likes cache: %w", err)
+	}
+	return nil
+}
 
+// RestoreLikes записывает в Redis отметки лайков пользователей
+func (s *MetricsService) RestoreLikes(ctx context.Context, likes []PostLike) error {
 	pipe := s.redis.Pipeline()
-	likesCmd := pipe.Get(ctx, likesKey)
-	viewsCmd := pipe.Get(ctx, viewsKey)
+	for _, like := range likes {
+		pipe.Set(ctx, fmt.Sprintf(keyUserLikedPost, like.UserID, like.PostID), "1", 0)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to restore likes: %w", err)
+	}
+	return nil
+}
 
-	_, err = pipe.Exec(ctx)
-	if err != nil && err != redis.Nil {
-		return 0, 0, fmt.Errorf("failed to get metrics: %w", err)
+// SetLikeCounts перезаписывает счётчики лайков постов
+func (s *MetricsService) SetLikeCounts(ctx context.Context, counts map[int]int) error {
+	pipe := s.redis.Pipeline()
+	for postID, likes := range counts {
+		pipe.Set(ctx, fmt.Sprintf(keyPostLikes, postID), likes, 0)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to restore like counts: %w", err)
+	}
+	return nil
+}
+
+// ScanLikes обходит отметки лайков в Redis пачками примерно по batch штук
+func (s *MetricsService) ScanLikes(ctx context.Context, batch int, fn func([]PostLike) error) error {
+	iter := s.redis.Scan(ctx, 0, "user:*:liked:*", int64(batch)).Iterator()
+	likes := make([]PostLike, 0, batch)
+
+	for iter.Next(ctx) {
+		var like PostLike
+		if _, err := fmt.Sscanf(iter.Val(), keyUserLikedPost, &like.UserID, &like.PostID); err != nil {
+			continue
+		}
+		likes = append(likes, like)
+
+		if len(likes) >= batch {
+			if err := fn(likes); err != nil {
+				return err
+			}
+			likes = likes[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("failed to scan likes: %w", err)
+	}
+
+	if len(likes) > 0 {
+		return fn(likes)
+	}
+	return nil
+}
+
+// GetCounters читает только счётчики лайков и просмотров набора постов одним MGET
+func (s *MetricsService) GetCounters(ctx context.Context, ids []int) (map[int]*PostMetrics, error) {
+	result := make(map[int]*PostMetrics, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	keys := make([]string, 0, 2*len(ids))
+	for _, id := range ids {
+		keys = append(keys, fmt.Sprintf(keyPostLikes, id), fmt.Sprintf(keyPostViews, id))
+	}
+	values, err := s.redis.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get counters: %w", err)
 	}
 
-	if likesCmd.Err() == nil {
-		likes, _ = strconv.Atoi(likesCmd.Val())
+	for i, id := range ids {
+		m := &PostMetrics{}
+		m.Likes, m.HasLikes = parseCounter(values[2*i])
+		m.Views, m.HasViews = parseCounter(values[2*i+1])
+		result[id] = m
 	}
-	if viewsCmd.Err() == nil {
-		views, _ = strconv.Atoi(viewsCmd.Val())
+	return result, nil
+}
+
+// WarmCounters заполняет отсутствующие счётчики значениями из PostgreSQL, не трогая существующие
+func (s *MetricsService) WarmCounters(ctx context.Context, counters []PostCounters) error {
+	pipe := s.redis.Pipeline()
+	for _, c := range counters {
+		pipe.SetNX(ctx, fmt.Sprintf(keyPostLikes, c.PostID), c.Likes, 0)
+		pipe.SetNX(ctx, fmt.Sprintf(keyPostViews, c.PostID), c.Views, 0)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to warm counters: %w", err)
+	}
+	return nil
+}
+
+// RepairCounters перезаписывает счётчики лайков, а счётчики просмотров только поднимает:
+// просмотры растут монотонно, и меньшее значение заведомо устарело
+func (s *MetricsService) RepairCounters(ctx context.Context, likes, views map[int]int) error {
+	if len(likes) == 0 && len(views) == 0 {
+		return nil
 	}
 
-	return likes, views, nil
+	pipe := s.redis.Pipeline()
+	for postID, n := range likes {
+		pipe.Set(ctx, fmt.Sprintf(keyPostLikes, postID), n, 0)
+	}
+	for postID, n := range views {
+		raiseCounterScript.Eval(ctx, pipe, []string{fmt.Sprintf(keyPostViews, postID)}, n)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to repair counters: %w", err)
+	}
+	return nil
+}
+
+// AddTrending начисляет постам очки тренда во всех окнах: в общий рейтинг и в рейтинги их тегов
+func (s *MetricsService) AddTrending(ctx context.Context, weights map[int]float64, tags map[int][]string, at time.Time) error {
+	if len(weights) == 0 {
+		return nil
+	}
+
+	pipe := s.redis.Pipeline()
+	for _, window := range trendingWindows {
+		for postID, weight := range weights {
+			score := window.score(weight, at)
+			member := strconv.Itoa(postID)
+			addLogScoreScript.Eval(ctx, pipe, []string{window.key("")}, member, score)
+			for _, tag := range tags[postID] {
+				addLogScoreScript.Eval(ctx, pipe, []string{window.key(tag)}, member, score)
+			}
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to update trending: %w", err)
+	}
+	return nil
+}
+
+// TrendingIDs убирает из рейтинга посты без активности в окне и возвращает первые limit id
+func (s *MetricsService) TrendingIDs(ctx context.Context, window TrendingWindow, tag string, limit int, now time.Time) ([]int, error) {
+	key := window.key(tag)
+
+	pipe := s.redis.Pipeline()
+	pipe.ZRemRangeByScore(ctx, key, "-inf", "("+strconv.FormatFloat(window.cutoff(now), 'f', -1, 64))
+	rangeCmd := pipe.ZRevRange(ctx, key, 0, int64(limit-1))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to read trending: %w", err)
+	}
+
+	members := rangeCmd.Val()
+	ids := make([]int, 0, len(members))
+	for _, member := range members {
+		if id, err := strconv.Atoi(member); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// Reactions возвращает допустимый набор реакций
+func (s *MetricsService) Reactions() []string {
+	return s.reactions
+}
+
+// IsAllowedReaction проверяет, входит ли реакция в настроенный набор
+func (s *MetricsService) IsAllowedReaction(reaction string) bool {
+	for _, r := range s.reactions {
+		if r == reaction {
+			return true
+		}
+	}
+	return false
+}
+
+// SetReaction ставит реакцию пользователя на пост, заменяя предыдущую
+func (s *MetricsService) SetReaction(ctx context.Context, userID, postID int, reaction string) error {
+	if !s.IsAllowedReaction(reaction) {
+		return errors_constant.InvalidReaction
+	}
+
+	keys := []string{fmt.Sprintf(keyPostReactors, postID), fmt.Sprintf(keyPostReactions, postID)}
+	previous, err := setReactionScript.Run(ctx, s.redis, keys, userID, reaction).Text()
+	if err != nil {
+		return fmt.Errorf("failed to set reaction: %w", err)
+	}
+	if previous == reaction {
+		return nil
+	}
+
+	s.publishReacted(ctx, userID, postID, reaction, previous)
+	return nil
+}
+
+// RemoveReaction снимает реакцию пользователя с поста
+func (s *MetricsService) RemoveReaction(ctx context.Context, userID, postID int) error {
+	keys := []string{fmt.Sprintf(keyPostReactors, postID), fmt.Sprintf(keyPostReactions, postID)}
+	previous, err := removeReactionScript.Run(ctx, s.redis, keys, userID).Text()
+	if err != nil {
+		return fmt.Errorf("failed to remove reaction: %w", err)
+	}
+	if previous == "" {
+		return errors_constant.ReactionNotFound
+	}
+
+	s.publishReacted(ctx, userID, postID, "", previous)
+	return nil
+}
+
+// GetReactions возвращает количество каждой реакции на пост и реакцию пользователя (userID == 0 — аноним)
+func (s *MetricsService) GetReactions(ctx context.Context, postID, userID int) (map[string]int, string, error) {
+	pipe := s.redis.Pipeline()
+	countsCmd := pipe.HGetAll(ctx, fmt.Sprintf(keyPostReactions, postID))
+	var mineCmd *redis.StringCmd
+	if userID > 0 {
+		mineCmd = pipe.HGet(ctx, fmt.Sprintf(keyPostReactors, postID), strconv.Itoa(userID))
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, "", fmt.Errorf("failed to get reactions: %w", err)
+	}
+
+	counts := s.reactionCounts(countsCmd.Val())
+
+	var mine string
+	if mineCmd != nil && mineCmd.Err() == nil {
+		mine = mineCmd.Val()
+	}
+
+	return counts, mine, nil
+}
+
+// reactionCounts дополняет счётчики из Redis нулями для всех настроенных реакций
+func (s *MetricsService) reactionCounts(raw map[string]string) map[string]int {
+	counts := make(map[string]int, len(s.reactions))
+	for _, r := range s.reactions {
+		counts[r] = 0
+	}
+	for reaction, v := range raw {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			counts[reaction] = n
+		}
+	}
+	return counts
+}
+
+func (s *MetricsService) publishReacted(ctx context.Context, userID, postID int, reaction, previous string) {
+	counts, _, err := s.GetReactions(ctx, postID, 0)
+	if err != nil {
+		s.logger.Error("failed to read reactions for event", err, nil)
+	}
+
+	event := PostReactedEvent{
+		PostID:    postID,
+		UserID:    userID,
+		Reaction:  reaction,
+		Previous:  previous,
+		Reactions: counts,
+	}
+	if err := s.publishEvent("post.reacted", event); err != nil {
+		s.logger.Error("failed to publish post.reacted event", err, nil)
+	}
+}
+
+// PostMetrics — метрики поста из Redis. HasLikes/HasViews равны false, если ключа нет,
+// и тогда вызывающий оставляет значения из PostgreSQL.
+type PostMetrics struct {
+	Likes      int
+	Views      int
+	Unique     int
+	HasLikes   bool
+	HasViews   bool
+	Liked      bool
+	Reactions  map[string]int
+	MyReaction string
+}
+
+// GetMetricsBatch возвращает метрики набора постов за один pipeline-запрос:
+// лайки и просмотры через MGET, отметки лайков viewerID и реакции (viewerID == 0 — аноним)
+func (s *MetricsService) GetMetricsBatch(ctx context.Context, ids []int, viewerID int) (map[int]*PostMetrics, error) {
+	result := make(map[int]*PostMetrics, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	counterKeys := make([]string, 0, 2*len(ids))
+	for _, id := range ids {
+		counterKeys = append(counterKeys, fmt.Sprintf(keyPostLikes, id), fmt.Sprintf(keyPostViews, id))
+	}
+
+	pipe := s.redis.Pipeline()
+	countersCmd := pipe.MGet(ctx, counterKeys...)
+
+	var likedCmd *redis.SliceCmd
+	mineCmds := make([]*redis.StringCmd, len(ids))
+	if viewerID > 0 {
+		likedKeys := make([]string, len(ids))
+		for i, id := range ids {
+			likedKeys[i] = fmt.Sprintf(keyUserLikedPost, viewerID, id)
+			mineCmds[i] = pipe.HGet(ctx, fmt.Sprintf(keyPostReactors, id), strconv.Itoa(viewerID))
+		}
+		likedCmd = pipe.MGet(ctx, likedKeys...)
+	}
+
+	reactionCmds := make([]*redis.MapStringStringCmd, len(ids))
+	uniqueCmds := make([]*redis.IntCmd, len(ids))
+	for i, id := range ids {
+		reactionCmds[i] = pipe.HGetAll(ctx, fmt.Sprintf(keyPostReactions, id))
+		uniqueCmds[i] = pipe.PFCount(ctx, fmt.Sprintf(keyPostUniq, id))
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to get metrics batch: %w", err)
+	}
+
+	counters := countersCmd.Val()
+	var liked []interface{}
+	if likedCmd != nil {
+		liked = likedCmd.Val()
+	}
+
+	for i, id := range ids {
+		m := &PostMetrics{
+			Unique:    int(uniqueCmds[i].Val()),
+			Reactions: s.reactionCounts(reactionCmds[i].Val()),
+		}
+		m.Likes, m.HasLikes = parseCounter(counters[2*i])
+		m.Views, m.HasViews = parseCounter(counters[2*i+1])
+		if liked != nil {
+			m.Liked = liked[i] != nil
+		}
+		if mineCmds[i] != nil && mineCmds[i].Err() == nil {
+			m.MyReaction = mineCmds[i].Val()
+		}
+		result[id] = m
+	}
+
+	return result, nil
+}
+
+func parseCounter(v interface{}) (int, bool) {
+	str, ok := v.(string)
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(str)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
 }
 
 func (s *MetricsService) publishEvent(topic string, event interface{}) error {
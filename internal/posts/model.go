@@ -1,20 +1,98 @@
 package posts
 
 import (
+	"mpb/pkg/markdown"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 type Tag string
 
+const (
+	StatusDraft     = "draft"
+	StatusScheduled = "scheduled"
+	StatusPublished = "published"
+	StatusArchived  = "archived"
+)
+
+// Post хранит Markdown-описание; DescriptionHTML, Excerpt и WordCount вычисляются из него при записи
 type Post struct {
-	ID           int        `db:"id"`
-	UserID       int        `db:"user_id"`
-	Title        string     `db:"title"`
-	Description  string     `db:"description"`
-	Tag          string     `db:"tag"`
-	Like         int        `db:"like"`
-	CountViewers int        `db:"count_viewers"`
-	CreatedAt    time.Time  `db:"created_at"`
-	UpdatedAt    time.Time  `db:"updated_at"`
-	DeletedAt    *time.Time `db:"deleted_at"`
+	ID              int            `db:"id"`
+	UserID          int            `db:"user_id"`
+	Title           string         `db:"title"`
+	Description     string         `db:"description"`
+	DescriptionHTML string         `db:"description_html"`
+	Excerpt         string         `db:"excerpt"`
+	WordCount       int            `db:"word_count"`
+	Tag             string         `db:"tag"`
+	Like            int            `db:"like"`
+	CountViewers    int            `db:"count_viewers"`
+	CommentsCount   int            `db:"comments_count"`
+	Status          string         `db:"status"`
+	PublishAt       *time.Time     `db:"publish_at"`
+	CreatedAt       time.Time      `db:"created_at"`
+	UpdatedAt       time.Time      `db:"updated_at"`
+	DeletedAt       *time.Time     `db:"deleted_at"`
+	Tags            []string       `db:"-"`
+	UniqueViewers   int            `db:"-"`
+	LikedByMe       bool           `db:"-"`
+	Reactions       map[string]int `db:"-"`
+	MyReaction      string         `db:"-"`
+}
+
+// ReadingTimeMinutes возвращает оценку времени чтения описания
+func (p *Post) ReadingTimeMinutes() int {
+	return markdown.ReadingTime(p.WordCount)
+}
+
+// IsVisibleTo сообщает, может ли пользователь видеть пост: неопубликованные посты видит только автор
+func (p *Post) IsVisibleTo(viewerID int) bool {
+	return p.Status == StatusPublished || p.UserID == viewerID
+}
+
+type PostSearchResult struct {
+	Post
+	Rank           float64 `db:"rank"`
+	TitleHighlight string  `db:"title_highlight"`
+	Snippet        string  `db:"snippet"`
+}
+
+type PostRevision struct {
+	ID          int            `db:"id"`
+	PostID      int            `db:"post_id"`
+	Revision    int            `db:"revision"`
+	EditorID    *int           `db:"editor_id"`
+	Title       string         `db:"title"`
+	Description string         `db:"description"`
+	Tag         string         `db:"tag"`
+	Tags        pq.StringArray `db:"tags"`
+	CreatedAt   time.Time      `db:"created_at"`
+}
+
+type PostLike struct {
+	PostID    int       `db:"post_id"`
+	UserID    int       `db:"user_id"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// PostCounters — счётчики поста в PostgreSQL; LikeRows — фактическое число строк в post_likes
+type PostCounters struct {
+	PostID   int `db:"id"`
+	Likes    int `db:"like"`
+	Views    int `db:"count_viewers"`
+	LikeRows int `db:"like_rows"`
+}
+
+// PostLiker — пользователь, лайкнувший пост
+type PostLiker struct {
+	UserID   int       `db:"user_id"`
+	Username string    `db:"username"`
+	LikedAt  time.Time `db:"liked_at"`
+}
+
+// LikedPost — пост из списка лайков пользователя
+type LikedPost struct {
+	Post
+	LikedAt time.Time `db:"liked_at"`
 }
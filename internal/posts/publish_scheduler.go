@@ -0,0 +1,51 @@
+package posts
+
+import (
+	"context"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+)
+
+// PublishScheduler периодически публикует запланированные посты
+type PublishScheduler struct {
+	service  *PostsService
+	interval time.Duration
+	logger   watermill.LoggerAdapter
+}
+
+func NewPublishScheduler(service *PostsService, interval time.Duration, logger watermill.LoggerAdapter) *PublishScheduler {
+	return &PublishScheduler{
+		service:  service,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Start запускает планировщик в отдельной горутине до отмены ctx
+func (s *PublishScheduler) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.tick(ctx)
+			}
+		}
+	}()
+}
+
+func (s *PublishScheduler) tick(ctx context.Context) {
+	published, err := s.service.PublishDuePosts(ctx)
+	if err != nil {
+		s.logger.Error("failed to publish scheduled posts", err, nil)
+		return
+	}
+	if published > 0 {
+		s.logger.Info("published scheduled posts", watermill.LogFields{"count": published})
+	}
+}
@@ -0,0 +1,55 @@
+package posts
+
+import (
+	"context"
+	"mpb/pkg/errors_constant"
+)
+
+// ReactionSummary — счётчики реакций на пост и реакция текущего пользователя
+type ReactionSummary struct {
+	Counts map[string]int
+	Mine   string
+}
+
+// ReactToPost ставит или меняет реакцию пользователя на опубликованный пост
+func (s *PostsService) ReactToPost(ctx context.Context, postID, userID int, reaction string) (*ReactionSummary, error) {
+	if !s.metricsService.IsAllowedReaction(reaction) {
+		return nil, errors_constant.InvalidReaction
+	}
+	if _, err := s.checkReactable(ctx, postID); err != nil {
+		return nil, err
+	}
+
+	if err := s.metricsService.SetReaction(ctx, userID, postID, reaction); err != nil {
+		return nil, err
+	}
+	return s.reactionSummary(ctx, postID, userID)
+}
+
+// RemoveReaction снимает реакцию пользователя с поста
+func (s *PostsService) RemoveReaction(ctx context.Context, postID, userID int) (*ReactionSummary, error) {
+	if _, err := s.checkReactable(ctx, postID); err != nil {
+		return nil, err
+	}
+
+	if err := s.metricsService.RemoveReaction(ctx, userID, postID); err != nil {
+		return nil, err
+	}
+	return s.reactionSummary(ctx, postID, userID)
+}
+
+func (s *PostsService) checkReactable(ctx context.Context, postID int) (*Post, error) {
+	post, err := s.repo.FindByID(ctx, postID)
+	if err != nil || post.Status != StatusPublished {
+		return nil, errors_constant.PostNotFound
+	}
+	return post, nil
+}
+
+func (s *PostsService) reactionSummary(ctx context.Context, postID, userID int) (*ReactionSummary, error) {
+	counts, mine, err := s.metricsService.GetReactions(ctx, postID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &ReactionSummary{Counts: counts, Mine: mine}, nil
+}
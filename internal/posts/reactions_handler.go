@@ -0,0 +1,91 @@
+package posts
+
+import (
+	"errors"
+	"mpb/internal/posts/dto"
+	"mpb/pkg/errors_constant"
+	"mpb/pkg/middleware"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// SetReaction godoc
+// @Summary React to a post
+// @Description Each user holds one reaction per post; sending another one replaces it.
+// @Tags Posts
+// @Accept json
+// @Produce json
+// @Param id path int true "Post ID"
+// @Param request body dto.ReactionRequest true "Reaction"
+// @Success 200 {object} dto.PostReactionsResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/posts/{id}/reactions [put]
+func (h *PostsHandlers) SetReaction(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid post id"})
+	}
+
+	req := middleware.Body[dto.ReactionRequest](c)
+	if req == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+	}
+
+	userID, ok := c.Locals("user_id").(int)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "user not authenticated"})
+	}
+
+	summary, err := h.service.ReactToPost(c.Context(), id, userID, req.Reaction)
+	if err != nil {
+		return reactionError(c, err)
+	}
+	return c.JSON(reactionsToResponse(id, summary))
+}
+
+// RemoveReaction godoc
+// @Summary Remove own reaction from a post
+// @Tags Posts
+// @Produce json
+// @Param id path int true "Post ID"
+// @Success 200 {object} dto.PostReactionsResponse
+// @Failure 404 {object} map[string]string
+// @Router /api/posts/{id}/reactions [delete]
+func (h *PostsHandlers) RemoveReaction(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid post id"})
+	}
+
+	userID, ok := c.Locals("user_id").(int)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "user not authenticated"})
+	}
+
+	summary, err := h.service.RemoveReaction(c.Context(), id, userID)
+	if err != nil {
+		return reactionError(c, err)
+	}
+	return c.JSON(reactionsToResponse(id, summary))
+}
+
+func reactionError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, errors_constant.InvalidReaction):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, errors_constant.PostNotFound), errors.Is(err, errors_constant.ReactionNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+}
+
+func reactionsToResponse(postID int, summary *ReactionSummary) dto.PostReactionsResponse {
+	return dto.PostReactionsResponse{
+		PostID:     postID,
+		Reactions:  summary.Counts,
+		MyReaction: summary.Mine,
+	}
+}
@@ -0,0 +1,147 @@
+package posts
+
+import (
+	"context"
+	"fmt"
+)
+
+const reconcileBatchSize = 500
+
+// MetricsDrift — расхождение счётчиков поста между Redis и PostgreSQL и значения, к которым их приводит сверка
+type MetricsDrift struct {
+	PostID        int
+	LikeRows      int
+	DBLikes       int
+	RedisLikes    int
+	HasRedisLikes bool
+	DBViews       int
+	RedisViews    int
+	HasRedisViews bool
+	Likes         int
+	Views         int
+}
+
+// ReconcileReport — итог прохода сверки
+type ReconcileReport struct {
+	Scanned       int
+	Drifted       int
+	RedisRepaired int // исправленных ключей Redis
+	DBRepaired    int // исправленных строк posts
+}
+
+// reconcileCounters сравнивает счётчики поста по правилу приоритета:
+//   - лайки: эталон — число строк в post_likes, к нему приводятся и Redis, и posts."like";
+//   - просмотры: счётчик только растёт, поэтому верным считается большее из значений Redis и posts.count_viewers.
+//
+// Отсутствующий ключ Redis тоже считается расхождением.
+func reconcileCounters(c PostCounters, m *PostMetrics) (MetricsDrift, bool) {
+	if m == nil {
+		m = &PostMetrics{}
+	}
+
+	d := MetricsDrift{
+		PostID:        c.PostID,
+		LikeRows:      c.LikeRows,
+		DBLikes:       c.Likes,
+		RedisLikes:    m.Likes,
+		HasRedisLikes: m.HasLikes,
+		DBViews:       c.Views,
+		RedisViews:    m.Views,
+		HasRedisViews: m.HasViews,
+		Likes:         c.LikeRows,
+		Views:         c.Views,
+	}
+	if m.HasViews && m.Views > d.Views {
+		d.Views = m.Views
+	}
+
+	return d, d.dbStale() || d.redisLikesStale() || d.redisViewsStale()
+}
+
+func (d MetricsDrift) dbStale() bool {
+	return d.DBLikes != d.Likes || d.DBViews != d.Views
+}
+
+func (d MetricsDrift) redisLikesStale() bool {
+	return !d.HasRedisLikes || d.RedisLikes != d.Likes
+}
+
+func (d MetricsDrift) redisViewsStale() bool {
+	return !d.HasRedisViews || d.RedisViews < d.Views
+}
+
+// ReconcileMetrics обходит посты пачками и сверяет счётчики в Redis и PostgreSQL (см. reconcileCounters).
+// При dryRun расхождения только передаются в onDrift (может быть nil), без исправления.
+func (s *PostsService) ReconcileMetrics(ctx context.Context, dryRun bool, onDrift func(MetricsDrift)) (ReconcileReport, error) {
+	var report ReconcileReport
+
+	if !dryRun {
+		if err := s.WarmLikeCache(ctx); err != nil {
+			return report, fmt.Errorf("failed to restore likes cache: %w", err)
+		}
+	}
+
+	afterID := 0
+	for {
+		counters, err := s.repo.CountersBatch(ctx, afterID, reconcileBatchSize)
+		if err != nil {
+			return report, err
+		}
+		if len(counters) == 0 {
+			break
+		}
+
+		ids := make([]int, len(counters))
+		for i, c := range counters {
+			ids[i] = c.PostID
+		}
+		metrics, err := s.metricsService.GetCounters(ctx, ids)
+		if err != nil {
+			return report, err
+		}
+
+		var dbFix []PostCounters
+		likesFix := make(map[int]int)
+		viewsFix := make(map[int]int)
+		for _, c := range counters {
+			d, drifted := reconcileCounters(c, metrics[c.PostID])
+			if !drifted {
+				continue
+			}
+			report.Drifted++
+			if onDrift != nil {
+				onDrift(d)
+			}
+
+			if d.dbStale() {
+				dbFix = append(dbFix, PostCounters{PostID: d.PostID, Likes: d.Likes, Views: d.Views})
+			}
+			if d.redisLikesStale() {
+				likesFix[d.PostID] = d.Likes
+			}
+			if d.redisViewsStale() {
+				viewsFix[d.PostID] = d.Views
+			}
+		}
+		report.Scanned += len(counters)
+
+		if !dryRun {
+			if err := s.repo.SetCounters(ctx, dbFix); err != nil {
+				return report, err
+			}
+			report.DBRepaired += len(dbFix)
+
+			if err := s.metricsService.RepairCounters(ctx, likesFix, viewsFix); err != nil {
+				return report, err
+			}
+			report.RedisRepaired += len(likesFix) + len(viewsFix)
+		}
+
+		afterID = counters[len(counters)-1].PostID
+		if len(counters) < reconcileBatchSize {
+			break
+		}
+	}
+
+	return report, nil
+}
@@ -2,24 +2,38 @@ package posts
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"mpb/pkg/db"
+	"mpb/pkg/errors_constant"
+	"mpb/pkg/slug"
 	"strings"
 	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type PostFilter struct {
+	IDs        []int
 	UserID     *int
 	Tag        *string
 	Title      *string
 	FromDate   *time.Time
 	ToDate     *time.Time
 	OnlyActive bool
+	Statuses   []string
 	Limit      int
 	Offset     int
 	OrderBy    string
+	Cursor     *PostCursor
+	// ViewerID не влияет на выборку, а задаёт пользователя для персональных метрик (0 — аноним)
+	ViewerID int
 }
 
+const postColumns = `id, user_id, title, description, description_html, excerpt, word_count, tag, "like", count_viewers, comments_count, status, publish_at, created_at, updated_at, deleted_at`
+
 type PostsRepository struct {
 	db *db.Db
 }
@@ -28,31 +42,44 @@ func NewPostsRepository(db *db.Db) *PostsRepository {
 	return &PostsRepository{db: db}
 }
 
-func (r *PostsRepository) Save(ctx context.Context, post *Post) error {
+// Save создаёт пост вместе с тегами и первой ревизией в одной транзакции
+func (r *PostsRepository) Save(ctx context.Context, post *Post, tags []string, editorID int) error {
 	const query = `
-		INSERT INTO posts (user_id, title, description, tag, "like", count_viewers)
-		VALUES (:user_id, :title, :description, :tag, :like, :count_viewers)
+		INSERT INTO posts (user_id, title, description, description_html, excerpt, word_count, tag, "like", count_viewers, status, publish_at)
+		VALUES (:user_id, :title, :description, :description_html, :excerpt, :word_count, :tag, :like, :count_viewers, :status, :publish_at)
 		RETURNING id, created_at, updated_at
 	`
 
-	rows, err := r.db.Conn.NamedQueryContext(ctx, query, post)
+	tx, err := r.db.Conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	bound, args, err := tx.BindNamed(query, post)
 	if err != nil {
+		return fmt.Errorf("failed to bind post: %w", err)
+	}
+	if err := tx.QueryRowxContext(ctx, bound, args...).Scan(&post.ID, &post.CreatedAt, &post.UpdatedAt); err != nil {
 		return fmt.Errorf("failed to insert post: %w", err)
 	}
-	defer rows.Close()
 
-	if rows.Next() {
-		if err := rows.Scan(&post.ID, &post.CreatedAt, &post.UpdatedAt); err != nil {
-			return fmt.Errorf("failed to scan inserted post: %w", err)
-		}
+	if err := setTags(ctx, tx, post.ID, tags); err != nil {
+		return err
+	}
+	if err := saveRevision(ctx, tx, post.ID, editorID); err != nil {
+		return err
 	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit post: %w", err)
+	}
 	return nil
 }
 
 func (r *PostsRepository) FindByID(ctx context.Context, postID int) (*Post, error) {
 	var post Post
-	const query = `SELECT * FROM posts WHERE id = $1 AND deleted_at IS NULL`
+	const query = `SELECT ` + postColumns + ` FROM posts WHERE id = $1 AND deleted_at IS NULL`
 	if err := r.db.Conn.GetContext(ctx, &post, query, postID); err != nil {
 		return nil, fmt.Errorf("failed to find post by id: %w", err)
 	}
@@ -77,49 +104,126 @@ func (r *PostsRepository) Delete(ctx context.Context, postID int) error {
 	return nil
 }
 
-func (r *PostsRepository) Update(ctx context.Context, post *Post) error {
+// Update сохраняет содержимое поста, теги (если tags != nil) и новую ревизию в одной транзакции.
+// Строка поста блокируется до коммита, поэтому параллельные правки получают разные номера ревизий.
+func (r *PostsRepository) Update(ctx context.Context, post *Post, tags []string, editorID int) error {
 	const query = `
 		UPDATE posts
 		SET title = :title,
 		    description = :description,
+		    description_html = :description_html,
+		    excerpt = :excerpt,
+		    word_count = :word_count,
 		    tag = :tag,
-		    "like" = :like,
-		    count_viewers = :count_viewers,
 		    updated_at = NOW()
 		WHERE id = :id AND deleted_at IS NULL
 		RETURNING updated_at
 	`
 
-	rows, err := r.db.Conn.NamedQueryContext(ctx, query, post)
+	tx, err := r.db.Conn.BeginTxx(ctx, nil)
 	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var locked int
+	const lock = `SELECT id FROM posts WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`
+	if err := tx.GetContext(ctx, &locked, lock, post.ID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors_constant.PostNotFound
+		}
+		return fmt.Errorf("failed to lock post: %w", err)
+	}
+
+	bound, args, err := tx.BindNamed(query, post)
+	if err != nil {
+		return fmt.Errorf("failed to bind post: %w", err)
+	}
+	if err := tx.QueryRowxContext(ctx, bound, args...).Scan(&post.UpdatedAt); err != nil {
 		return fmt.Errorf("failed to update post: %w", err)
 	}
-	defer rows.Close()
 
-	if rows.Next() {
-		if err := rows.Scan(&post.UpdatedAt); err != nil {
-			return fmt.Errorf("failed to scan updated post: %w", err)
+	if tags != nil {
+		if err := setTags(ctx, tx, post.ID, tags); err != nil {
+			return err
 		}
 	}
+	if err := saveRevision(ctx, tx, post.ID, editorID); err != nil {
+		return err
+	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit post update: %w", err)
+	}
 	return nil
 }
 
+// UpdateStatus меняет статус поста и время публикации, не трогая содержимое
+func (r *PostsRepository) UpdateStatus(ctx context.Context, post *Post) error {
+	const query = `
+		UPDATE posts
+		SET status = $1, publish_at = $2
+		WHERE id = $3 AND deleted_at IS NULL
+		RETURNING updated_at
+	`
+
+	if err := r.db.Conn.QueryRowContext(ctx, query, post.Status, post.PublishAt, post.ID).Scan(&post.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to update post status: %w", err)
+	}
+	return nil
+}
+
+// PublishDue публикует запланированные посты, у которых наступило publish_at.
+// SKIP LOCKED позволяет запускать планировщик на нескольких инстансах.
+func (r *PostsRepository) PublishDue(ctx context.Context, now time.Time, limit int) ([]Post, error) {
+	const query = `
+		UPDATE posts
+		SET status = 'published'
+		WHERE id IN (
+			SELECT id FROM posts
+			WHERE status = 'scheduled' AND publish_at <= $1 AND deleted_at IS NULL
+			ORDER BY publish_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + postColumns
+
+	var posts []Post
+	if err := r.db.Conn.SelectContext(ctx, &posts, query, now, limit); err != nil {
+		return nil, fmt.Errorf("failed to publish scheduled posts: %w", err)
+	}
+	return posts, nil
+}
+
 func (r *PostsRepository) List(ctx context.Context, f PostFilter) ([]Post, error) {
-	query := `SELECT * FROM posts WHERE 1=1`
+	query := `SELECT ` + postColumns + ` FROM posts WHERE 1=1`
 	var args []interface{}
 
 	if f.OnlyActive {
 		query += ` AND deleted_at IS NULL`
+		if len(f.Statuses) == 0 {
+			query += ` AND status = 'published'`
+		}
+	}
+	if len(f.Statuses) > 0 {
+		args = append(args, pq.Array(f.Statuses))
+		query += fmt.Sprintf(" AND status = ANY($%d)", len(args))
 	}
 
+	if len(f.IDs) > 0 {
+		args = append(args, pq.Array(f.IDs))
+		query += fmt.Sprintf(" AND id = ANY($%d)", len(args))
+	}
 	if f.UserID != nil {
 		args = append(args, *f.UserID)
 		query += fmt.Sprintf(" AND user_id = $%d", len(args))
 	}
 	if f.Tag != nil {
-		args = append(args, *f.Tag)
-		query += fmt.Sprintf(" AND tag = $%d", len(args))
+		args = append(args, slug.Make(*f.Tag))
+		query += fmt.Sprintf(` AND id IN (
+			SELECT pt.post_id FROM post_tags pt
+			JOIN tags t ON pt.tag_id = COALESCE(t.alias_of, t.id)
+			WHERE t.slug = $%d)`, len(args))
 	}
 	if f.Title != nil {
 		args = append(args, "%"+*f.Title+"%")
@@ -134,22 +238,25 @@ func (r *PostsRepository) List(ctx context.Context, f PostFilter) ([]Post, error
 		query += fmt.Sprintf(" AND created_at <= $%d", len(args))
 	}
 
-	orderBy := "created_at DESC"
-	if f.OrderBy != "" {
-		validOrderColumns := map[string]bool{
-			"created_at": true, "updated_at": true, "like": true, "count_viewers": true,
-		}
-		if validOrderColumns[strings.Split(f.OrderBy, " ")[0]] {
-			orderBy = f.OrderBy
-		}
+	column, desc := parsePostOrder(f.OrderBy)
+	orderExpr := postOrderColumns[column]
+	direction, op := "ASC", ">"
+	if desc {
+		direction, op = "DESC", "<"
 	}
-	query += fmt.Sprintf(" ORDER BY %s", orderBy)
+
+	if f.Cursor != nil {
+		args = append(args, f.Cursor.value(), f.Cursor.ID)
+		query += fmt.Sprintf(" AND (%s, id) %s ($%d, $%d)", orderExpr, op, len(args)-1, len(args))
+	}
+
+	query += fmt.Sprintf(" ORDER BY %s %s, id %s", orderExpr, direction, direction)
 
 	if f.Limit > 0 {
 		args = append(args, f.Limit)
 		query += fmt.Sprintf(" LIMIT $%d", len(args))
 	}
-	if f.Offset > 0 {
+	if f.Offset > 0 && f.Cursor == nil {
 		args = append(args, f.Offset)
 		query += fmt.Sprintf(" OFFSET $%d", len(args))
 	}
@@ -161,3 +268,368 @@ func (r *PostsRepository) List(ctx context.Context, f PostFilter) ([]Post, error
 
 	return posts, nil
 }
+
+// setTags заменяет теги поста внутри транзакции. Новые теги создаются, синонимы заменяются каноническими тегами.
+func setTags(ctx context.Context, tx *sqlx.Tx, postID int, names []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM post_tags WHERE post_id = $1`, postID); err != nil {
+		return fmt.Errorf("failed to clear post tags: %w", err)
+	}
+
+	for _, name := range names {
+		var tagID int
+		const upsertTag = `
+			INSERT INTO tags (name, slug) VALUES ($1, $2)
+			ON CONFLICT (slug) DO UPDATE SET slug = EXCLUDED.slug
+			RETURNING COALESCE(alias_of, id)
+		`
+		if err := tx.GetContext(ctx, &tagID, upsertTag, name, slug.Make(name)); err != nil {
+			return fmt.Errorf("failed to upsert tag: %w", err)
+		}
+
+		const insertPostTag = `INSERT INTO post_tags (post_id, tag_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+		if _, err := tx.ExecContext(ctx, insertPostTag, postID, tagID); err != nil {
+			return fmt.Errorf("failed to attach tag: %w", err)
+		}
+	}
+	return nil
+}
+
+// TagsByPostIDs возвращает slug'и тегов для набора постов одним запросом
+func (r *PostsRepository) TagsByPostIDs(ctx context.Context, postIDs []int) (map[int][]string, error) {
+	const query = `
+		SELECT pt.post_id, t.slug
+		FROM post_tags pt
+		JOIN tags t ON t.id = pt.tag_id
+		WHERE pt.post_id = ANY($1)
+		ORDER BY pt.post_id, t.slug
+	`
+
+	rows, err := r.db.Conn.QueryContext(ctx, query, pq.Array(postIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load post tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := make(map[int][]string, len(postIDs))
+	for rows.Next() {
+		var postID int
+		var tag string
+		if err := rows.Scan(&postID, &tag); err != nil {
+			return nil, fmt.Errorf("failed to scan post tag: %w", err)
+		}
+		tags[postID] = append(tags[postID], tag)
+	}
+
+	return tags, rows.Err()
+}
+
+// AddLike записывает лайк и пересчитывает posts.like по таблице post_likes
+func (r *PostsRepository) AddLike(ctx context.Context, postID, userID int) error {
+	return r.changeLike(ctx, `INSERT INTO post_likes (post_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, postID, userID)
+}
+
+// RemoveLike удаляет лайк и пересчитывает posts.like по таблице post_likes
+func (r *PostsRepository) RemoveLike(ctx context.Context, postID, userID int) error {
+	return r.changeLike(ctx, `DELETE FROM post_likes WHERE post_id = $1 AND user_id = $2`, postID, userID)
+}
+
+func (r *PostsRepository) changeLike(ctx context.Context, query string, postID, userID int) error {
+	tx, err := r.db.Conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, query, postID, userID); err != nil {
+		return fmt.Errorf("failed to change like: %w", err)
+	}
+
+	const recount = `UPDATE posts SET "like" = (SELECT COUNT(*) FROM post_likes WHERE post_id = $1) WHERE id = $1`
+	if _, err := tx.ExecContext(ctx, recount, postID); err != nil {
+		return fmt.Errorf("failed to recount likes: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit like: %w", err)
+	}
+	return nil
+}
+
+// ListLikers возвращает пользователей, лайкнувших пост, начиная с последних
+func (r *PostsRepository) ListLikers(ctx context.Context, postID int, cursor *LikeCursor, limit int) ([]PostLiker, error) {
+	query := `
+		SELECT pl.user_id, u.username, pl.created_at AS liked_at
+		FROM post_likes pl
+		JOIN users u ON u.id = pl.user_id
+		WHERE pl.post_id = $1`
+	args := []interface{}{postID}
+
+	if cursor != nil {
+		args = append(args, cursor.Time, cursor.ID)
+		query += fmt.Sprintf(" AND (pl.created_at, pl.user_id) < ($%d, $%d)", len(args)-1, len(args))
+	}
+
+	args = append(args, limit)
+	query += fmt.Sprintf(" ORDER BY pl.created_at DESC, pl.user_id DESC LIMIT $%d", len(args))
+
+	var likers []PostLiker
+	if err := r.db.Conn.SelectContext(ctx, &likers, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list likers: %w", err)
+	}
+	return likers, nil
+}
+
+// ListLikedPosts возвращает опубликованные посты, которые лайкнул пользователь, начиная с последних лайков
+func (r *PostsRepository) ListLikedPosts(ctx context.Context, userID int, cursor *LikeCursor, limit int) ([]LikedPost, error) {
+	query := `
+		SELECT ` + postColumns + `, liked_at FROM (
+			SELECT p.*, pl.created_at AS liked_at
+			FROM post_likes pl
+			JOIN posts p ON p.id = pl.post_id
+			WHERE pl.user_id = $1 AND p.deleted_at IS NULL AND p.status = 'published'
+		) liked WHERE 1=1`
+	args := []interface{}{userID}
+
+	if cursor != nil {
+		args = append(args, cursor.Time, cursor.ID)
+		query += fmt.Sprintf(" AND (liked_at, id) < ($%d, $%d)", len(args)-1, len(args))
+	}
+
+	args = append(args, limit)
+	query += fmt.Sprintf(" ORDER BY liked_at DESC, id DESC LIMIT $%d", len(args))
+
+	var posts []LikedPost
+	if err := r.db.Conn.SelectContext(ctx, &posts, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list liked posts: %w", err)
+	}
+	return posts, nil
+}
+
+// LikesBatch читает лайки в порядке (post_id, user_id) после указанной пары, для восстановления кэша
+func (r *PostsRepository) LikesBatch(ctx context.Context, afterPostID, afterUserID, limit int) ([]PostLike, error) {
+	const query = `
+		SELECT post_id, user_id, created_at FROM post_likes
+		WHERE (post_id, user_id) > ($1, $2)
+		ORDER BY post_id, user_id
+		LIMIT $3
+	`
+
+	var likes []PostLike
+	if err := r.db.Conn.SelectContext(ctx, &likes, query, afterPostID, afterUserID, limit); err != nil {
+		return nil, fmt.Errorf("failed to read likes: %w", err)
+	}
+	return likes, nil
+}
+
+// ImportLikes переносит лайки в post_likes, пропуская удалённые посты и пользователей
+func (r *PostsRepository) ImportLikes(ctx context.Context, likes []PostLike) error {
+	postIDs := make([]int64, len(likes))
+	userIDs := make([]int64, len(likes))
+	for i := range likes {
+		postIDs[i] = int64(likes[i].PostID)
+		userIDs[i] = int64(likes[i].UserID)
+	}
+
+	tx, err := r.db.Conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	const insert = `
+		INSERT INTO post_likes (post_id, user_id)
+		SELECT l.post_id, l.user_id
+		FROM unnest($1::int[], $2::int[]) AS l(post_id, user_id)
+		JOIN posts p ON p.id = l.post_id
+		JOIN users u ON u.id = l.user_id
+		ON CONFLICT DO NOTHING
+	`
+	if _, err := tx.ExecContext(ctx, insert, pq.Array(postIDs), pq.Array(userIDs)); err != nil {
+		return fmt.Errorf("failed to import likes: %w", err)
+	}
+
+	const recount = `
+		UPDATE posts SET "like" = (SELECT COUNT(*) FROM post_likes pl WHERE pl.post_id = posts.id)
+		WHERE id = ANY($1)
+	`
+	if _, err := tx.ExecContext(ctx, recount, pq.Array(postIDs)); err != nil {
+		return fmt.Errorf("failed to recount likes: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit imported likes: %w", err)
+	}
+	return nil
+}
+
+// CountersBatch читает счётчики неудалённых постов с id больше afterID вместе с числом строк post_likes
+func (r *PostsRepository) CountersBatch(ctx context.Context, afterID, limit int) ([]PostCounters, error) {
+	const query = `
+		SELECT p.id, p."like", p.count_viewers,
+			(SELECT COUNT(*) FROM post_likes pl WHERE pl.post_id = p.id) AS like_rows
+		FROM posts p
+		WHERE p.id > $1 AND p.deleted_at IS NULL
+		ORDER BY p.id
+		LIMIT $2
+	`
+
+	var counters []PostCounters
+	if err := r.db.Conn.SelectContext(ctx, &counters, query, afterID, limit); err != nil {
+		return nil, fmt.Errorf("failed to read post counters: %w", err)
+	}
+	return counters, nil
+}
+
+// SetCounters перезаписывает колонки "like" и count_viewers одним запросом
+func (r *PostsRepository) SetCounters(ctx context.Context, counters []PostCounters) error {
+	if len(counters) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(counters))
+	likes := make([]int64, len(counters))
+	views := make([]int64, len(counters))
+	for i, c := range counters {
+		ids[i], likes[i], views[i] = int64(c.PostID), int64(c.Likes), int64(c.Views)
+	}
+
+	const query = `
+		UPDATE posts p SET "like" = v.likes, count_viewers = v.views
+		FROM unnest($1::int[], $2::int[], $3::int[]) AS v(id, likes, views)
+		WHERE p.id = v.id
+	`
+	if _, err := r.db.Conn.ExecContext(ctx, query, pq.Array(ids), pq.Array(likes), pq.Array(views)); err != nil {
+		return fmt.Errorf("failed to set post counters: %w", err)
+	}
+	return nil
+}
+
+// AddViews прибавляет накопленные приросты просмотров одним UPDATE ... FROM (VALUES ...).
+// Меняется только count_viewers: updated_at и содержимое поста не затрагиваются.
+func (r *PostsRepository) AddViews(ctx context.Context, deltas map[int]int) error {
+	if len(deltas) == 0 {
+		return nil
+	}
+
+	values := make([]string, 0, len(deltas))
+	args := make([]interface{}, 0, 2*len(deltas))
+	for postID, delta := range deltas {
+		values = append(values, fmt.Sprintf("($%d::int, $%d::int)", len(args)+1, len(args)+2))
+		args = append(args, postID, delta)
+	}
+
+	query := `
+		UPDATE posts p SET count_viewers = p.count_viewers + v.delta
+		FROM (VALUES ` + strings.Join(values, ", ") + `) AS v(id, delta)
+		WHERE p.id = v.id
+	`
+	if _, err := r.db.Conn.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to add views: %w", err)
+	}
+	return nil
+}
+
+// UpsertReaction сохраняет текущую реакцию пользователя на пост
+func (r *PostsRepository) UpsertReaction(ctx context.Context, postID, userID int, reaction string) error {
+	const query = `
+		INSERT INTO post_reactions (post_id, user_id, reaction)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (post_id, user_id) DO UPDATE SET reaction = EXCLUDED.reaction, updated_at = NOW()
+	`
+	if _, err := r.db.Conn.ExecContext(ctx, query, postID, userID, reaction); err != nil {
+		return fmt.Errorf("failed to save reaction: %w", err)
+	}
+	return nil
+}
+
+func (r *PostsRepository) DeleteReaction(ctx context.Context, postID, userID int) error {
+	const query = `DELETE FROM post_reactions WHERE post_id = $1 AND user_id = $2`
+	if _, err := r.db.Conn.ExecContext(ctx, query, postID, userID); err != nil {
+		return fmt.Errorf("failed to delete reaction: %w", err)
+	}
+	return nil
+}
+
+// saveRevision записывает текущее состояние поста следующим номером ревизии.
+// Вызывается в транзакции, которая уже изменила пост: строка поста заблокирована, и MAX(revision) не гонится.
+func saveRevision(ctx context.Context, tx *sqlx.Tx, postID, editorID int) error {
+	const query = `
+		INSERT INTO post_revisions (post_id, revision, editor_id, title, description, tag, tags)
+		SELECT p.id,
+		       (SELECT COALESCE(MAX(revision), 0) + 1 FROM post_revisions WHERE post_id = p.id),
+		       $2, p.title, p.description, p.tag,
+		       COALESCE((SELECT array_agg(t.slug ORDER BY t.slug)
+		                 FROM post_tags pt JOIN tags t ON t.id = pt.tag_id
+		                 WHERE pt.post_id = p.id), '{}')
+		FROM posts p
+		WHERE p.id = $1
+	`
+
+	if _, err := tx.ExecContext(ctx, query, postID, editorID); err != nil {
+		return fmt.Errorf("failed to save post revision: %w", err)
+	}
+	return nil
+}
+
+func (r *PostsRepository) ListRevisions(ctx context.Context, postID int) ([]PostRevision, error) {
+	const query = `SELECT * FROM post_revisions WHERE post_id = $1 ORDER BY revision DESC`
+
+	var revisions []PostRevision
+	if err := r.db.Conn.SelectContext(ctx, &revisions, query, postID); err != nil {
+		return nil, fmt.Errorf("failed to list post revisions: %w", err)
+	}
+
+	return revisions, nil
+}
+
+func (r *PostsRepository) FindRevision(ctx context.Context, postID, revision int) (*PostRevision, error) {
+	var rev PostRevision
+	const query = `SELECT * FROM post_revisions WHERE post_id = $1 AND revision = $2`
+	if err := r.db.Conn.GetContext(ctx, &rev, query, postID, revision); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors_constant.RevisionNotFound
+		}
+		return nil, fmt.Errorf("failed to find post revision: %w", err)
+	}
+	return &rev, nil
+}
+
+// Границы подсветки в ts_headline — символы из области частного использования Unicode.
+// Из текста они вырезаются до подсветки, поэтому после экранирования HTML заменяются на <mark> только настоящие границы.
+const (
+	highlightStart = "\uE000"
+	highlightStop  = "\uE001"
+)
+
+var (
+	titleHeadlineOptions   = fmt.Sprintf(`StartSel="%s", StopSel="%s", HighlightAll=true`, highlightStart, highlightStop)
+	snippetHeadlineOptions = fmt.Sprintf(`StartSel="%s", StopSel="%s", MaxFragments=2, MaxWords=30, MinWords=10`, highlightStart, highlightStop)
+)
+
+// Search ищет посты по search_vector и возвращает их с рангом и подсвеченными фрагментами.
+// ts_headline считается только для строк текущей страницы.
+func (r *PostsRepository) Search(ctx context.Context, q string, limit, offset int) ([]PostSearchResult, error) {
+	const query = `
+		WITH q AS (SELECT websearch_to_tsquery('simple', $1) AS query),
+		ranked AS (
+			SELECT ` + postColumns + `, ts_rank_cd(search_vector, q.query) AS rank
+			FROM posts, q
+			WHERE deleted_at IS NULL AND status = 'published' AND search_vector @@ q.query
+			ORDER BY rank DESC, id DESC
+			LIMIT $2 OFFSET $3
+		)
+		SELECT ranked.*,
+		       ts_headline('simple', translate(ranked.title, $6, ''), q.query, $4) AS title_highlight,
+		       ts_headline('simple', translate(ranked.description, $6, ''), q.query, $5) AS snippet
+		FROM ranked, q
+		ORDER BY ranked.rank DESC, ranked.id DESC
+	`
+
+	var results []PostSearchResult
+	if err := r.db.Conn.SelectContext(ctx, &results, query, q, limit, offset,
+		titleHeadlineOptions, snippetHeadlineOptions, highlightStart+highlightStop); err != nil {
+		return nil, fmt.Errorf("failed to search posts: %w", err)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,119 @@
+package posts
+
+import (
+	"context"
+	"fmt"
+	"mpb/internal/user"
+	"mpb/pkg/diff"
+	"mpb/pkg/errors_constant"
+)
+
+type RevisionDiff struct {
+	From        *PostRevision
+	To          *PostRevision
+	Title       []diff.Line
+	Description []diff.Line
+	TagsAdded   []string
+	TagsRemoved []string
+}
+
+// ListRevisions возвращает историю правок поста, новые первыми.
+// Историю видят автор, модераторы и администраторы.
+func (s *PostsService) ListRevisions(ctx context.Context, viewerID int, role string, postID int) ([]PostRevision, error) {
+	if err := s.checkRevisionAccess(ctx, viewerID, role, postID); err != nil {
+		return nil, err
+	}
+
+	revisions, err := s.repo.ListRevisions(ctx, postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list revisions: %w", err)
+	}
+
+	return revisions, nil
+}
+
+func (s *PostsService) GetRevision(ctx context.Context, viewerID int, role string, postID, revision int) (*PostRevision, error) {
+	if err := s.checkRevisionAccess(ctx, viewerID, role, postID); err != nil {
+		return nil, err
+	}
+
+	rev, err := s.repo.FindRevision(ctx, postID, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	return rev, nil
+}
+
+func (s *PostsService) DiffRevisions(ctx context.Context, viewerID int, role string, postID, from, to int) (*RevisionDiff, error) {
+	fromRev, err := s.GetRevision(ctx, viewerID, role, postID, from)
+	if err != nil {
+		return nil, err
+	}
+
+	toRev, err := s.repo.FindRevision(ctx, postID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	added, removed := diffTags(fromRev.Tags, toRev.Tags)
+	return &RevisionDiff{
+		From:        fromRev,
+		To:          toRev,
+		Title:       diff.Lines(fromRev.Title, toRev.Title),
+		Description: diff.Lines(fromRev.Description, toRev.Description),
+		TagsAdded:   added,
+		TagsRemoved: removed,
+	}, nil
+}
+
+// RestoreRevision возвращает пост к содержимому старой ревизии. Восстановление записывается новой ревизией.
+func (s *PostsService) RestoreRevision(ctx context.Context, userID, postID, revision int) (*Post, error) {
+	post, err := s.repo.FindByID(ctx, postID)
+	if err != nil {
+		return nil, errors_constant.PostNotFound
+	}
+	if post.UserID != userID {
+		return nil, errors_constant.UserNotAuthorized
+	}
+
+	rev, err := s.repo.FindRevision(ctx, postID, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := append([]string{}, rev.Tags...)
+	return s.UpdatePost(ctx, userID, postID, rev.Title, rev.Description, tags)
+}
+
+func (s *PostsService) checkRevisionAccess(ctx context.Context, viewerID int, role string, postID int) error {
+	post, err := s.repo.FindByID(ctx, postID)
+	if err != nil {
+		return errors_constant.PostNotFound
+	}
+
+	if post.UserID != viewerID && role != user.RoleModerator && role != user.RoleAdmin {
+		return errors_constant.UserNotAuthorized
+	}
+	return nil
+}
+
+func diffTags(from, to []string) (added, removed []string) {
+	inFrom := make(map[string]bool, len(from))
+	for _, tag := range from {
+		inFrom[tag] = true
+	}
+	inTo := make(map[string]bool, len(to))
+	for _, tag := range to {
+		inTo[tag] = true
+		if !inFrom[tag] {
+			added = append(added, tag)
+		}
+	}
+	for _, tag := range from {
+		if !inTo[tag] {
+			removed = append(removed, tag)
+		}
+	}
+	return added, removed
+}
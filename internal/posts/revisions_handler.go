@@ -0,0 +1,207 @@
+package posts
+
+import (
+	"errors"
+	"mpb/internal/posts/dto"
+	"mpb/pkg/diff"
+	"mpb/pkg/errors_constant"
+	"mpb/pkg/middleware"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ListRevisions godoc
+// @Summary List post revisions
+// @Description Available to the author, moderators and admins. Descriptions are omitted; fetch a single revision for the full snapshot.
+// @Tags Posts
+// @Produce json
+// @Param id path int true "Post ID"
+// @Success 200 {array} dto.PostRevisionResponse
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/posts/{id}/revisions [get]
+func (h *PostsHandlers) ListRevisions(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid post id"})
+	}
+
+	userID, ok := c.Locals("user_id").(int)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "user not authenticated"})
+	}
+	role, _ := c.Locals("role").(string)
+
+	revisions, err := h.service.ListRevisions(c.Context(), userID, role, id)
+	if err != nil {
+		return revisionError(c, err)
+	}
+
+	response := make([]dto.PostRevisionResponse, len(revisions))
+	for i := range revisions {
+		response[i] = revisionToResponse(&revisions[i])
+		response[i].Description = ""
+	}
+	return c.JSON(response)
+}
+
+// GetRevision godoc
+// @Summary Get a post revision
+// @Tags Posts
+// @Produce json
+// @Param id path int true "Post ID"
+// @Param rev path int true "Revision number"
+// @Success 200 {object} dto.PostRevisionResponse
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/posts/{id}/revisions/{rev} [get]
+func (h *PostsHandlers) GetRevision(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid post id"})
+	}
+	rev, err := strconv.Atoi(c.Params("rev"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid revision"})
+	}
+
+	userID, ok := c.Locals("user_id").(int)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "user not authenticated"})
+	}
+	role, _ := c.Locals("role").(string)
+
+	revision, err := h.service.GetRevision(c.Context(), userID, role, id, rev)
+	if err != nil {
+		return revisionError(c, err)
+	}
+
+	return c.JSON(revisionToResponse(revision))
+}
+
+// DiffRevisions godoc
+// @Summary Line-level diff between two revisions
+// @Tags Posts
+// @Produce json
+// @Param id path int true "Post ID"
+// @Param from query int true "Base revision"
+// @Param to query int true "Compared revision"
+// @Success 200 {object} dto.PostRevisionDiffResponse
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/posts/{id}/revisions/diff [get]
+func (h *PostsHandlers) DiffRevisions(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid post id"})
+	}
+
+	query := middleware.Query[dto.RevisionDiffQuery](c)
+	if query == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid query parameters"})
+	}
+
+	userID, ok := c.Locals("user_id").(int)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "user not authenticated"})
+	}
+	role, _ := c.Locals("role").(string)
+
+	result, err := h.service.DiffRevisions(c.Context(), userID, role, id, query.From, query.To)
+	if err != nil {
+		return revisionError(c, err)
+	}
+
+	tagsAdded, tagsRemoved := result.TagsAdded, result.TagsRemoved
+	if tagsAdded == nil {
+		tagsAdded = []string{}
+	}
+	if tagsRemoved == nil {
+		tagsRemoved = []string{}
+	}
+
+	return c.JSON(dto.PostRevisionDiffResponse{
+		PostID:      id,
+		From:        result.From.Revision,
+		To:          result.To.Revision,
+		Title:       diffToResponse(result.Title),
+		Description: diffToResponse(result.Description),
+		TagsAdded:   tagsAdded,
+		TagsRemoved: tagsRemoved,
+	})
+}
+
+// RestoreRevision godoc
+// @Summary Restore a post to an older revision
+// @Description Only the author can restore. The restore is recorded as a new revision.
+// @Tags Posts
+// @Produce json
+// @Param id path int true "Post ID"
+// @Param rev path int true "Revision number"
+// @Success 200 {object} dto.PostResponse
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/posts/{id}/revisions/{rev}/restore [post]
+func (h *PostsHandlers) RestoreRevision(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid post id"})
+	}
+	rev, err := strconv.Atoi(c.Params("rev"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid revision"})
+	}
+
+	userID, ok := c.Locals("user_id").(int)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "user not authenticated"})
+	}
+
+	post, err := h.service.RestoreRevision(c.Context(), userID, id, rev)
+	if err != nil {
+		return revisionError(c, err)
+	}
+
+	return c.JSON(PostToResponse(post))
+}
+
+func revisionError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, errors_constant.PostNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "post not found"})
+	case errors.Is(err, errors_constant.RevisionNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "revision not found"})
+	case errors.Is(err, errors_constant.UserNotAuthorized):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "you cannot access revisions of this post"})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+}
+
+func revisionToResponse(rev *PostRevision) dto.PostRevisionResponse {
+	tags := []string(rev.Tags)
+	if tags == nil {
+		tags = []string{}
+	}
+
+	return dto.PostRevisionResponse{
+		ID:          rev.ID,
+		PostID:      rev.PostID,
+		Revision:    rev.Revision,
+		EditorID:    rev.EditorID,
+		Title:       rev.Title,
+		Description: rev.Description,
+		Tag:         rev.Tag,
+		Tags:        tags,
+		CreatedAt:   rev.CreatedAt,
+	}
+}
+
+func diffToResponse(lines []diff.Line) []dto.DiffLine {
+	response := make([]dto.DiffLine, len(lines))
+	for i, line := range lines {
+		response[i] = dto.DiffLine{Op: line.Op, Text: line.Text}
+	}
+	return response
+}
@@ -2,32 +2,47 @@ package posts
 
 import (
 	"mpb/internal/posts/dto"
+	"mpb/pkg/jwtkeys"
 	"mpb/pkg/middleware"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type PostsRoutes struct {
-	router    fiber.Router
-	handler   *PostsHandlers
-	jwtSecret []byte
+	router  fiber.Router
+	handler *PostsHandlers
+	jwtKeys *jwtkeys.KeySet
 }
 
-func NewPostsRoutes(router fiber.Router, handler *PostsHandlers, jwtSecret []byte) *PostsRoutes {
-	return &PostsRoutes{router: router, handler: handler, jwtSecret: jwtSecret}
+func NewPostsRoutes(router fiber.Router, handler *PostsHandlers, jwtKeys *jwtkeys.KeySet) *PostsRoutes {
+	return &PostsRoutes{router: router, handler: handler, jwtKeys: jwtKeys}
 }
 
 func (r *PostsRoutes) Register() {
 	posts := r.router.Group("/posts")
 
-	posts.Get("/", r.handler.GetAllPosts)
-	posts.Get("/:id", r.handler.GetPost)
+	posts.Get("/", middleware.OptionalJWTAuth(r.jwtKeys), middleware.ValidateQuery[dto.ListPostsQuery](), r.handler.GetAllPosts)
+	posts.Get("/search", middleware.ValidateQuery[dto.SearchPostsQuery](), r.handler.SearchPosts)
+	posts.Get("/trending", middleware.OptionalJWTAuth(r.jwtKeys), middleware.ValidateQuery[dto.TrendingQuery](), r.handler.GetTrendingPosts)
+	posts.Get("/:id", middleware.OptionalJWTAuth(r.jwtKeys), r.handler.GetPost)
+	posts.Get("/:id/metrics", middleware.OptionalJWTAuth(r.jwtKeys), r.handler.GetPostMetrics)
+	posts.Get("/:id/likes", middleware.ValidateQuery[dto.LikesQuery](), r.handler.GetPostLikers)
 
-	res := posts.Group("/", middleware.JWTAuth(r.jwtSecret))
-	res.Post("/", middleware.ValidateBody[dto.CreatePostRequest](), r.handler.CreatePost)
+	res := posts.Group("/", middleware.JWTAuth(r.jwtKeys))
+	res.Post("/", middleware.RequireVerifiedEmail(), middleware.ValidateBody[dto.CreatePostRequest](), r.handler.CreatePost)
 	res.Put("/:id", middleware.ValidateBody[dto.UpdatePostRequest](), r.handler.UpdatePost)
 	res.Delete("/:id", r.handler.DeletePost)
 
+	res.Get("/:id/revisions", r.handler.ListRevisions)
+	res.Get("/:id/revisions/diff", middleware.ValidateQuery[dto.RevisionDiffQuery](), r.handler.DiffRevisions)
+	res.Get("/:id/revisions/:rev", r.handler.GetRevision)
+	res.Post("/:id/revisions/:rev/restore", r.handler.RestoreRevision)
+
 	res.Post("/:id/like", r.handler.LikePost)
 	res.Delete("/:id/unlike", r.handler.UnlikePost)
+	res.Put("/:id/reactions", middleware.ValidateBody[dto.ReactionRequest](), r.handler.SetReaction)
+	res.Delete("/:id/reactions", r.handler.RemoveReaction)
+
+	me := r.router.Group("/me", middleware.JWTAuth(r.jwtKeys))
+	me.Get("/drafts", middleware.ValidateQuery[dto.DraftsQuery](), r.handler.GetMyDrafts)
 }
@@ -4,7 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"html"
 	"mpb/pkg/errors_constant"
+	"mpb/pkg/markdown"
 	"strings"
 	"time"
 
@@ -13,11 +15,35 @@ import (
 )
 
 type PostsRepositoryInterface interface {
-	Save(ctx context.Context, post *Post) error
+	Save(ctx context.Context, post *Post, tags []string, editorID int) error
 	FindByID(ctx context.Context, id int) (*Post, error)
-	Update(ctx context.Context, post *Post) error
+	Update(ctx context.Context, post *Post, tags []string, editorID int) error
+	UpdateStatus(ctx context.Context, post *Post) error
+	PublishDue(ctx context.Context, now time.Time, limit int) ([]Post, error)
 	Delete(ctx context.Context, id int) error
 	List(ctx context.Context, f PostFilter) ([]Post, error)
+	Search(ctx context.Context, q string, limit, offset int) ([]PostSearchResult, error)
+	TagsByPostIDs(ctx context.Context, postIDs []int) (map[int][]string, error)
+	AddLike(ctx context.Context, postID, userID int) error
+	RemoveLike(ctx context.Context, postID, userID int) error
+	ListLikers(ctx context.Context, postID int, cursor *LikeCursor, limit int) ([]PostLiker, error)
+	ListLikedPosts(ctx context.Context, userID int, cursor *LikeCursor, limit int) ([]LikedPost, error)
+	LikesBatch(ctx context.Context, afterPostID, afterUserID, limit int) ([]PostLike, error)
+	ImportLikes(ctx context.Context, likes []PostLike) error
+	CountersBatch(ctx context.Context, afterID, limit int) ([]PostCounters, error)
+	SetCounters(ctx context.Context, counters []PostCounters) error
+	AddViews(ctx context.Context, deltas map[int]int) error
+	UpsertReaction(ctx context.Context, postID, userID int, reaction string) error
+	DeleteReaction(ctx context.Context, postID, userID int) error
+	ListRevisions(ctx context.Context, postID int) ([]PostRevision, error)
+	FindRevision(ctx context.Context, postID, revision int) (*PostRevision, error)
+}
+
+const excerptLength = 200
+
+type PostsPage struct {
+	Posts      []Post
+	NextCursor string
 }
 
 type PostsService struct {
@@ -36,64 +62,96 @@ func NewPostsService(repo *PostsRepository, metricsService *MetricsService, publ
 	}
 }
 
-func (s *PostsService) CreatePost(ctx context.Context, userID int, title, description, tag string) (*Post, error) {
+// CreatePost создаёт пост. Пустой status означает немедленную публикацию.
+func (s *PostsService) CreatePost(ctx context.Context, userID int, title, description string, tags []string, status string, publishAt *time.Time) (*Post, error) {
 	title = strings.TrimSpace(title)
 	if len(title) < 3 {
 		return nil, errors_constant.InvalidTitle
 	}
 
+	tagNames, err := normalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	if status == "" {
+		status = StatusPublished
+	}
+	if status == StatusArchived {
+		return nil, errors_constant.InvalidPostStatus
+	}
+	publishAt, err = resolvePublishAt(status, publishAt, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
 	post := &Post{
 		UserID:       userID,
 		Title:        title,
 		Description:  description,
-		Tag:          tag,
+		Tag:          primaryTag(tagNames),
 		Like:         0,
 		CountViewers: 0,
+		Status:       status,
+		PublishAt:    publishAt,
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
 	}
+	if err := renderDescription(post); err != nil {
+		return nil, err
+	}
 
-	if err := s.repo.Save(ctx, post); err != nil {
+	if err := s.repo.Save(ctx, post, tagNames, userID); err != nil {
 		return nil, fmt.Errorf("failed to create post: %w", err)
 	}
+	post.Tags = tagSlugs(tagNames)
 
-	event := PostCreatedEvent{
-		ID:        post.ID,
-		UserID:    post.UserID,
-		Title:     post.Title,
-		CreatedAt: post.CreatedAt,
+	if post.Status == StatusPublished {
+		s.publishCreated(post)
 	}
 
-	payload, _ := json.Marshal(event)
-	msg := message.NewMessage(watermill.NewUUID(), payload)
+	return post, nil
+}
 
-	if err := s.publisher.Publish("post.created", msg); err != nil {
-		s.logger.Error("failed to publish post.created event", err, nil)
-	}
+// GetPostByID возвращает пост без учёта просмотра; неопубликованные посты доступны только автору (viewerID == 0 — аноним)
+func (s *PostsService) GetPostByID(ctx context.Context, id, viewerID int) (*Post, error) {
+	return s.getPost(ctx, id, viewerID, nil)
+}
 
-	return post, nil
+// ViewPost возвращает пост и засчитывает просмотр зрителя
+func (s *PostsService) ViewPost(ctx context.Context, id int, viewer Viewer) (*Post, error) {
+	return s.getPost(ctx, id, viewer.UserID, &viewer)
 }
 
-func (s *PostsService) GetPostByID(ctx context.Context, id int) (*Post, error) {
+func (s *PostsService) getPost(ctx context.Context, id, viewerID int, viewer *Viewer) (*Post, error) {
 	post, err := s.repo.FindByID(ctx, id)
 	if err != nil {
 		return nil, errors_constant.PostNotFound
 	}
+	if !post.IsVisibleTo(viewerID) {
+		return nil, errors_constant.PostNotFound
+	}
 
-	if err := s.metricsService.IncrementViews(ctx, id); err != nil {
-		s.logger.Error("failed to increment views", err, nil)
+	if viewer != nil && post.Status == StatusPublished {
+		if err := s.metricsService.RecordView(ctx, post, *viewer); err != nil {
+			s.logger.Error("failed to record view", err, nil)
+		}
 	}
 
-	likes, views, err := s.metricsService.GetMetrics(ctx, id)
-	if err == nil {
-		post.Like = likes
-		post.CountViewers = views
+	metrics, err := s.metricsService.GetMetricsBatch(ctx, []int{id}, viewerID)
+	if err != nil {
+		s.logger.Error("failed to load post metrics", err, nil)
+	} else {
+		s.warmMissingCounters(ctx, metrics, post)
+		applyPostMetrics(post, metrics[id])
 	}
 
+	s.loadTags(ctx, post)
 	return post, nil
 }
 
-func (s *PostsService) UpdatePost(ctx context.Context, userID, postID int, title, description, tag string) (*Post, error) {
+// UpdatePost обновляет пост. tags == nil оставляет теги без изменений.
+func (s *PostsService) UpdatePost(ctx context.Context, userID, postID int, title, description string, tags []string) (*Post, error) {
 	post, err := s.repo.FindByID(ctx, postID)
 	if err != nil {
 		return nil, errors_constant.PostNotFound
@@ -103,15 +161,32 @@ func (s *PostsService) UpdatePost(ctx context.Context, userID, postID int, title
 		return nil, errors_constant.UserNotAuthorized
 	}
 
+	var tagNames []string
+	if tags != nil {
+		tagNames, err = normalizeTags(tags)
+		if err != nil {
+			return nil, err
+		}
+		post.Tag = primaryTag(tagNames)
+	}
+
 	post.Title = strings.TrimSpace(title)
 	post.Description = description
-	post.Tag = tag
 	post.UpdatedAt = time.Now()
+	if err := renderDescription(post); err != nil {
+		return nil, err
+	}
 
-	if err := s.repo.Update(ctx, post); err != nil {
+	if err := s.repo.Update(ctx, post, tagNames, userID); err != nil {
 		return nil, fmt.Errorf("failed to update post: %w", err)
 	}
 
+	if tags != nil {
+		post.Tags = tagSlugs(tagNames)
+	} else {
+		s.loadTags(ctx, post)
+	}
+
 	// TODO: publish PostUpdated event
 	return post, nil
 }
@@ -130,7 +205,7 @@ func (s *PostsService) DeletePost(ctx context.Context, userID, postID int) error
 		return fmt.Errorf("failed to delete post: %w", err)
 	}
 
-	// TODO: publish PostDeleted event
+	s.publishDeleted(post)
 	return nil
 }
 
@@ -140,50 +215,246 @@ func (s *PostsService) ListPosts(ctx context.Context, f PostFilter) ([]Post, err
 		return nil, fmt.Errorf("failed to list posts: %w", err)
 	}
 
-	for i := range posts {
-		likes, views, err := s.metricsService.GetMetrics(ctx, posts[i].ID)
-		if err == nil {
-			posts[i].Like = likes
-			posts[i].CountViewers = views
+	s.applyMetrics(ctx, posts, f.ViewerID)
+	s.attachTags(ctx, posts)
+	return posts, nil
+}
+
+// ListPostsPage возвращает страницу постов и курсор на следующую, если она есть
+func (s *PostsService) ListPostsPage(ctx context.Context, f PostFilter) (*PostsPage, error) {
+	f.Limit = clampLimit(f.Limit)
+
+	column, desc := parsePostOrder(f.OrderBy)
+	if f.Cursor != nil && (f.Cursor.OrderBy != column || f.Cursor.Desc != desc) {
+		return nil, errors_constant.InvalidCursor
+	}
+
+	limit := f.Limit
+	f.Limit = limit + 1
+
+	posts, err := s.repo.List(ctx, f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list posts: %w", err)
+	}
+
+	page := &PostsPage{Posts: posts}
+	if len(posts) > limit {
+		page.Posts = posts[:limit]
+		// курсор строится до подмены метрик из Redis, чтобы совпадать со значениями в БД
+		page.NextCursor = NewPostCursor(&page.Posts[limit-1], column, desc).Encode()
+	}
+
+	s.applyMetrics(ctx, page.Posts, f.ViewerID)
+	s.attachTags(ctx, page.Posts)
+	return page, nil
+}
+
+// SearchPosts выполняет полнотекстовый поиск (синтаксис websearch_to_tsquery)
+func (s *PostsService) SearchPosts(ctx context.Context, q string, limit, offset int) ([]PostSearchResult, error) {
+	q = strings.TrimSpace(q)
+	if q == "" {
+		return nil, errors_constant.InvalidSearchQuery
+	}
+	limit = clampLimit(limit)
+
+	results, err := s.repo.Search(ctx, q, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search posts: %w", err)
+	}
+
+	for i := range results {
+		results[i].TitleHighlight = escapeHighlight(results[i].TitleHighlight)
+		results[i].Snippet = escapeHighlight(results[i].Snippet)
+	}
+
+	if len(results) > 0 {
+		ids := make([]int, len(results))
+		for i := range results {
+			ids[i] = results[i].ID
+		}
+
+		metrics, err := s.metricsService.GetMetricsBatch(ctx, ids, 0)
+		if err != nil {
+			s.logger.Error("failed to load post metrics", err, nil)
+		}
+		tags, err := s.repo.TagsByPostIDs(ctx, ids)
+		if err != nil {
+			s.logger.Error("failed to load post tags", err, nil)
+		}
+		for i := range results {
+			applyPostMetrics(&results[i].Post, metrics[results[i].ID])
+			results[i].Tags = tags[results[i].ID]
 		}
 	}
 
-	return posts, nil
+	return results, nil
+}
+
+// renderDescription рендерит Markdown-описание в очищенный HTML, выдержку и счётчик слов,
+// чтобы чтение постов не требовало повторного рендеринга
+func renderDescription(post *Post) error {
+	doc, err := markdown.Render(post.Description)
+	if err != nil {
+		return fmt.Errorf("failed to render description: %w", err)
+	}
+
+	post.DescriptionHTML = doc.HTML
+	post.Excerpt = markdown.Excerpt(doc.Text, excerptLength)
+	post.WordCount = doc.WordCount
+	return nil
+}
+
+// publishCreated отправляет post.created; вызывается в момент фактической публикации поста
+func (s *PostsService) publishCreated(post *Post) {
+	event := PostCreatedEvent{
+		ID:          post.ID,
+		UserID:      post.UserID,
+		Title:       post.Title,
+		CreatedAt:   post.CreatedAt,
+		PublishedAt: post.CreatedAt,
+	}
+	if post.PublishAt != nil {
+		event.PublishedAt = *post.PublishAt
+	}
+
+	payload, _ := json.Marshal(event)
+	msg := message.NewMessage(watermill.NewUUID(), payload)
+
+	if err := s.publisher.Publish("post.created", msg); err != nil {
+		s.logger.Error("failed to publish post.created event", err, nil)
+	}
+}
+
+func (s *PostsService) publishDeleted(post *Post) {
+	event := PostDeletedEvent{ID: post.ID, UserID: post.UserID}
+
+	payload, _ := json.Marshal(event)
+	msg := message.NewMessage(watermill.NewUUID(), payload)
+
+	if err := s.publisher.Publish("post.deleted", msg); err != nil {
+		s.logger.Error("failed to publish post.deleted event", err, nil)
+	}
+}
+
+// escapeHighlight экранирует HTML во фрагменте ts_headline и превращает границы подсветки в теги <mark>
+func escapeHighlight(s string) string {
+	return highlightReplacer.Replace(html.EscapeString(s))
+}
+
+var highlightReplacer = strings.NewReplacer(highlightStart, "<mark>", highlightStop, "</mark>")
+
+func (s *PostsService) attachTags(ctx context.Context, posts []Post) {
+	if len(posts) == 0 {
+		return
+	}
+
+	ids := make([]int, len(posts))
+	for i := range posts {
+		ids[i] = posts[i].ID
+	}
+
+	tags, err := s.repo.TagsByPostIDs(ctx, ids)
+	if err != nil {
+		s.logger.Error("failed to load post tags", err, nil)
+		return
+	}
+	for i := range posts {
+		posts[i].Tags = tags[posts[i].ID]
+	}
 }
 
-// LikePost ставит лайк посту и возвращает статус и общее количество лайков
-func (s *PostsService) LikePost(ctx context.Context, postID, userID int) (bool, int, error) {
-	// Проверяем, существует ли пост
-	_, err := s.repo.FindByID(ctx, postID)
+func (s *PostsService) loadTags(ctx context.Context, post *Post) {
+	tags, err := s.repo.TagsByPostIDs(ctx, []int{post.ID})
 	if err != nil {
-		return false, 0, errors_constant.PostNotFound
+		s.logger.Error("failed to load post tags", err, nil)
+		return
+	}
+	post.Tags = tags[post.ID]
+}
+
+// applyMetrics подставляет метрики из Redis одним запросом на всю страницу постов
+func (s *PostsService) applyMetrics(ctx context.Context, posts []Post, viewerID int) {
+	if len(posts) == 0 {
+		return
+	}
+
+	ids := make([]int, len(posts))
+	for i := range posts {
+		ids[i] = posts[i].ID
 	}
 
-	// Пытаемся поставить лайк
-	err = s.metricsService.LikePost(ctx, userID, postID)
+	metrics, err := s.metricsService.GetMetricsBatch(ctx, ids, viewerID)
 	if err != nil {
-		// Если пользователь уже лайкнул, возвращаем текущее количество лайков
-		if err.Error() == "user already liked this post" {
-			likes, _ := s.metricsService.GetLikes(ctx, postID)
-			return true, likes, nil
+		s.logger.Error("failed to load post metrics", err, nil)
+		return
+	}
+	refs := make([]*Post, len(posts))
+	for i := range posts {
+		refs[i] = &posts[i]
+	}
+	s.warmMissingCounters(ctx, metrics, refs...)
+
+	for i := range posts {
+		applyPostMetrics(&posts[i], metrics[posts[i].ID])
+	}
+}
+
+// warmMissingCounters лениво переносит в Redis значения из PostgreSQL для постов, чьих счётчиков там нет
+func (s *PostsService) warmMissingCounters(ctx context.Context, metrics map[int]*PostMetrics, posts ...*Post) {
+	var missing []PostCounters
+	for _, post := range posts {
+		if m := metrics[post.ID]; m != nil && (!m.HasLikes || !m.HasViews) {
+			missing = append(missing, PostCounters{PostID: post.ID, Likes: post.Like, Views: post.CountViewers})
 		}
-		return false, 0, err
+	}
+	if len(missing) == 0 {
+		return
 	}
 
-	likes, err := s.metricsService.GetLikes(ctx, postID)
-	if err != nil {
-		return true, 0, err
+	if err := s.metricsService.WarmCounters(ctx, missing); err != nil {
+		s.logger.Error("failed to warm post counters", err, nil)
 	}
+}
 
-	return true, likes, nil
+// applyPostMetrics переносит метрики в пост; отсутствующие в Redis счётчики остаются из PostgreSQL
+func applyPostMetrics(post *Post, m *PostMetrics) {
+	if m == nil {
+		return
+	}
+	if m.HasLikes {
+		post.Like = m.Likes
+	}
+	if m.HasViews {
+		post.CountViewers = m.Views
+	}
+	post.UniqueViewers = m.Unique
+	post.LikedByMe = m.Liked
+	post.Reactions = m.Reactions
+	post.MyReaction = m.MyReaction
 }
 
-// IncrementViews увеличивает счетчик просмотров
-func (s *PostsService) IncrementViews(ctx context.Context, postID int) error {
-	return s.metricsService.IncrementViews(ctx, postID)
+// LikePost ставит лайк опубликованному посту и возвращает новое количество лайков
+func (s *PostsService) LikePost(ctx context.Context, postID, userID int) (int, error) {
+	post, err := s.checkReactable(ctx, postID)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := s.metricsService.LikePost(ctx, userID, post); err != nil {
+		return 0, err
+	}
+	return s.metricsService.GetLikes(ctx, postID)
 }
 
-// GetMetrics возвращает метрики поста (лайки и просмотры)
-func (s *PostsService) GetMetrics(ctx context.Context, postID int) (int, int, error) {
-	return s.metricsService.GetMetrics(ctx, postID)
+// UnlikePost снимает лайк с опубликованного поста и возвращает новое количество лайков
+func (s *PostsService) UnlikePost(ctx context.Context, postID, userID int) (int, error) {
+	post, err := s.checkReactable(ctx, postID)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := s.metricsService.UnlikePost(ctx, userID, post); err != nil {
+		return 0, err
+	}
+	return s.metricsService.GetLikes(ctx, postID)
 }
@@ -3,6 +3,8 @@ package posts
 import (
 	"context"
 	"errors"
+	"fmt"
+	"mpb/configs"
 	"mpb/pkg/errors_constant"
 	"testing"
 	"time"
@@ -11,14 +13,15 @@ import (
 	"github.com/ThreeDotsLabs/watermill/message"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 type MockPostsRepository struct {
 	mock.Mock
 }
 
-func (m *MockPostsRepository) Save(ctx context.Context, post *Post) error {
-	args := m.Called(ctx, post)
+func (m *MockPostsRepository) Save(ctx context.Context, post *Post, tags []string, editorID int) error {
+	args := m.Called(ctx, post, tags, editorID)
 	if args.Get(0) != nil {
 		post.ID = 1
 		post.CreatedAt = time.Now()
@@ -35,12 +38,25 @@ func (m *MockPostsRepository) FindByID(ctx context.Context, id int) (*Post, erro
 	return args.Get(0).(*Post), args.Error(1)
 }
 
-func (m *MockPostsRepository) Update(ctx context.Context, post *Post) error {
-	args := m.Called(ctx, post)
+func (m *MockPostsRepository) Update(ctx context.Context, post *Post, tags []string, editorID int) error {
+	args := m.Called(ctx, post, tags, editorID)
 	post.UpdatedAt = time.Now()
 	return args.Error(0)
 }
 
+func (m *MockPostsRepository) UpdateStatus(ctx context.Context, post *Post) error {
+	args := m.Called(ctx, post)
+	return args.Error(0)
+}
+
+func (m *MockPostsRepository) PublishDue(ctx context.Context, now time.Time, limit int) ([]Post, error) {
+	args := m.Called(ctx, now, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]Post), args.Error(1)
+}
+
 func (m *MockPostsRepository) Delete(ctx context.Context, id int) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
@@ -54,27 +70,121 @@ func (m *MockPostsRepository) List(ctx context.Context, f PostFilter) ([]Post, e
 	return args.Get(0).([]Post), args.Error(1)
 }
 
-type MockMetricsService struct {
-	mock.Mock
+func (m *MockPostsRepository) Search(ctx context.Context, q string, limit, offset int) ([]PostSearchResult, error) {
+	args := m.Called(ctx, q, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]PostSearchResult), args.Error(1)
 }
 
-func (m *MockMetricsService) IncrementViews(ctx context.Context, postID int) error {
-	args := m.Called(ctx, postID)
+func (m *MockPostsRepository) TagsByPostIDs(ctx context.Context, postIDs []int) (map[int][]string, error) {
+	args := m.Called(ctx, postIDs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[int][]string), args.Error(1)
+}
+
+func (m *MockPostsRepository) AddLike(ctx context.Context, postID, userID int) error {
+	args := m.Called(ctx, postID, userID)
+	return args.Error(0)
+}
+
+func (m *MockPostsRepository) RemoveLike(ctx context.Context, postID, userID int) error {
+	args := m.Called(ctx, postID, userID)
 	return args.Error(0)
 }
 
+func (m *MockPostsRepository) ListLikers(ctx context.Context, postID int, cursor *LikeCursor, limit int) ([]PostLiker, error) {
+	args := m.Called(ctx, postID, cursor, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]PostLiker), args.Error(1)
+}
+
+func (m *MockPostsRepository) ListLikedPosts(ctx context.Context, userID int, cursor *LikeCursor, limit int) ([]LikedPost, error) {
+	args := m.Called(ctx, userID, cursor, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]LikedPost), args.Error(1)
+}
+
+func (m *MockPostsRepository) LikesBatch(ctx context.Context, afterPostID, afterUserID, limit int) ([]PostLike, error) {
+	args := m.Called(ctx, afterPostID, afterUserID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]PostLike), args.Error(1)
+}
+
+func (m *MockPostsRepository) ImportLikes(ctx context.Context, likes []PostLike) error {
+	args := m.Called(ctx, likes)
+	return args.Error(0)
+}
+
+func (m *MockPostsRepository) CountersBatch(ctx context.Context, afterID, limit int) ([]PostCounters, error) {
+	args := m.Called(ctx, afterID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]PostCounters), args.Error(1)
+}
+
+func (m *MockPostsRepository) SetCounters(ctx context.Context, counters []PostCounters) error {
+	args := m.Called(ctx, counters)
+	return args.Error(0)
+}
+
+func (m *MockPostsRepository) AddViews(ctx context.Context, deltas map[int]int) error {
+	args := m.Called(ctx, deltas)
+	return args.Error(0)
+}
+
+func (m *MockPostsRepository) UpsertReaction(ctx context.Context, postID, userID int, reaction string) error {
+	args := m.Called(ctx, postID, userID, reaction)
+	return args.Error(0)
+}
+
+func (m *MockPostsRepository) DeleteReaction(ctx context.Context, postID, userID int) error {
+	args := m.Called(ctx, postID, userID)
+	return args.Error(0)
+}
+
+func (m *MockPostsRepository) ListRevisions(ctx context.Context, postID int) ([]PostRevision, error) {
+	args := m.Called(ctx, postID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]PostRevision), args.Error(1)
+}
+
+func (m *MockPostsRepository) FindRevision(ctx context.Context, postID, revision int) (*PostRevision, error) {
+	args := m.Called(ctx, postID, revision)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*PostRevision), args.Error(1)
+}
+
+type MockMetricsService struct {
+	mock.Mock
+}
+
 func (m *MockMetricsService) GetViews(ctx context.Context, postID int) (int, error) {
 	args := m.Called(ctx, postID)
 	return args.Int(0), args.Error(1)
 }
 
-func (m *MockMetricsService) LikePost(ctx context.Context, userID, postID int) error {
-	args := m.Called(ctx, userID, postID)
+func (m *MockMetricsService) LikePost(ctx context.Context, userID int, post *Post) error {
+	args := m.Called(ctx, userID, post)
 	return args.Error(0)
 }
 
-func (m *MockMetricsService) UnlikePost(ctx context.Context, userID, postID int) error {
-	args := m.Called(ctx, userID, postID)
+func (m *MockMetricsService) UnlikePost(ctx context.Context, userID int, post *Post) error {
+	args := m.Called(ctx, userID, post)
 	return args.Error(0)
 }
 
@@ -88,11 +198,6 @@ func (m *MockMetricsService) CheckUserLiked(ctx context.Context, userID, postID
 	return args.Bool(0), args.Error(1)
 }
 
-func (m *MockMetricsService) GetMetrics(ctx context.Context, postID int) (likes, views int, err error) {
-	args := m.Called(ctx, postID)
-	return args.Int(0), args.Int(1), args.Error(2)
-}
-
 type MockPublisher struct {
 	mock.Mock
 }
@@ -122,6 +227,8 @@ func TestPostsService_CreatePost(t *testing.T) {
 		title         string
 		description   string
 		tag           string
+		status        string
+		publishAt     *time.Time
 		mockSetup     func(*MockPostsRepository, *MockMetricsService, *MockPublisher)
 		expectedError error
 	}{
@@ -132,11 +239,36 @@ func TestPostsService_CreatePost(t *testing.T) {
 			description: "Test Description",
 			tag:         "test",
 			mockSetup: func(repo *MockPostsRepository, metrics *MockMetricsService, pub *MockPublisher) {
-				repo.On("Save", mock.Anything, mock.AnythingOfType("*posts.Post")).Return(nil)
+				repo.On("Save", mock.Anything, mock.AnythingOfType("*posts.Post"), []string{"test"}, 1).Return(nil)
 				pub.On("Publish", "post.created", mock.Anything).Return(nil)
 			},
 			expectedError: nil,
 		},
+		{
+			name:        "draft does not publish post.created",
+			userID:      1,
+			title:       "Test Post Title",
+			description: "Test Description",
+			tag:         "test",
+			status:      StatusDraft,
+			mockSetup: func(repo *MockPostsRepository, metrics *MockMetricsService, pub *MockPublisher) {
+				repo.On("Save", mock.Anything, mock.MatchedBy(func(p *Post) bool {
+					return p.Status == StatusDraft && p.PublishAt == nil
+				}), []string{"test"}, 1).Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:          "scheduled in the past",
+			userID:        1,
+			title:         "Test Post Title",
+			description:   "Test Description",
+			tag:           "test",
+			status:        StatusScheduled,
+			publishAt:     timePtr(time.Now().Add(-time.Hour)),
+			mockSetup:     func(*MockPostsRepository, *MockMetricsService, *MockPublisher) {},
+			expectedError: errors_constant.InvalidPublishAt,
+		},
 		{
 			name:          "title too short",
 			userID:        1,
@@ -162,7 +294,7 @@ func TestPostsService_CreatePost(t *testing.T) {
 			description: "Test Description",
 			tag:         "test",
 			mockSetup: func(repo *MockPostsRepository, metrics *MockMetricsService, pub *MockPublisher) {
-				repo.On("Save", mock.Anything, mock.AnythingOfType("*posts.Post")).Return(errors.New("db error"))
+				repo.On("Save", mock.Anything, mock.AnythingOfType("*posts.Post"), []string{"test"}, 1).Return(errors.New("db error"))
 			},
 			expectedError: errors.New("failed to create post: db error"),
 		},
@@ -184,7 +316,7 @@ func TestPostsService_CreatePost(t *testing.T) {
 				logger:         logger,
 			}
 
-			post, err := service.CreatePost(context.Background(), tt.userID, tt.title, tt.description, tt.tag)
+			post, err := service.CreatePost(context.Background(), tt.userID, tt.title, tt.description, []string{tt.tag}, tt.status, tt.publishAt)
 
 			if tt.expectedError != nil {
 				assert.Error(t, err)
@@ -197,6 +329,9 @@ func TestPostsService_CreatePost(t *testing.T) {
 				assert.Equal(t, "Test Post Title", post.Title)
 				assert.Equal(t, tt.description, post.Description)
 				assert.Equal(t, tt.tag, post.Tag)
+				assert.Equal(t, "<p>Test Description</p>\n", post.DescriptionHTML)
+				assert.Equal(t, "Test Description", post.Excerpt)
+				assert.Equal(t, 2, post.WordCount)
 				assert.Equal(t, 0, post.Like)
 				assert.Equal(t, 0, post.CountViewers)
 			}
@@ -211,6 +346,7 @@ func TestPostsService_GetPostByID(t *testing.T) {
 	tests := []struct {
 		name          string
 		postID        int
+		viewerID      int
 		mockSetup     func(*MockPostsRepository, *MockMetricsService, *MockPublisher)
 		expectedError error
 		skip          bool
@@ -242,6 +378,15 @@ func TestPostsService_GetPostByID(t *testing.T) {
 			expectedError: errors_constant.PostNotFound,
 			skip:          false,
 		},
+		{
+			name:     "draft hidden from other users",
+			postID:   2,
+			viewerID: 3,
+			mockSetup: func(repo *MockPostsRepository, metrics *MockMetricsService, pub *MockPublisher) {
+				repo.On("FindByID", mock.Anything, 2).Return(&Post{ID: 2, UserID: 1, Status: StatusDraft}, nil)
+			},
+			expectedError: errors_constant.PostNotFound,
+		},
 	}
 
 	for _, tt := range tests {
@@ -264,7 +409,7 @@ func TestPostsService_GetPostByID(t *testing.T) {
 				logger:         logger,
 			}
 
-			post, err := service.GetPostByID(context.Background(), tt.postID)
+			post, err := service.GetPostByID(context.Background(), tt.postID, tt.viewerID)
 
 			if tt.expectedError != nil {
 				assert.Error(t, err)
@@ -309,7 +454,9 @@ func TestPostsService_UpdatePost(t *testing.T) {
 					Tag:    "old",
 				}
 				repo.On("FindByID", mock.Anything, 1).Return(post, nil)
-				repo.On("Update", mock.Anything, mock.AnythingOfType("*posts.Post")).Return(nil)
+				repo.On("Update", mock.Anything, mock.MatchedBy(func(p *Post) bool {
+					return p.ID == 1 && p.Title == "Updated Title"
+				}), []string{"updated"}, 1).Return(nil)
 			},
 			expectedError: nil,
 		},
@@ -359,7 +506,7 @@ func TestPostsService_UpdatePost(t *testing.T) {
 				logger:         logger,
 			}
 
-			post, err := service.UpdatePost(context.Background(), tt.userID, tt.postID, tt.title, tt.description, tt.tag)
+			post, err := service.UpdatePost(context.Background(), tt.userID, tt.postID, tt.title, tt.description, []string{tt.tag})
 
 			if tt.expectedError != nil {
 				assert.Error(t, err)
@@ -397,6 +544,7 @@ func TestPostsService_DeletePost(t *testing.T) {
 				}
 				repo.On("FindByID", mock.Anything, 1).Return(post, nil)
 				repo.On("Delete", mock.Anything, 1).Return(nil)
+				pub.On("Publish", "post.deleted", mock.Anything).Return(nil)
 			},
 			expectedError: nil,
 		},
@@ -450,6 +598,7 @@ func TestPostsService_DeletePost(t *testing.T) {
 			}
 
 			repo.AssertExpectations(t)
+			publisher.AssertExpectations(t)
 		})
 	}
 }
@@ -472,8 +621,6 @@ func TestPostsService_ListPosts(t *testing.T) {
 					{ID: 2, Title: "Post 2"},
 				}
 				repo.On("List", mock.Anything, mock.AnythingOfType("posts.PostFilter")).Return(posts, nil)
-				metrics.On("GetMetrics", mock.Anything, 1).Return(5, 10, nil)
-				metrics.On("GetMetrics", mock.Anything, 2).Return(3, 7, nil)
 			},
 			expectedCount: 2,
 			expectedError: nil,
@@ -538,3 +685,388 @@ func TestPostsService_ListPosts(t *testing.T) {
 		})
 	}
 }
+
+func TestPostsService_ListPostsPage(t *testing.T) {
+	tests := []struct {
+		name          string
+		filter        PostFilter
+		mockSetup     func(*MockPostsRepository)
+		expectedError error
+	}{
+		{
+			name:   "default limit fetches one extra row",
+			filter: PostFilter{OnlyActive: true},
+			mockSetup: func(repo *MockPostsRepository) {
+				repo.On("List", mock.Anything, mock.MatchedBy(func(f PostFilter) bool {
+					return f.Limit == defaultPageLimit+1
+				})).Return([]Post{}, nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:          "cursor from another ordering",
+			filter:        PostFilter{OnlyActive: true, OrderBy: "like DESC", Cursor: &PostCursor{OrderBy: "created_at", Desc: true, ID: 1}},
+			mockSetup:     func(*MockPostsRepository) {},
+			expectedError: errors_constant.InvalidCursor,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(MockPostsRepository)
+			tt.mockSetup(repo)
+
+			service := &PostsService{
+				repo:   repo,
+				logger: new(MockLogger),
+			}
+
+			page, err := service.ListPostsPage(context.Background(), tt.filter)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				assert.Nil(t, page)
+			} else {
+				assert.NoError(t, err)
+				assert.Empty(t, page.NextCursor)
+			}
+
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPostsService_SearchPosts(t *testing.T) {
+	repo := new(MockPostsRepository)
+	service := &PostsService{repo: repo, logger: new(MockLogger)}
+
+	_, err := service.SearchPosts(context.Background(), "   ", 10, 0)
+	assert.ErrorIs(t, err, errors_constant.InvalidSearchQuery)
+
+	repo.On("Search", mock.Anything, "golang", maxPageLimit, 0).Return([]PostSearchResult{}, nil)
+	results, err := service.SearchPosts(context.Background(), " golang ", 1000, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+	repo.AssertExpectations(t)
+}
+
+func TestEscapeHighlight(t *testing.T) {
+	got := escapeHighlight(`<script>x</script> ` + highlightStart + `go` + highlightStop + ` & <mark>literal</mark>`)
+	assert.Equal(t, `&lt;script&gt;x&lt;/script&gt; <mark>go</mark> &amp; &lt;mark&gt;literal&lt;/mark&gt;`, got)
+}
+
+func TestNormalizeTags(t *testing.T) {
+	names, err := normalizeTags([]string{" Go ", "go", "Machine   Learning", "machine-learning", "", "!!"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"go", "machine learning"}, names)
+	assert.Equal(t, []string{"go", "machine-learning"}, tagSlugs(names))
+
+	tooMany := make([]string, MaxTagsPerPost+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("tag%d", i)
+	}
+	_, err = normalizeTags(tooMany)
+	assert.ErrorIs(t, err, errors_constant.TooManyTags)
+}
+
+func TestPostsService_ListRevisions(t *testing.T) {
+	tests := []struct {
+		name          string
+		viewerID      int
+		role          string
+		expectedError error
+	}{
+		{name: "author", viewerID: 1, role: "user", expectedError: nil},
+		{name: "moderator", viewerID: 2, role: "moderator", expectedError: nil},
+		{name: "other user", viewerID: 2, role: "user", expectedError: errors_constant.UserNotAuthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(MockPostsRepository)
+			repo.On("FindByID", mock.Anything, 1).Return(&Post{ID: 1, UserID: 1}, nil)
+			if tt.expectedError == nil {
+				repo.On("ListRevisions", mock.Anything, 1).Return([]PostRevision{{PostID: 1, Revision: 1}}, nil)
+			}
+
+			service := &PostsService{repo: repo, logger: new(MockLogger)}
+			revisions, err := service.ListRevisions(context.Background(), tt.viewerID, tt.role, 1)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Len(t, revisions, 1)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPostsService_RestoreRevision(t *testing.T) {
+	repo := new(MockPostsRepository)
+	repo.On("FindByID", mock.Anything, 1).Return(&Post{ID: 1, UserID: 1, Title: "Current Title", Tag: "new"}, nil)
+	repo.On("FindRevision", mock.Anything, 1, 2).Return(&PostRevision{
+		PostID: 1, Revision: 2, Title: "Old Title", Description: "Old description", Tag: "old", Tags: []string{"old"},
+	}, nil)
+	repo.On("Update", mock.Anything, mock.MatchedBy(func(p *Post) bool {
+		return p.Title == "Old Title"
+	}), []string{"old"}, 1).Return(nil)
+
+	service := &PostsService{repo: repo, logger: new(MockLogger)}
+	post, err := service.RestoreRevision(context.Background(), 1, 1, 2)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Old Title", post.Title)
+	assert.Equal(t, "Old description", post.Description)
+	assert.Equal(t, []string{"old"}, post.Tags)
+
+	_, err = service.RestoreRevision(context.Background(), 2, 1, 2)
+	assert.ErrorIs(t, err, errors_constant.UserNotAuthorized)
+	repo.AssertExpectations(t)
+}
+
+func TestPostsService_ChangeStatus(t *testing.T) {
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name          string
+		current       string
+		status        string
+		publishAt     *time.Time
+		expectEvent   bool
+		expectedError error
+	}{
+		{name: "draft to published", current: StatusDraft, status: StatusPublished, expectEvent: true},
+		{name: "draft to scheduled", current: StatusDraft, status: StatusScheduled, publishAt: &future},
+		{name: "scheduled without publish_at", current: StatusDraft, status: StatusScheduled, expectedError: errors_constant.InvalidPublishAt},
+		{name: "published to archived", current: StatusPublished, status: StatusArchived},
+		{name: "archived back to published", current: StatusArchived, status: StatusPublished},
+		{name: "published to draft", current: StatusPublished, status: StatusDraft, expectedError: errors_constant.InvalidPostStatus},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(MockPostsRepository)
+			publisher := new(MockPublisher)
+
+			repo.On("FindByID", mock.Anything, 1).Return(&Post{ID: 1, UserID: 1, Status: tt.current}, nil)
+			if tt.expectedError == nil {
+				repo.On("UpdateStatus", mock.Anything, mock.MatchedBy(func(p *Post) bool {
+					return p.Status == tt.status
+				})).Return(nil)
+				repo.On("TagsByPostIDs", mock.Anything, []int{1}).Return(map[int][]string{}, nil)
+			}
+			if tt.expectEvent {
+				publisher.On("Publish", "post.created", mock.Anything).Return(nil)
+			}
+
+			service := &PostsService{repo: repo, publisher: publisher, logger: new(MockLogger)}
+			post, err := service.ChangeStatus(context.Background(), 1, 1, tt.status, tt.publishAt)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.status, post.Status)
+			}
+			repo.AssertExpectations(t)
+			publisher.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPostsService_PublishDuePosts(t *testing.T) {
+	repo := new(MockPostsRepository)
+	publisher := new(MockPublisher)
+
+	repo.On("PublishDue", mock.Anything, mock.MatchedBy(func(now time.Time) bool {
+		return now.Location() == time.UTC
+	}), publishBatchSize).
+		Return([]Post{{ID: 1, Status: StatusPublished}, {ID: 2, Status: StatusPublished}}, nil)
+	publisher.On("Publish", "post.created", mock.Anything).Return(nil).Twice()
+
+	service := &PostsService{repo: repo, publisher: publisher, logger: new(MockLogger)}
+	published, err := service.PublishDuePosts(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, published)
+	repo.AssertExpectations(t)
+	publisher.AssertExpectations(t)
+}
+
+func TestResolvePublishAt_UTC(t *testing.T) {
+	zone := time.FixedZone("UTC+3", 3*60*60)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 123456789, zone)
+
+	published, err := resolvePublishAt(StatusPublished, nil, now)
+	require.NoError(t, err)
+	assert.Equal(t, time.UTC, published.Location())
+	assert.Equal(t, time.Date(2024, 5, 1, 9, 0, 0, 123456000, time.UTC), *published)
+
+	scheduled, err := resolvePublishAt(StatusScheduled, timePtr(now.Add(time.Hour)), now)
+	require.NoError(t, err)
+	assert.Equal(t, time.UTC, scheduled.Location())
+	assert.True(t, scheduled.Equal(now.Add(time.Hour).Truncate(time.Microsecond)))
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func TestPostsService_ReactToPost(t *testing.T) {
+	metrics := NewMetricsService(nil, nil, new(MockLogger), configs.PostsConfig{Reactions: []string{"👍", "🔥"}})
+
+	t.Run("reaction outside of configured set", func(t *testing.T) {
+		repo := new(MockPostsRepository)
+		service := &PostsService{repo: repo, metricsService: metrics, logger: new(MockLogger)}
+
+		_, err := service.ReactToPost(context.Background(), 1, 2, "💩")
+		assert.ErrorIs(t, err, errors_constant.InvalidReaction)
+		repo.AssertNotCalled(t, "FindByID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("unpublished post", func(t *testing.T) {
+		repo := new(MockPostsRepository)
+		repo.On("FindByID", mock.Anything, 1).Return(&Post{ID: 1, UserID: 1, Status: StatusDraft}, nil)
+		service := &PostsService{repo: repo, metricsService: metrics, logger: new(MockLogger)}
+
+		_, err := service.ReactToPost(context.Background(), 1, 2, "🔥")
+		assert.ErrorIs(t, err, errors_constant.PostNotFound)
+		repo.AssertExpectations(t)
+	})
+}
+
+func TestPostsService_LikePostUnpublished(t *testing.T) {
+	repo := new(MockPostsRepository)
+	repo.On("FindByID", mock.Anything, 1).Return(&Post{ID: 1, UserID: 1, Status: StatusScheduled}, nil)
+	// без Redis: до счётчиков лайков дело доходить не должно
+	metrics := NewMetricsService(nil, nil, new(MockLogger), configs.PostsConfig{})
+	service := &PostsService{repo: repo, metricsService: metrics, logger: new(MockLogger)}
+
+	_, err := service.LikePost(context.Background(), 1, 2)
+	assert.ErrorIs(t, err, errors_constant.PostNotFound)
+	_, err = service.UnlikePost(context.Background(), 1, 2)
+	assert.ErrorIs(t, err, errors_constant.PostNotFound)
+	repo.AssertExpectations(t)
+}
+
+func TestPostsService_ListPostLikers(t *testing.T) {
+	now := time.Now()
+	repo := new(MockPostsRepository)
+	repo.On("FindByID", mock.Anything, 1).Return(&Post{ID: 1, Status: StatusPublished}, nil)
+	repo.On("FindByID", mock.Anything, 2).Return(&Post{ID: 2, Status: StatusDraft}, nil)
+	repo.On("ListLikers", mock.Anything, 1, (*LikeCursor)(nil), 3).Return([]PostLiker{
+		{UserID: 5, Username: "e", LikedAt: now},
+		{UserID: 4, Username: "d", LikedAt: now.Add(-time.Minute)},
+		{UserID: 3, Username: "c", LikedAt: now.Add(-2 * time.Minute)},
+	}, nil)
+
+	service := &PostsService{repo: repo, logger: new(MockLogger)}
+
+	page, err := service.ListPostLikers(context.Background(), 1, 2, nil)
+	assert.NoError(t, err)
+	assert.Len(t, page.Likers, 2)
+
+	cursor, err := DecodeLikeCursor(page.NextCursor)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, cursor.ID)
+
+	_, err = service.ListPostLikers(context.Background(), 2, 2, nil)
+	assert.ErrorIs(t, err, errors_constant.PostNotFound)
+	repo.AssertExpectations(t)
+}
+
+func TestApplyPostMetrics(t *testing.T) {
+	post := &Post{ID: 1, Like: 4, CountViewers: 40}
+
+	applyPostMetrics(post, &PostMetrics{Views: 41, Unique: 12, HasViews: true, Liked: true, Reactions: map[string]int{"🔥": 2}})
+	assert.Equal(t, 4, post.Like, "missing Redis key keeps the PostgreSQL value")
+	assert.Equal(t, 41, post.CountViewers)
+	assert.Equal(t, 12, post.UniqueViewers)
+	assert.True(t, post.LikedByMe)
+	assert.Equal(t, 2, post.Reactions["🔥"])
+
+	applyPostMetrics(post, nil)
+	assert.Equal(t, 41, post.CountViewers)
+}
+
+func TestReconcileCounters(t *testing.T) {
+	tests := []struct {
+		name      string
+		counters  PostCounters
+		metrics   *PostMetrics
+		drifted   bool
+		likes     int
+		views     int
+		dbStale   bool
+		likeStale bool
+		viewStale bool
+	}{
+		{
+			name:     "in sync",
+			counters: PostCounters{PostID: 1, Likes: 3, Views: 10, LikeRows: 3},
+			metrics:  &PostMetrics{Likes: 3, Views: 10, HasLikes: true, HasViews: true},
+			likes:    3,
+			views:    10,
+		},
+		{
+			name:      "redis flushed",
+			counters:  PostCounters{PostID: 1, Likes: 3, Views: 10, LikeRows: 3},
+			metrics:   &PostMetrics{},
+			drifted:   true,
+			likes:     3,
+			views:     10,
+			likeStale: true,
+			viewStale: true,
+		},
+		{
+			name:     "dropped view events",
+			counters: PostCounters{PostID: 1, Likes: 3, Views: 10, LikeRows: 3},
+			metrics:  &PostMetrics{Likes: 3, Views: 14, HasLikes: true, HasViews: true},
+			drifted:  true,
+			likes:    3,
+			views:    14,
+			dbStale:  true,
+		},
+		{
+			name:      "post_likes wins over both counters",
+			counters:  PostCounters{PostID: 1, Likes: 5, Views: 10, LikeRows: 4},
+			metrics:   &PostMetrics{Likes: 6, Views: 10, HasLikes: true, HasViews: true},
+			drifted:   true,
+			likes:     4,
+			views:     10,
+			dbStale:   true,
+			likeStale: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, drifted := reconcileCounters(tt.counters, tt.metrics)
+			assert.Equal(t, tt.drifted, drifted)
+			assert.Equal(t, tt.likes, d.Likes)
+			assert.Equal(t, tt.views, d.Views)
+			assert.Equal(t, tt.dbStale, d.dbStale())
+			assert.Equal(t, tt.likeStale, d.redisLikesStale())
+			assert.Equal(t, tt.viewStale, d.redisViewsStale())
+		})
+	}
+}
+
+func TestTrendingWindow_Score(t *testing.T) {
+	window, err := ParseTrendingWindow("")
+	assert.NoError(t, err)
+	assert.Equal(t, "24h", window.Name)
+
+	_, err = ParseTrendingWindow("1y")
+	assert.ErrorIs(t, err, errors_constant.InvalidTrendingWindow)
+
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	// два периода полураспада уменьшают вклад в четыре раза
+	assert.InDelta(t, window.score(1, now), window.score(4, now.Add(-12*time.Hour)), 1e-9)
+	assert.Greater(t, window.score(trendingLikeWeight, now), window.score(trendingViewWeight, now))
+	assert.InDelta(t, window.cutoff(now), window.score(trendingViewWeight, now.Add(-24*time.Hour)), 1e-9)
+	assert.Equal(t, "trending:24h:tag:go", window.key("go"))
+}
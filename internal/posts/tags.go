@@ -0,0 +1,55 @@
+package posts
+
+import (
+	"mpb/pkg/errors_constant"
+	"mpb/pkg/slug"
+	"strings"
+)
+
+const (
+	MaxTagsPerPost = 10
+	maxTagLength   = 50
+)
+
+// normalizeTags приводит теги к нижнему регистру, схлопывает пробелы
+// и убирает пустые значения и дубликаты по slug
+func normalizeTags(raw []string) ([]string, error) {
+	seen := make(map[string]bool, len(raw))
+	names := make([]string, 0, len(raw))
+
+	for _, tag := range raw {
+		name := strings.Join(strings.Fields(strings.ToLower(tag)), " ")
+		tagSlug := slug.Make(name)
+		if tagSlug == "" || seen[tagSlug] {
+			continue
+		}
+		if len(name) > maxTagLength {
+			return nil, errors_constant.InvalidTag
+		}
+
+		seen[tagSlug] = true
+		names = append(names, name)
+	}
+
+	if len(names) > MaxTagsPerPost {
+		return nil, errors_constant.TooManyTags
+	}
+
+	return names, nil
+}
+
+func tagSlugs(names []string) []string {
+	slugs := make([]string, len(names))
+	for i, name := range names {
+		slugs[i] = slug.Make(name)
+	}
+	return slugs
+}
+
+// primaryTag — первый тег поста, хранится в posts.tag для старых клиентов
+func primaryTag(names []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	return names[0]
+}
@@ -0,0 +1,108 @@
+package posts
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"mpb/pkg/errors_constant"
+	"time"
+)
+
+// Веса взаимодействий в очках тренда
+const (
+	trendingViewWeight    = 1.0
+	trendingLikeWeight    = 4.0
+	trendingCommentWeight = 6.0
+)
+
+// trendingEpoch — точка отсчёта логарифмических очков, общая для всех окон
+var trendingEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// TrendingWindow — окно тренда: Span ограничивает давность учитываемой активности,
+// HalfLife — время, за которое вклад взаимодействия уменьшается вдвое
+type TrendingWindow struct {
+	Name     string
+	Span     time.Duration
+	HalfLife time.Duration
+}
+
+var trendingWindows = map[string]TrendingWindow{
+	"24h": {Name: "24h", Span: 24 * time.Hour, HalfLife: 6 * time.Hour},
+	"7d":  {Name: "7d", Span: 7 * 24 * time.Hour, HalfLife: 36 * time.Hour},
+}
+
+const defaultTrendingWindow = "24h"
+
+// ParseTrendingWindow возвращает окно по имени; пустое имя — окно по умолчанию
+func ParseTrendingWindow(name string) (TrendingWindow, error) {
+	if name == "" {
+		name = defaultTrendingWindow
+	}
+	window, ok := trendingWindows[name]
+	if !ok {
+		return TrendingWindow{}, errors_constant.InvalidTrendingWindow
+	}
+	return window, nil
+}
+
+// Очки хранятся в логарифмической шкале: ln(Σ wᵢ·2^((tᵢ−epoch)/HalfLife)).
+// Каждое взаимодействие добавляет ln(w) + (t−epoch)/τ, где τ = HalfLife/ln2, поэтому очки
+// обновляются инкрементально, а порядок постов совпадает с порядком экспоненциально затухающих сумм.
+
+func (w TrendingWindow) tau() float64 {
+	return w.HalfLife.Seconds() / math.Ln2
+}
+
+// score возвращает логарифмические очки взаимодействия веса weight в момент at
+func (w TrendingWindow) score(weight float64, at time.Time) float64 {
+	return math.Log(weight) + at.Sub(trendingEpoch).Seconds()/w.tau()
+}
+
+// cutoff — очки одного просмотра в начале окна: посты с меньшими очками выпадают из тренда
+func (w TrendingWindow) cutoff(now time.Time) float64 {
+	return w.score(trendingViewWeight, now.Add(-w.Span))
+}
+
+func (w TrendingWindow) key(tag string) string {
+	if tag == "" {
+		return fmt.Sprintf(keyTrending, w.Name)
+	}
+	return fmt.Sprintf(keyTrendingTag, w.Name, tag)
+}
+
+// TrendingPosts возвращает опубликованные посты в порядке убывания очков тренда (tag пустой — все теги)
+func (s *PostsService) TrendingPosts(ctx context.Context, windowName, tag string, limit, viewerID int) ([]Post, error) {
+	window, err := ParseTrendingWindow(windowName)
+	if err != nil {
+		return nil, err
+	}
+	limit = clampLimit(limit)
+
+	ids, err := s.metricsService.TrendingIDs(ctx, window, tag, limit, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return []Post{}, nil
+	}
+
+	found, err := s.repo.List(ctx, PostFilter{IDs: ids, OnlyActive: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load trending posts: %w", err)
+	}
+
+	byID := make(map[int]Post, len(found))
+	for _, post := range found {
+		byID[post.ID] = post
+	}
+	posts := make([]Post, 0, len(found))
+	for _, id := range ids {
+		if post, ok := byID[id]; ok {
+			posts = append(posts, post)
+		}
+	}
+
+	s.applyMetrics(ctx, posts, viewerID)
+	s.attachTags(ctx, posts)
+	return posts, nil
+}
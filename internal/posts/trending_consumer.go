@@ -0,0 +1,125 @@
+package posts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mpb/configs"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+// TrendingConsumer начисляет очки тренда по событиям просмотров, лайков и комментариев.
+// Очки копятся в памяти и раз в flushInterval записываются в Redis одним pipeline.
+type TrendingConsumer struct {
+	repo          PostsRepositoryInterface
+	metrics       *MetricsService
+	logger        watermill.LoggerAdapter
+	flushInterval time.Duration
+}
+
+func NewTrendingConsumer(repo PostsRepositoryInterface, metrics *MetricsService, logger watermill.LoggerAdapter, conf configs.PostsConfig) *TrendingConsumer {
+	return &TrendingConsumer{
+		repo:          repo,
+		metrics:       metrics,
+		logger:        logger,
+		flushInterval: conf.TrendingFlushInterval,
+	}
+}
+
+// trendingEvent — общая часть событий post.viewed, post.liked и comment.created
+type trendingEvent struct {
+	PostID int `json:"post_id"`
+}
+
+func (c *TrendingConsumer) Start(subscriber message.Subscriber) error {
+	weights := map[string]float64{
+		"post.viewed":     trendingViewWeight,
+		"post.liked":      trendingLikeWeight,
+		"comment.created": trendingCommentWeight,
+	}
+
+	hits := make(chan trendingHit)
+	for topic, weight := range weights {
+		messages, err := subscriber.Subscribe(context.Background(), topic)
+		if err != nil {
+			return fmt.Errorf("failed to subscribe to %s: %w", topic, err)
+		}
+		go c.forward(messages, weight, hits)
+	}
+
+	go c.consume(hits)
+	return nil
+}
+
+type trendingHit struct {
+	postID int
+	weight float64
+}
+
+func (c *TrendingConsumer) forward(messages <-chan *message.Message, weight float64, hits chan<- trendingHit) {
+	for msg := range messages {
+		// повторная доставка битое событие не исправит, поэтому оно подтверждается и отбрасывается
+		var event trendingEvent
+		if err := json.Unmarshal(msg.Payload, &event); err != nil {
+			c.logger.Error("dropping malformed trending event", err, watermill.LogFields{"message_uuid": msg.UUID})
+			msg.Ack()
+			continue
+		}
+		if event.PostID == 0 {
+			c.logger.Error("dropping malformed trending event", errors.New("post_id is missing"), watermill.LogFields{"message_uuid": msg.UUID})
+			msg.Ack()
+			continue
+		}
+		hits <- trendingHit{postID: event.PostID, weight: weight}
+		msg.Ack()
+	}
+}
+
+func (c *TrendingConsumer) consume(hits <-chan trendingHit) {
+	ticker := time.NewTicker(c.flushInterval)
+	defer ticker.Stop()
+
+	pending := make(map[int]float64)
+	for {
+		select {
+		case hit, ok := <-hits:
+			if !ok {
+				c.flush(pending)
+				return
+			}
+			pending[hit.postID] += hit.weight
+		case <-ticker.C:
+			c.flush(pending)
+		}
+	}
+}
+
+// flush записывает накопленные очки; при ошибке они остаются до следующей попытки
+func (c *TrendingConsumer) flush(pending map[int]float64) {
+	if len(pending) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ids := make([]int, 0, len(pending))
+	for id := range pending {
+		ids = append(ids, id)
+	}
+	tags, err := c.repo.TagsByPostIDs(ctx, ids)
+	if err != nil {
+		c.logger.Error("failed to load tags for trending", err, nil)
+		return
+	}
+
+	if err := c.metrics.AddTrending(ctx, pending, tags, time.Now()); err != nil {
+		c.logger.Error("failed to flush trending", err, watermill.LogFields{"posts": len(pending)})
+		return
+	}
+	clear(pending)
+}
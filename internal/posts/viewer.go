@@ -0,0 +1,32 @@
+package posts
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strconv"
+)
+
+// crawlerUserAgents — краулеры, мониторинг и служебные клиенты, чьи запросы не считаются просмотрами
+var crawlerUserAgents = regexp.MustCompile(`(?i)bot|crawl|spider|slurp|facebookexternalhit|preview|curl|wget|python-requests|go-http-client|okhttp|headless|lighthouse|pingdom|uptime|kube-probe|healthcheck`)
+
+// Viewer описывает того, кто открыл пост: авторизованного пользователя или анонима по IP и User-Agent
+type Viewer struct {
+	UserID    int
+	IP        string
+	UserAgent string
+}
+
+// IsCrawler сообщает, что запрос пришёл от бота или служебного клиента
+func (v Viewer) IsCrawler() bool {
+	return v.UserAgent == "" || crawlerUserAgents.MatchString(v.UserAgent)
+}
+
+// Fingerprint возвращает стабильный идентификатор зрителя для дедупликации просмотров
+func (v Viewer) Fingerprint() string {
+	if v.UserID > 0 {
+		return "u:" + strconv.Itoa(v.UserID)
+	}
+	sum := sha256.Sum256([]byte(v.IP + "|" + v.UserAgent))
+	return "a:" + hex.EncodeToString(sum[:12])
+}
@@ -0,0 +1,45 @@
+package posts
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestViewer_IsCrawler(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		crawler   bool
+	}{
+		{"Mozilla/5.0 (X11; Linux x86_64) Firefox/128.0", false},
+		{"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", true},
+		{"curl/8.5.0", true},
+		{"Go-http-client/1.1", true},
+		{"mpb-healthcheck", true},
+		{"", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.userAgent, func(t *testing.T) {
+			assert.Equal(t, tt.crawler, Viewer{UserAgent: tt.userAgent}.IsCrawler())
+		})
+	}
+}
+
+func TestViewer_Fingerprint(t *testing.T) {
+	anon := Viewer{IP: "10.0.0.1", UserAgent: "Firefox"}
+
+	assert.Equal(t, "u:7", Viewer{UserID: 7, IP: "10.0.0.1", UserAgent: "Firefox"}.Fingerprint())
+	assert.Equal(t, anon.Fingerprint(), anon.Fingerprint())
+	assert.NotEqual(t, anon.Fingerprint(), Viewer{IP: "10.0.0.2", UserAgent: "Firefox"}.Fingerprint())
+	assert.Regexp(t, `^a:[0-9a-f]{24}$`, anon.Fingerprint())
+}
+
+func TestMetricsService_RecordViewSkipsAuthorAndCrawlers(t *testing.T) {
+	metrics := &MetricsService{}
+	post := &Post{ID: 1, UserID: 5}
+
+	assert.NoError(t, metrics.RecordView(context.Background(), post, Viewer{UserID: 5, UserAgent: "Firefox"}))
+	assert.NoError(t, metrics.RecordView(context.Background(), post, Viewer{IP: "10.0.0.1", UserAgent: "Googlebot"}))
+}
@@ -1,19 +1,20 @@
 package stories
 
 import (
+	"mpb/pkg/jwtkeys"
 	"mpb/pkg/middleware"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type StoriesRoutes struct {
-	router    fiber.Router
-	handler   *StoriesHandlers
-	jwtSecret []byte
+	router  fiber.Router
+	handler *StoriesHandlers
+	jwtKeys *jwtkeys.KeySet
 }
 
-func NewStoriesRoutes(router fiber.Router, handler *StoriesHandlers, jwtSecret []byte) *StoriesRoutes {
-	return &StoriesRoutes{router: router, handler: handler, jwtSecret: jwtSecret}
+func NewStoriesRoutes(router fiber.Router, handler *StoriesHandlers, jwtKeys *jwtkeys.KeySet) *StoriesRoutes {
+	return &StoriesRoutes{router: router, handler: handler, jwtKeys: jwtKeys}
 }
 
 func (r *StoriesRoutes) Register() {
@@ -23,7 +24,7 @@ func (r *StoriesRoutes) Register() {
 	stories.Get("/:id", r.handler.GetStory)
 	stories.Get("/user/:id", r.handler.ListUserStories)
 
-	authGroup := stories.Group("/", middleware.JWTAuth(r.jwtSecret))
+	authGroup := stories.Group("/", middleware.JWTAuth(r.jwtKeys))
 	authGroup.Post("/", r.handler.CreateStory)
 	authGroup.Post("/:id/view", r.handler.ViewStory)
 	authGroup.Delete("/:id", r.handler.DeleteStory)
@@ -0,0 +1,45 @@
+package dto
+
+import (
+	postsdto "mpb/internal/posts/dto"
+	"time"
+)
+
+type ListTagsQuery struct {
+	Q      *string `query:"q" validate:"omitempty,min=1,max=50"`
+	Limit  int     `query:"limit" validate:"omitempty,min=1,max=100"`
+	Offset int     `query:"offset" validate:"omitempty,min=0"`
+}
+
+type TagPostsQuery struct {
+	Limit  int    `query:"limit" validate:"omitempty,min=1,max=100"`
+	Cursor string `query:"cursor" validate:"omitempty,max=512"`
+}
+
+type MergeTagRequest struct {
+	Into string `json:"into" validate:"required,min=1,max=50"`
+}
+
+type AliasTagRequest struct {
+	Target string `json:"target" validate:"required,min=1,max=50"`
+}
+
+type TagResponse struct {
+	ID         int       `json:"id"`
+	Name       string    `json:"name"`
+	Slug       string    `json:"slug"`
+	PostsCount int       `json:"posts_count,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type TagPostsResponse struct {
+	Tag        TagResponse             `json:"tag"`
+	Data       []postsdto.PostResponse `json:"data"`
+	NextCursor string                  `json:"next_cursor,omitempty"`
+}
+
+type TagTrendingResponse struct {
+	Tag    TagResponse             `json:"tag"`
+	Window string                  `json:"window"`
+	Data   []postsdto.PostResponse `json:"data"`
+}
@@ -0,0 +1,221 @@
+package tags
+
+import (
+	"errors"
+	"mpb/internal/posts"
+	postsdto "mpb/internal/posts/dto"
+	"mpb/internal/tags/dto"
+	"mpb/pkg/errors_constant"
+	"mpb/pkg/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type TagsHandlers struct {
+	service      *TagsService
+	postsService *posts.PostsService
+}
+
+func NewTagsHandlers(service *TagsService, postsService *posts.PostsService) *TagsHandlers {
+	return &TagsHandlers{service: service, postsService: postsService}
+}
+
+// ListTags godoc
+// @Summary List tags with usage counts
+// @Tags Tags
+// @Produce json
+// @Param q query string false "Slug prefix"
+// @Param limit query int false "Page size (1-100)"
+// @Param offset query int false "Offset"
+// @Success 200 {array} dto.TagResponse
+// @Router /api/tags [get]
+func (h *TagsHandlers) ListTags(c *fiber.Ctx) error {
+	query := middleware.Query[dto.ListTagsQuery](c)
+	if query == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid query parameters"})
+	}
+
+	limit := query.Limit
+	if limit == 0 {
+		limit = 50
+	}
+
+	tags, err := h.service.ListTags(c.Context(), TagFilter{Prefix: query.Q, Limit: limit, Offset: query.Offset})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	response := make([]dto.TagResponse, len(tags))
+	for i := range tags {
+		response[i] = tagToResponse(&tags[i].Tag)
+		response[i].PostsCount = tags[i].PostsCount
+	}
+	return c.JSON(response)
+}
+
+// GetTagPosts godoc
+// @Summary List posts with a tag
+// @Tags Tags
+// @Produce json
+// @Param slug path string true "Tag slug"
+// @Param limit query int false "Page size (1-100, default 20)"
+// @Param cursor query string false "Cursor from next_cursor"
+// @Success 200 {object} dto.TagPostsResponse
+// @Failure 404 {object} map[string]string
+// @Router /api/tags/{slug}/posts [get]
+func (h *TagsHandlers) GetTagPosts(c *fiber.Ctx) error {
+	query := middleware.Query[dto.TagPostsQuery](c)
+	if query == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid query parameters"})
+	}
+
+	tag, err := h.service.GetTag(c.Context(), c.Params("slug"))
+	if err != nil {
+		if errors.Is(err, errors_constant.TagNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "tag not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	filter := posts.PostFilter{Tag: &tag.Slug, OnlyActive: true, Limit: query.Limit}
+	if query.Cursor != "" {
+		cursor, err := posts.DecodePostCursor(query.Cursor)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		filter.Cursor = cursor
+	}
+
+	page, err := h.postsService.ListPostsPage(c.Context(), filter)
+	if err != nil {
+		if errors.Is(err, errors_constant.InvalidCursor) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	response := dto.TagPostsResponse{
+		Tag:        tagToResponse(tag),
+		Data:       make([]postsdto.PostResponse, len(page.Posts)),
+		NextCursor: page.NextCursor,
+	}
+	for i := range page.Posts {
+		response.Data[i] = posts.PostToResponse(&page.Posts[i])
+	}
+
+	if page.NextCursor != "" {
+		c.Links(posts.NextPageURL(c, page.NextCursor), "next")
+	}
+	return c.JSON(response)
+}
+
+// GetTagTrending godoc
+// @Summary Trending posts with a tag
+// @Tags Tags
+// @Produce json
+// @Param slug path string true "Tag slug"
+// @Param window query string false "Window: 24h (default) or 7d"
+// @Param limit query int false "Page size (1-100, default 20)"
+// @Success 200 {object} dto.TagTrendingResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/tags/{slug}/trending [get]
+func (h *TagsHandlers) GetTagTrending(c *fiber.Ctx) error {
+	query := middleware.Query[postsdto.TrendingQuery](c)
+	if query == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid query parameters"})
+	}
+
+	tag, err := h.service.GetTag(c.Context(), c.Params("slug"))
+	if err != nil {
+		if errors.Is(err, errors_constant.TagNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "tag not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	trending, err := h.postsService.TrendingPosts(c.Context(), query.Window, tag.Slug, query.Limit, 0)
+	if err != nil {
+		if errors.Is(err, errors_constant.InvalidTrendingWindow) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	page := posts.TrendingToResponse(query.Window, trending)
+	return c.JSON(dto.TagTrendingResponse{Tag: tagToResponse(tag), Window: page.Window, Data: page.Data})
+}
+
+// MergeTag godoc
+// @Summary Merge a tag into another one (admin)
+// @Description Moves all posts of the tag to the target tag and deletes it.
+// @Tags Tags
+// @Accept json
+// @Produce json
+// @Param slug path string true "Tag slug to merge"
+// @Param request body dto.MergeTagRequest true "Target tag"
+// @Success 200 {object} dto.TagResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/tags/{slug}/merge [post]
+func (h *TagsHandlers) MergeTag(c *fiber.Ctx) error {
+	req := middleware.Body[dto.MergeTagRequest](c)
+	if req == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+	}
+
+	tag, err := h.service.MergeTags(c.Context(), c.Params("slug"), req.Into)
+	if err != nil {
+		return tagError(c, err)
+	}
+
+	return c.JSON(tagToResponse(tag))
+}
+
+// AliasTag godoc
+// @Summary Make a tag an alias of another one (admin)
+// @Description Moves existing posts to the target tag; posts created with the alias later are tagged with the target.
+// @Tags Tags
+// @Accept json
+// @Produce json
+// @Param slug path string true "Alias slug"
+// @Param request body dto.AliasTagRequest true "Target tag"
+// @Success 200 {object} dto.TagResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/tags/{slug}/alias [post]
+func (h *TagsHandlers) AliasTag(c *fiber.Ctx) error {
+	req := middleware.Body[dto.AliasTagRequest](c)
+	if req == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+	}
+
+	tag, err := h.service.AliasTag(c.Context(), c.Params("slug"), req.Target)
+	if err != nil {
+		return tagError(c, err)
+	}
+
+	return c.JSON(tagToResponse(tag))
+}
+
+func tagError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, errors_constant.TagNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "tag not found"})
+	case errors.Is(err, errors_constant.TagMergeConflict), errors.Is(err, errors_constant.InvalidTag):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+}
+
+func tagToResponse(tag *Tag) dto.TagResponse {
+	return dto.TagResponse{
+		ID:        tag.ID,
+		Name:      tag.Name,
+		Slug:      tag.Slug,
+		CreatedAt: tag.CreatedAt,
+	}
+}
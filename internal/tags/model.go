@@ -0,0 +1,22 @@
+package tags
+
+import "time"
+
+type Tag struct {
+	ID        int       `db:"id"`
+	Name      string    `db:"name"`
+	Slug      string    `db:"slug"`
+	AliasOf   *int      `db:"alias_of"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type TagWithCount struct {
+	Tag
+	PostsCount int `db:"posts_count"`
+}
+
+type TagFilter struct {
+	Prefix *string
+	Limit  int
+	Offset int
+}
@@ -0,0 +1,138 @@
+package tags
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"mpb/pkg/db"
+	"mpb/pkg/errors_constant"
+)
+
+type TagsRepository struct {
+	db *db.Db
+}
+
+func NewTagsRepository(db *db.Db) *TagsRepository {
+	return &TagsRepository{db: db}
+}
+
+// List возвращает канонические теги с количеством опубликованных постов
+func (r *TagsRepository) List(ctx context.Context, f TagFilter) ([]TagWithCount, error) {
+	query := `
+		SELECT t.id, t.name, t.slug, t.alias_of, t.created_at, COUNT(p.id) AS posts_count
+		FROM tags t
+		LEFT JOIN post_tags pt ON pt.tag_id = t.id
+		LEFT JOIN posts p ON p.id = pt.post_id AND p.deleted_at IS NULL AND p.status = 'published'
+		WHERE t.alias_of IS NULL`
+	var args []interface{}
+
+	if f.Prefix != nil {
+		args = append(args, *f.Prefix+"%")
+		query += fmt.Sprintf(" AND t.slug LIKE $%d", len(args))
+	}
+
+	query += " GROUP BY t.id ORDER BY posts_count DESC, t.slug"
+
+	if f.Limit > 0 {
+		args = append(args, f.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if f.Offset > 0 {
+		args = append(args, f.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	var tags []TagWithCount
+	if err := r.db.Conn.SelectContext(ctx, &tags, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+
+	return tags, nil
+}
+
+func (r *TagsRepository) FindBySlug(ctx context.Context, slug string) (*Tag, error) {
+	var tag Tag
+	const query = `SELECT id, name, slug, alias_of, created_at FROM tags WHERE slug = $1`
+	if err := r.db.Conn.GetContext(ctx, &tag, query, slug); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors_constant.TagNotFound
+		}
+		return nil, fmt.Errorf("failed to find tag by slug: %w", err)
+	}
+	return &tag, nil
+}
+
+func (r *TagsRepository) FindByID(ctx context.Context, id int) (*Tag, error) {
+	var tag Tag
+	const query = `SELECT id, name, slug, alias_of, created_at FROM tags WHERE id = $1`
+	if err := r.db.Conn.GetContext(ctx, &tag, query, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors_constant.TagNotFound
+		}
+		return nil, fmt.Errorf("failed to find tag by id: %w", err)
+	}
+	return &tag, nil
+}
+
+func (r *TagsRepository) Create(ctx context.Context, tag *Tag) error {
+	const query = `
+		INSERT INTO tags (name, slug) VALUES ($1, $2)
+		RETURNING id, created_at
+	`
+	if err := r.db.Conn.QueryRowContext(ctx, query, tag.Name, tag.Slug).Scan(&tag.ID, &tag.CreatedAt); err != nil {
+		return fmt.Errorf("failed to create tag: %w", err)
+	}
+	return nil
+}
+
+// Merge переносит посты и синонимы тега sourceID на targetID.
+// Если keepAlias, source остаётся синонимом target, иначе удаляется.
+func (r *TagsRepository) Merge(ctx context.Context, sourceID, targetID int, keepAlias bool) error {
+	tx, err := r.db.Conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	const movePosts = `
+		INSERT INTO post_tags (post_id, tag_id)
+		SELECT post_id, $2 FROM post_tags WHERE tag_id = $1
+		ON CONFLICT DO NOTHING
+	`
+	if _, err := tx.ExecContext(ctx, movePosts, sourceID, targetID); err != nil {
+		return fmt.Errorf("failed to move tagged posts: %w", err)
+	}
+
+	// posts.tag хранит первый тег для старых клиентов — переименовываем и его
+	const renamePrimary = `
+		UPDATE posts p SET tag = target.name
+		FROM tags source, tags target
+		WHERE source.id = $1 AND target.id = $2 AND p.tag = source.name
+	`
+	if _, err := tx.ExecContext(ctx, renamePrimary, sourceID, targetID); err != nil {
+		return fmt.Errorf("failed to rename primary tag: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE tags SET alias_of = $2 WHERE alias_of = $1`, sourceID, targetID); err != nil {
+		return fmt.Errorf("failed to repoint aliases: %w", err)
+	}
+
+	if keepAlias {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM post_tags WHERE tag_id = $1`, sourceID); err != nil {
+			return fmt.Errorf("failed to detach alias posts: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE tags SET alias_of = $2 WHERE id = $1`, sourceID, targetID); err != nil {
+			return fmt.Errorf("failed to mark tag as alias: %w", err)
+		}
+	} else {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM tags WHERE id = $1`, sourceID); err != nil {
+			return fmt.Errorf("failed to delete merged tag: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit tag merge: %w", err)
+	}
+	return nil
+}
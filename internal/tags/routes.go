@@ -0,0 +1,33 @@
+package tags
+
+import (
+	postsdto "mpb/internal/posts/dto"
+	"mpb/internal/tags/dto"
+	"mpb/internal/user"
+	"mpb/pkg/jwtkeys"
+	"mpb/pkg/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type TagsRoutes struct {
+	router  fiber.Router
+	handler *TagsHandlers
+	jwtKeys *jwtkeys.KeySet
+}
+
+func NewTagsRoutes(router fiber.Router, handler *TagsHandlers, jwtKeys *jwtkeys.KeySet) *TagsRoutes {
+	return &TagsRoutes{router: router, handler: handler, jwtKeys: jwtKeys}
+}
+
+func (r *TagsRoutes) Register() {
+	tags := r.router.Group("/tags")
+
+	tags.Get("/", middleware.ValidateQuery[dto.ListTagsQuery](), r.handler.ListTags)
+	tags.Get("/:slug/posts", middleware.ValidateQuery[dto.TagPostsQuery](), r.handler.GetTagPosts)
+	tags.Get("/:slug/trending", middleware.ValidateQuery[postsdto.TrendingQuery](), r.handler.GetTagTrending)
+
+	admin := tags.Group("/", middleware.JWTAuth(r.jwtKeys), middleware.RequireRole(user.RoleAdmin))
+	admin.Post("/:slug/merge", middleware.ValidateBody[dto.MergeTagRequest](), r.handler.MergeTag)
+	admin.Post("/:slug/alias", middleware.ValidateBody[dto.AliasTagRequest](), r.handler.AliasTag)
+}
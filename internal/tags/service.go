@@ -0,0 +1,95 @@
+package tags
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mpb/pkg/errors_constant"
+	"mpb/pkg/slug"
+	"strings"
+)
+
+type TagsRepositoryInterface interface {
+	List(ctx context.Context, f TagFilter) ([]TagWithCount, error)
+	FindBySlug(ctx context.Context, slug string) (*Tag, error)
+	FindByID(ctx context.Context, id int) (*Tag, error)
+	Create(ctx context.Context, tag *Tag) error
+	Merge(ctx context.Context, sourceID, targetID int, keepAlias bool) error
+}
+
+type TagsService struct {
+	repo TagsRepositoryInterface
+}
+
+func NewTagsService(repo TagsRepositoryInterface) *TagsService {
+	return &TagsService{repo: repo}
+}
+
+func (s *TagsService) ListTags(ctx context.Context, f TagFilter) ([]TagWithCount, error) {
+	return s.repo.List(ctx, f)
+}
+
+// GetTag находит тег по slug и возвращает канонический тег, если это синоним
+func (s *TagsService) GetTag(ctx context.Context, tagSlug string) (*Tag, error) {
+	tag, err := s.repo.FindBySlug(ctx, slug.Make(tagSlug))
+	if err != nil {
+		return nil, err
+	}
+	if tag.AliasOf != nil {
+		return s.repo.FindByID(ctx, *tag.AliasOf)
+	}
+	return tag, nil
+}
+
+// MergeTags переносит все посты source в target и удаляет source
+func (s *TagsService) MergeTags(ctx context.Context, sourceSlug, targetSlug string) (*Tag, error) {
+	source, err := s.repo.FindBySlug(ctx, slug.Make(sourceSlug))
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := s.GetTag(ctx, targetSlug)
+	if err != nil {
+		return nil, err
+	}
+	if source.ID == target.ID {
+		return nil, errors_constant.TagMergeConflict
+	}
+
+	if err := s.repo.Merge(ctx, source.ID, target.ID, false); err != nil {
+		return nil, fmt.Errorf("failed to merge tags: %w", err)
+	}
+
+	return target, nil
+}
+
+// AliasTag делает aliasSlug синонимом target: существующие посты переносятся,
+// новые посты с этим тегом сразу получают target. Тег-синоним создаётся, если его ещё нет.
+func (s *TagsService) AliasTag(ctx context.Context, aliasSlug, targetSlug string) (*Tag, error) {
+	target, err := s.GetTag(ctx, targetSlug)
+	if err != nil {
+		return nil, err
+	}
+
+	aliasName := strings.Join(strings.Fields(strings.ToLower(aliasSlug)), " ")
+	alias, err := s.repo.FindBySlug(ctx, slug.Make(aliasName))
+	if errors.Is(err, errors_constant.TagNotFound) {
+		alias = &Tag{Name: aliasName, Slug: slug.Make(aliasName)}
+		if alias.Slug == "" {
+			return nil, errors_constant.InvalidTag
+		}
+		err = s.repo.Create(ctx, alias)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if alias.ID == target.ID {
+		return nil, errors_constant.TagMergeConflict
+	}
+
+	if err := s.repo.Merge(ctx, alias.ID, target.ID, true); err != nil {
+		return nil, fmt.Errorf("failed to alias tag: %w", err)
+	}
+
+	return target, nil
+}
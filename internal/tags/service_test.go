@@ -0,0 +1,167 @@
+package tags
+
+import (
+	"context"
+	"mpb/pkg/errors_constant"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockTagsRepository struct {
+	mock.Mock
+}
+
+func (m *MockTagsRepository) List(ctx context.Context, f TagFilter) ([]TagWithCount, error) {
+	args := m.Called(ctx, f)
+	return args.Get(0).([]TagWithCount), args.Error(1)
+}
+
+func (m *MockTagsRepository) FindBySlug(ctx context.Context, slug string) (*Tag, error) {
+	args := m.Called(ctx, slug)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Tag), args.Error(1)
+}
+
+func (m *MockTagsRepository) FindByID(ctx context.Context, id int) (*Tag, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Tag), args.Error(1)
+}
+
+func (m *MockTagsRepository) Create(ctx context.Context, tag *Tag) error {
+	args := m.Called(ctx, tag)
+	if args.Error(0) == nil {
+		tag.ID = 99
+	}
+	return args.Error(0)
+}
+
+func (m *MockTagsRepository) Merge(ctx context.Context, sourceID, targetID int, keepAlias bool) error {
+	return m.Called(ctx, sourceID, targetID, keepAlias).Error(0)
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func TestTagsService_GetTag(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("normalises slug", func(t *testing.T) {
+		repo := new(MockTagsRepository)
+		repo.On("FindBySlug", ctx, "machine-learning").Return(&Tag{ID: 1, Slug: "machine-learning"}, nil)
+		service := NewTagsService(repo)
+
+		tag, err := service.GetTag(ctx, "  Machine Learning ")
+		require.NoError(t, err)
+		assert.Equal(t, 1, tag.ID)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("alias resolves to canonical tag", func(t *testing.T) {
+		repo := new(MockTagsRepository)
+		repo.On("FindBySlug", ctx, "golang").Return(&Tag{ID: 2, Slug: "golang", AliasOf: intPtr(1)}, nil)
+		repo.On("FindByID", ctx, 1).Return(&Tag{ID: 1, Slug: "go"}, nil)
+		service := NewTagsService(repo)
+
+		tag, err := service.GetTag(ctx, "golang")
+		require.NoError(t, err)
+		assert.Equal(t, "go", tag.Slug)
+	})
+
+	t.Run("unknown tag", func(t *testing.T) {
+		repo := new(MockTagsRepository)
+		repo.On("FindBySlug", ctx, "nope").Return(nil, errors_constant.TagNotFound)
+		service := NewTagsService(repo)
+
+		_, err := service.GetTag(ctx, "nope")
+		assert.ErrorIs(t, err, errors_constant.TagNotFound)
+	})
+}
+
+func TestTagsService_MergeTags(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("merges source into canonical target", func(t *testing.T) {
+		repo := new(MockTagsRepository)
+		repo.On("FindBySlug", ctx, "js").Return(&Tag{ID: 3, Slug: "js"}, nil)
+		repo.On("FindBySlug", ctx, "ecmascript").Return(&Tag{ID: 5, Slug: "ecmascript", AliasOf: intPtr(4)}, nil)
+		repo.On("FindByID", ctx, 4).Return(&Tag{ID: 4, Slug: "javascript"}, nil)
+		repo.On("Merge", ctx, 3, 4, false).Return(nil)
+		service := NewTagsService(repo)
+
+		tag, err := service.MergeTags(ctx, "JS", "ecmascript")
+		require.NoError(t, err)
+		assert.Equal(t, "javascript", tag.Slug)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("tag into itself", func(t *testing.T) {
+		repo := new(MockTagsRepository)
+		repo.On("FindBySlug", ctx, "go").Return(&Tag{ID: 1, Slug: "go"}, nil)
+		service := NewTagsService(repo)
+
+		_, err := service.MergeTags(ctx, "go", "go")
+		assert.ErrorIs(t, err, errors_constant.TagMergeConflict)
+		repo.AssertNotCalled(t, "Merge", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestTagsService_AliasTag(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("creates missing alias", func(t *testing.T) {
+		repo := new(MockTagsRepository)
+		repo.On("FindBySlug", ctx, "go").Return(&Tag{ID: 1, Slug: "go"}, nil)
+		repo.On("FindBySlug", ctx, "go-lang").Return(nil, errors_constant.TagNotFound)
+		repo.On("Create", ctx, mock.MatchedBy(func(tag *Tag) bool {
+			return tag.Name == "go lang" && tag.Slug == "go-lang"
+		})).Return(nil)
+		repo.On("Merge", ctx, 99, 1, true).Return(nil)
+		service := NewTagsService(repo)
+
+		tag, err := service.AliasTag(ctx, " Go   Lang", "go")
+		require.NoError(t, err)
+		assert.Equal(t, 1, tag.ID)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("existing tag becomes alias", func(t *testing.T) {
+		repo := new(MockTagsRepository)
+		repo.On("FindBySlug", ctx, "go").Return(&Tag{ID: 1, Slug: "go"}, nil)
+		repo.On("FindBySlug", ctx, "golang").Return(&Tag{ID: 2, Slug: "golang"}, nil)
+		repo.On("Merge", ctx, 2, 1, true).Return(nil)
+		service := NewTagsService(repo)
+
+		_, err := service.AliasTag(ctx, "golang", "go")
+		require.NoError(t, err)
+		repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("alias without letters", func(t *testing.T) {
+		repo := new(MockTagsRepository)
+		repo.On("FindBySlug", ctx, "go").Return(&Tag{ID: 1, Slug: "go"}, nil)
+		repo.On("FindBySlug", ctx, "").Return(nil, errors_constant.TagNotFound)
+		service := NewTagsService(repo)
+
+		_, err := service.AliasTag(ctx, "!!", "go")
+		assert.ErrorIs(t, err, errors_constant.InvalidTag)
+	})
+
+	t.Run("alias of itself", func(t *testing.T) {
+		repo := new(MockTagsRepository)
+		repo.On("FindBySlug", ctx, "go").Return(&Tag{ID: 1, Slug: "go"}, nil)
+		service := NewTagsService(repo)
+
+		_, err := service.AliasTag(ctx, "Go", "go")
+		assert.ErrorIs(t, err, errors_constant.TagMergeConflict)
+	})
+}
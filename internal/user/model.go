@@ -2,15 +2,23 @@ package user
 
 import "time"
 
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
 type User struct {
-	ID           int        `db:"id"`
-	Name         string     `db:"name"`
-	Username     string     `db:"username"`
-	PasswordHash string     `db:"password_hash"`
-	Email        *string    `db:"email"`
-	Age          int        `db:"age"`
-	IsActive     bool       `db:"is_active"`
-	CreatedAt    time.Time  `db:"created_at"`
-	UpdatedAt    time.Time  `db:"updated_at"`
-	DeletedAt    *time.Time `db:"deleted_at"`
+	ID              int        `db:"id"`
+	Name            string     `db:"name"`
+	Username        string     `db:"username"`
+	PasswordHash    string     `db:"password_hash"`
+	Email           *string    `db:"email"`
+	EmailVerifiedAt *time.Time `db:"email_verified_at"`
+	Age             int        `db:"age"`
+	IsActive        bool       `db:"is_active"`
+	Role            string     `db:"role"`
+	CreatedAt       time.Time  `db:"created_at"`
+	UpdatedAt       time.Time  `db:"updated_at"`
+	DeletedAt       *time.Time `db:"deleted_at"`
 }
@@ -1,19 +1,20 @@
 package user_attachments
 
 import (
+	"mpb/pkg/jwtkeys"
 	"mpb/pkg/middleware"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type UserAttachmentsRoutes struct {
-	router    fiber.Router
-	handler   *UserAttachmentsHandlers
-	jwtSecret []byte
+	router  fiber.Router
+	handler *UserAttachmentsHandlers
+	jwtKeys *jwtkeys.KeySet
 }
 
-func NewUserAttachmentsRoutes(router fiber.Router, handler *UserAttachmentsHandlers, jwtSecret []byte) *UserAttachmentsRoutes {
-	return &UserAttachmentsRoutes{router: router, handler: handler, jwtSecret: jwtSecret}
+func NewUserAttachmentsRoutes(router fiber.Router, handler *UserAttachmentsHandlers, jwtKeys *jwtkeys.KeySet) *UserAttachmentsRoutes {
+	return &UserAttachmentsRoutes{router: router, handler: handler, jwtKeys: jwtKeys}
 }
 
 func (r *UserAttachmentsRoutes) Register() {
@@ -21,7 +22,7 @@ func (r *UserAttachmentsRoutes) Register() {
 
 	group.Get("/:id/attachments", r.handler.GetAttachments)
 
-	authGroup := group.Group("/", middleware.JWTAuth(r.jwtSecret))
+	authGroup := group.Group("/", middleware.JWTAuth(r.jwtKeys))
 	authGroup.Post("/:id/attachments", r.handler.UploadAttachments)
 	authGroup.Delete("/attachments/:id", r.handler.DeleteAttachment)
 }
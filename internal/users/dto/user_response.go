@@ -3,14 +3,34 @@ package dto
 import "time"
 
 type UserProfileResponse struct {
-	ID               int       `json:"id"`
-	Name             string    `json:"name"`
-	Username         string    `json:"username"`
-	Email            *string   `json:"email,omitempty"`
-	Age              int       `json:"age"`
-	IsActive         bool      `json:"is_active"`
-	PostsCount       int       `json:"posts_count"`
-	AttachmentsCount int       `json:"attachments_count"`
-	CreatedAt        time.Time `json:"created_at"`
-	UpdatedAt        time.Time `json:"updated_at"`
+	ID                int       `json:"id"`
+	Name              string    `json:"name"`
+	Username          string    `json:"username"`
+	Email             *string   `json:"email,omitempty"`
+	Age               int       `json:"age"`
+	IsActive          bool      `json:"is_active"`
+	PostsCount        int       `json:"posts_count"`
+	AttachmentsCount  int       `json:"attachments_count"`
+	FollowersCount    int       `json:"followers_count"`
+	FollowingCount    int       `json:"following_count"`
+	CommentLikesCount int       `json:"comment_likes_count"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+type FollowsQuery struct {
+	Limit  int    `query:"limit" validate:"omitempty,min=1,max=100"`
+	Cursor string `query:"cursor" validate:"omitempty,max=512"`
+}
+
+type FollowUserResponse struct {
+	ID         int       `json:"id"`
+	Username   string    `json:"username"`
+	Name       string    `json:"name"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+type FollowsResponse struct {
+	Data       []FollowUserResponse `json:"data"`
+	NextCursor string               `json:"next_cursor,omitempty"`
 }
@@ -0,0 +1,14 @@
+package users
+
+import "time"
+
+type UserFollowedEvent struct {
+	FollowerID int       `json:"follower_id"`
+	FolloweeID int       `json:"followee_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type UserUnfollowedEvent struct {
+	FollowerID int `json:"follower_id"`
+	FolloweeID int `json:"followee_id"`
+}
@@ -1,22 +1,25 @@
 package users
 
 import (
+	"context"
 	"errors"
 	"mpb/internal/posts"
 	"mpb/internal/posts/dto"
 	usersdto "mpb/internal/users/dto"
 	"mpb/pkg/errors_constant"
+	"mpb/pkg/middleware"
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type UsersHandlers struct {
-	service *UsersService
+	service      *UsersService
+	postsService *posts.PostsService
 }
 
-func NewUsersHandlers(service *UsersService) *UsersHandlers {
-	return &UsersHandlers{service: service}
+func NewUsersHandlers(service *UsersService, postsService *posts.PostsService) *UsersHandlers {
+	return &UsersHandlers{service: service, postsService: postsService}
 }
 
 // GetUserProfile godoc
@@ -59,15 +62,20 @@ func (h *UsersHandlers) GetUserPosts(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid user id"})
 	}
 
+	if err := h.service.EnsureUserExists(c.Context(), id); err != nil {
+		if errors.Is(err, errors_constant.UserNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
 	filter := posts.PostFilter{
+		UserID:     &id,
 		OnlyActive: true,
 	}
 
-	userPosts, err := h.service.GetUserPosts(c.Context(), id, filter)
+	userPosts, err := h.postsService.ListPosts(c.Context(), filter)
 	if err != nil {
-		if errors.Is(err, errors_constant.UserNotFound) {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user not found"})
-		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
@@ -79,6 +87,61 @@ func (h *UsersHandlers) GetUserPosts(c *fiber.Ctx) error {
 	return c.JSON(response)
 }
 
+// GetUserLikes godoc
+// @Summary Get posts liked by user
+// @Tags Users
+// @Produce json
+// @Param id path int true "User ID"
+// @Param limit query int false "Page size (1-100, default 20)"
+// @Param cursor query string false "Cursor from next_cursor"
+// @Success 200 {object} dto.LikedPostsResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/users/{id}/likes [get]
+func (h *UsersHandlers) GetUserLikes(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid user id"})
+	}
+
+	query := middleware.Query[dto.LikesQuery](c)
+	if query == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid query parameters"})
+	}
+
+	var cursor *posts.LikeCursor
+	if query.Cursor != "" {
+		if cursor, err = posts.DecodeLikeCursor(query.Cursor); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+
+	if err := h.service.EnsureUserExists(c.Context(), id); err != nil {
+		if errors.Is(err, errors_constant.UserNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	page, err := h.postsService.ListUserLikes(c.Context(), id, query.Limit, cursor)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	response := dto.LikedPostsResponse{
+		Data:       make([]dto.LikedPostResponse, len(page.Posts)),
+		NextCursor: page.NextCursor,
+	}
+	for i := range page.Posts {
+		response.Data[i] = posts.LikedPostToResponse(&page.Posts[i])
+	}
+
+	if page.NextCursor != "" {
+		c.Links(posts.NextPageURL(c, page.NextCursor), "next")
+	}
+	return c.JSON(response)
+}
+
 // ListUsers godoc
 // @Summary List all users
 // @Tags Users
@@ -95,53 +158,215 @@ func (h *UsersHandlers) ListUsers(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
 
+	ids := make([]int, len(users))
+	for i, u := range users {
+		ids[i] = u.ID
+	}
+	followCounts, err := h.service.FollowCounts(c.Context(), ids)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	commentLikes, err := h.service.CommentLikesCounts(c.Context(), ids)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
 	response := make([]usersdto.UserProfileResponse, len(users))
 	for i, u := range users {
 		postsCount, _ := h.service.repo.GetPostsCount(c.Context(), u.ID)
 		attachmentsCount, _ := h.service.repo.GetAttachmentsCount(c.Context(), u.ID)
 
 		response[i] = usersdto.UserProfileResponse{
-			ID:               u.ID,
-			Name:             u.Name,
-			Username:         u.Username,
-			Email:            u.Email,
-			Age:              u.Age,
-			IsActive:         u.IsActive,
-			PostsCount:       postsCount,
-			AttachmentsCount: attachmentsCount,
-			CreatedAt:        u.CreatedAt,
-			UpdatedAt:        u.UpdatedAt,
+			ID:                u.ID,
+			Name:              u.Name,
+			Username:          u.Username,
+			Email:             u.Email,
+			Age:               u.Age,
+			IsActive:          u.IsActive,
+			PostsCount:        postsCount,
+			AttachmentsCount:  attachmentsCount,
+			FollowersCount:    followCounts[u.ID].Followers,
+			FollowingCount:    followCounts[u.ID].Following,
+			CommentLikesCount: commentLikes[u.ID],
+			CreatedAt:         u.CreatedAt,
+			UpdatedAt:         u.UpdatedAt,
+		}
+	}
+
+	return c.JSON(response)
+}
+
+// FollowUser godoc
+// @Summary Follow a user
+// @Tags Users
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 204
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/users/{id}/follow [post]
+func (h *UsersHandlers) FollowUser(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid user id"})
+	}
+	userID := c.Locals("user_id").(int)
+
+	if err := h.service.FollowUser(c.Context(), userID, id); err != nil {
+		return followError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// UnfollowUser godoc
+// @Summary Unfollow a user
+// @Tags Users
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 204
+// @Failure 404 {object} map[string]interface{}
+// @Security BearerAuth
+// @Router /api/users/{id}/follow [delete]
+func (h *UsersHandlers) UnfollowUser(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid user id"})
+	}
+	userID := c.Locals("user_id").(int)
+
+	if err := h.service.UnfollowUser(c.Context(), userID, id); err != nil {
+		return followError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetFollowers godoc
+// @Summary Get followers of a user
+// @Tags Users
+// @Produce json
+// @Param id path int true "User ID"
+// @Param limit query int false "Page size (1-100, default 20)"
+// @Param cursor query string false "Cursor from next_cursor"
+// @Success 200 {object} usersdto.FollowsResponse
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/users/{id}/followers [get]
+func (h *UsersHandlers) GetFollowers(c *fiber.Ctx) error {
+	return h.listFollows(c, h.service.ListFollowers)
+}
+
+// GetFollowing godoc
+// @Summary Get users followed by a user
+// @Tags Users
+// @Produce json
+// @Param id path int true "User ID"
+// @Param limit query int false "Page size (1-100, default 20)"
+// @Param cursor query string false "Cursor from next_cursor"
+// @Success 200 {object} usersdto.FollowsResponse
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/users/{id}/following [get]
+func (h *UsersHandlers) GetFollowing(c *fiber.Ctx) error {
+	return h.listFollows(c, h.service.ListFollowing)
+}
+
+func (h *UsersHandlers) listFollows(c *fiber.Ctx, list func(context.Context, int, int, *FollowCursor) (*FollowsPage, error)) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid user id"})
+	}
+
+	query := middleware.Query[usersdto.FollowsQuery](c)
+	if query == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid query parameters"})
+	}
+
+	var cursor *FollowCursor
+	if query.Cursor != "" {
+		if cursor, err = DecodeFollowCursor(query.Cursor); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 		}
 	}
 
+	page, err := list(c.Context(), id, query.Limit, cursor)
+	if err != nil {
+		return followError(c, err)
+	}
+
+	response := usersdto.FollowsResponse{
+		Data:       make([]usersdto.FollowUserResponse, len(page.Users)),
+		NextCursor: page.NextCursor,
+	}
+	for i, u := range page.Users {
+		response.Data[i] = usersdto.FollowUserResponse{
+			ID:         u.ID,
+			Username:   u.Username,
+			Name:       u.Name,
+			FollowedAt: u.FollowedAt,
+		}
+	}
+
+	if page.NextCursor != "" {
+		c.Links(posts.NextPageURL(c, page.NextCursor), "next")
+	}
 	return c.JSON(response)
 }
 
+func followError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, errors_constant.CannotFollowSelf):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, errors_constant.UserNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user not found"})
+	case errors.Is(err, errors_constant.NotFollowing):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, errors_constant.AlreadyFollowing):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+}
+
 func profileToResponse(profile *UserProfile) usersdto.UserProfileResponse {
 	return usersdto.UserProfileResponse{
-		ID:               profile.ID,
-		Name:             profile.Name,
-		Username:         profile.Username,
-		Email:            profile.Email,
-		Age:              profile.Age,
-		IsActive:         profile.IsActive,
-		PostsCount:       profile.PostsCount,
-		AttachmentsCount: profile.AttachmentsCount,
-		CreatedAt:        profile.CreatedAt,
-		UpdatedAt:        profile.UpdatedAt,
+		ID:                profile.ID,
+		Name:              profile.Name,
+		Username:          profile.Username,
+		Email:             profile.Email,
+		Age:               profile.Age,
+		IsActive:          profile.IsActive,
+		PostsCount:        profile.PostsCount,
+		AttachmentsCount:  profile.AttachmentsCount,
+		FollowersCount:    profile.FollowersCount,
+		FollowingCount:    profile.FollowingCount,
+		CommentLikesCount: profile.CommentLikesCount,
+		CreatedAt:         profile.CreatedAt,
+		UpdatedAt:         profile.UpdatedAt,
 	}
 }
 
 func convertPostToDTO(post *posts.Post) dto.PostResponse {
 	return dto.PostResponse{
-		ID:           post.ID,
-		UserID:       post.UserID,
-		Title:        post.Title,
-		Description:  post.Description,
-		Tag:          post.Tag,
-		Like:         post.Like,
-		CountViewers: post.CountViewers,
-		CreatedAt:    post.CreatedAt,
-		UpdatedAt:    post.UpdatedAt,
+		ID:                 post.ID,
+		UserID:             post.UserID,
+		Title:              post.Title,
+		Description:        post.Description,
+		DescriptionHTML:    post.DescriptionHTML,
+		Excerpt:            post.Excerpt,
+		WordCount:          post.WordCount,
+		ReadingTimeMinutes: post.ReadingTimeMinutes(),
+		Tag:                post.Tag,
+		Tags:               post.Tags,
+		Like:               post.Like,
+		CountViewers:       post.CountViewers,
+		UniqueViewers:      post.UniqueViewers,
+		CommentsCount:      post.CommentsCount,
+		LikedByMe:          post.LikedByMe,
+		Reactions:          post.Reactions,
+		MyReaction:         post.MyReaction,
+		Status:             post.Status,
+		PublishAt:          post.PublishAt,
+		CreatedAt:          post.CreatedAt,
+		UpdatedAt:          post.UpdatedAt,
 	}
 }
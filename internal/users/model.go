@@ -1,13 +1,64 @@
 package users
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"mpb/internal/user"
+	"mpb/pkg/errors_constant"
+	"time"
 )
 
 type UserProfile struct {
 	user.User
 	PostsCount       int `json:"posts_count"`
 	AttachmentsCount int `json:"attachments_count"`
+	FollowersCount   int `json:"followers_count"`
+	FollowingCount   int `json:"following_count"`
+	// CommentLikesCount — сколько «нравится» получили комментарии пользователя
+	CommentLikesCount int `json:"comment_likes_count"`
+}
+
+// FollowUser — пользователь из списка подписчиков или подписок и время подписки
+type FollowUser struct {
+	ID         int       `db:"id"`
+	Username   string    `db:"username"`
+	Name       string    `db:"name"`
+	FollowedAt time.Time `db:"followed_at"`
+}
+
+// FollowCursor указывает на последнюю строку страницы подписок: время подписки и id пользователя
+type FollowCursor struct {
+	Time time.Time `json:"t"`
+	ID   int       `json:"id"`
+}
+
+func (c FollowCursor) Encode() string {
+	payload, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(payload)
+}
+
+func DecodeFollowCursor(s string) (*FollowCursor, error) {
+	payload, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors_constant.InvalidCursor
+	}
+
+	var cursor FollowCursor
+	if err := json.Unmarshal(payload, &cursor); err != nil || cursor.ID <= 0 || cursor.Time.IsZero() {
+		return nil, errors_constant.InvalidCursor
+	}
+	return &cursor, nil
+}
+
+// FollowCounts — число подписчиков и подписок пользователя
+type FollowCounts struct {
+	Followers int `db:"followers"`
+	Following int `db:"following"`
+}
+
+type FollowsPage struct {
+	Users      []FollowUser
+	NextCursor string
 }
 
 type UserFilter struct {
@@ -6,6 +6,8 @@ import (
 	"mpb/internal/user"
 	"mpb/pkg/db"
 	"strings"
+
+	"github.com/lib/pq"
 )
 
 type UsersRepository struct {
@@ -78,7 +80,7 @@ func (r *UsersRepository) List(ctx context.Context, f UserFilter) ([]user.User,
 
 func (r *UsersRepository) GetPostsCount(ctx context.Context, userID int) (int, error) {
 	var count int
-	const query = `SELECT COUNT(*) FROM posts WHERE user_id = $1 AND deleted_at IS NULL`
+	const query = `SELECT COUNT(*) FROM posts WHERE user_id = $1 AND deleted_at IS NULL AND status = 'published'`
 	if err := r.db.Conn.GetContext(ctx, &count, query, userID); err != nil {
 		return 0, fmt.Errorf("failed to get posts count: %w", err)
 	}
@@ -93,3 +95,144 @@ func (r *UsersRepository) GetAttachmentsCount(ctx context.Context, userID int) (
 	}
 	return count, nil
 }
+
+// GetCommentLikesCount возвращает число «нравится» на неудалённых комментариях пользователя
+func (r *UsersRepository) GetCommentLikesCount(ctx context.Context, userID int) (int, error) {
+	var count int
+	const query = `SELECT COALESCE(SUM("like"), 0) FROM comments WHERE user_id = $1 AND deleted_at IS NULL`
+	if err := r.db.Conn.GetContext(ctx, &count, query, userID); err != nil {
+		return 0, fmt.Errorf("failed to get comment likes count: %w", err)
+	}
+	return count, nil
+}
+
+// GetCommentLikesCountBatch возвращает число «нравится» на неудалённых комментариях нескольких пользователей
+// одним запросом; пользователей без комментариев в результате нет
+func (r *UsersRepository) GetCommentLikesCountBatch(ctx context.Context, userIDs []int) (map[int]int, error) {
+	const query = `
+		SELECT user_id, COALESCE(SUM("like"), 0) AS count
+		FROM comments
+		WHERE user_id = ANY($1) AND deleted_at IS NULL
+		GROUP BY user_id
+	`
+	var rows []struct {
+		UserID int `db:"user_id"`
+		Count  int `db:"count"`
+	}
+	if err := r.db.Conn.SelectContext(ctx, &rows, query, pq.Array(userIDs)); err != nil {
+		return nil, fmt.Errorf("failed to get comment likes count: %w", err)
+	}
+
+	counts := make(map[int]int, len(rows))
+	for _, row := range rows {
+		counts[row.UserID] = row.Count
+	}
+	return counts, nil
+}
+
+// Follow подписывает followerID на followeeID; false — подписка уже была
+func (r *UsersRepository) Follow(ctx context.Context, followerID, followeeID int) (bool, error) {
+	const query = `INSERT INTO follows (follower_id, followee_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+	res, err := r.db.Conn.ExecContext(ctx, query, followerID, followeeID)
+	if err != nil {
+		return false, fmt.Errorf("failed to follow user: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return n > 0, nil
+}
+
+// Unfollow снимает подписку; false — подписки не было
+func (r *UsersRepository) Unfollow(ctx context.Context, followerID, followeeID int) (bool, error) {
+	const query = `DELETE FROM follows WHERE follower_id = $1 AND followee_id = $2`
+	res, err := r.db.Conn.ExecContext(ctx, query, followerID, followeeID)
+	if err != nil {
+		return false, fmt.Errorf("failed to unfollow user: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return n > 0, nil
+}
+
+// ListFollowers возвращает подписчиков пользователя, новые подписки первыми
+func (r *UsersRepository) ListFollowers(ctx context.Context, userID int, cursor *FollowCursor, limit int) ([]FollowUser, error) {
+	return r.listFollows(ctx, "followee_id", "follower_id", userID, cursor, limit)
+}
+
+// ListFollowing возвращает пользователей, на которых подписан userID, новые подписки первыми
+func (r *UsersRepository) ListFollowing(ctx context.Context, userID int, cursor *FollowCursor, limit int) ([]FollowUser, error) {
+	return r.listFollows(ctx, "follower_id", "followee_id", userID, cursor, limit)
+}
+
+func (r *UsersRepository) listFollows(ctx context.Context, ownColumn, otherColumn string, userID int, cursor *FollowCursor, limit int) ([]FollowUser, error) {
+	query := fmt.Sprintf(`
+		SELECT u.id, u.username, u.name, f.created_at AS followed_at
+		FROM follows f
+		JOIN users u ON u.id = f.%[2]s AND u.deleted_at IS NULL
+		WHERE f.%[1]s = $1`, ownColumn, otherColumn)
+	args := []interface{}{userID}
+
+	if cursor != nil {
+		args = append(args, cursor.Time, cursor.ID)
+		query += fmt.Sprintf(" AND (f.created_at, f.%s) < ($2, $3)", otherColumn)
+	}
+
+	args = append(args, limit)
+	query += fmt.Sprintf(" ORDER BY f.created_at DESC, f.%s DESC LIMIT $%d", otherColumn, len(args))
+
+	var users []FollowUser
+	if err := r.db.Conn.SelectContext(ctx, &users, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list follows: %w", err)
+	}
+	return users, nil
+}
+
+// GetFollowCounts возвращает число подписчиков и подписок пользователя
+func (r *UsersRepository) GetFollowCounts(ctx context.Context, userID int) (followers, following int, err error) {
+	const query = `
+		SELECT
+			(SELECT COUNT(*) FROM follows f JOIN users u ON u.id = f.follower_id AND u.deleted_at IS NULL
+			 WHERE f.followee_id = $1) AS followers,
+			(SELECT COUNT(*) FROM follows f JOIN users u ON u.id = f.followee_id AND u.deleted_at IS NULL
+			 WHERE f.follower_id = $1) AS following
+	`
+	if err := r.db.Conn.QueryRowContext(ctx, query, userID).Scan(&followers, &following); err != nil {
+		return 0, 0, fmt.Errorf("failed to get follow counts: %w", err)
+	}
+	return followers, following, nil
+}
+
+// GetFollowCountsBatch возвращает число подписчиков и подписок для нескольких пользователей одним запросом;
+// пользователей без подписок в результате нет
+func (r *UsersRepository) GetFollowCountsBatch(ctx context.Context, userIDs []int) (map[int]FollowCounts, error) {
+	const query = `
+		SELECT user_id, SUM(followers) AS followers, SUM(following) AS following
+		FROM (
+			SELECT f.followee_id AS user_id, 1 AS followers, 0 AS following
+			FROM follows f JOIN users u ON u.id = f.follower_id AND u.deleted_at IS NULL
+			WHERE f.followee_id = ANY($1)
+			UNION ALL
+			SELECT f.follower_id, 0, 1
+			FROM follows f JOIN users u ON u.id = f.followee_id AND u.deleted_at IS NULL
+			WHERE f.follower_id = ANY($1)
+		) c
+		GROUP BY user_id
+	`
+	var rows []struct {
+		UserID int `db:"user_id"`
+		FollowCounts
+	}
+	if err := r.db.Conn.SelectContext(ctx, &rows, query, pq.Array(userIDs)); err != nil {
+		return nil, fmt.Errorf("failed to get follow counts: %w", err)
+	}
+
+	counts := make(map[int]FollowCounts, len(rows))
+	for _, row := range rows {
+		counts[row.UserID] = row.FollowCounts
+	}
+	return counts, nil
+}
@@ -1,18 +1,25 @@
 package users
 
 import (
+	"mpb/internal/posts/dto"
+	usersdto "mpb/internal/users/dto"
+	"mpb/pkg/jwtkeys"
+	"mpb/pkg/middleware"
+
 	"github.com/gofiber/fiber/v2"
 )
 
 type UsersRoutes struct {
 	router  fiber.Router
 	handler *UsersHandlers
+	jwtKeys *jwtkeys.KeySet
 }
 
-func NewUsersRoutes(router fiber.Router, handler *UsersHandlers) *UsersRoutes {
+func NewUsersRoutes(router fiber.Router, handler *UsersHandlers, jwtKeys *jwtkeys.KeySet) *UsersRoutes {
 	return &UsersRoutes{
 		router:  router,
 		handler: handler,
+		jwtKeys: jwtKeys,
 	}
 }
 
@@ -22,4 +29,11 @@ func (r *UsersRoutes) Register() {
 	users.Get("/", r.handler.ListUsers)
 	users.Get("/:id", r.handler.GetUserProfile)
 	users.Get("/:id/posts", r.handler.GetUserPosts)
+	users.Get("/:id/likes", middleware.ValidateQuery[dto.LikesQuery](), r.handler.GetUserLikes)
+	users.Get("/:id/followers", middleware.ValidateQuery[usersdto.FollowsQuery](), r.handler.GetFollowers)
+	users.Get("/:id/following", middleware.ValidateQuery[usersdto.FollowsQuery](), r.handler.GetFollowing)
+
+	res := users.Group("/", middleware.JWTAuth(r.jwtKeys))
+	res.Post("/:id/follow", r.handler.FollowUser)
+	res.Delete("/:id/follow", r.handler.UnfollowUser)
 }
@@ -2,20 +2,50 @@ package users
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"mpb/internal/posts"
 	"mpb/internal/user"
 	"mpb/pkg/errors_constant"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+const (
+	defaultFollowsLimit = 20
+	maxFollowsLimit     = 100
 )
 
+type UsersRepositoryInterface interface {
+	FindByID(ctx context.Context, userID int) (*user.User, error)
+	List(ctx context.Context, f UserFilter) ([]user.User, error)
+	GetPostsCount(ctx context.Context, userID int) (int, error)
+	GetAttachmentsCount(ctx context.Context, userID int) (int, error)
+	GetCommentLikesCount(ctx context.Context, userID int) (int, error)
+	GetCommentLikesCountBatch(ctx context.Context, userIDs []int) (map[int]int, error)
+	Follow(ctx context.Context, followerID, followeeID int) (bool, error)
+	Unfollow(ctx context.Context, followerID, followeeID int) (bool, error)
+	ListFollowers(ctx context.Context, userID int, cursor *FollowCursor, limit int) ([]FollowUser, error)
+	ListFollowing(ctx context.Context, userID int, cursor *FollowCursor, limit int) ([]FollowUser, error)
+	GetFollowCounts(ctx context.Context, userID int) (followers, following int, err error)
+	GetFollowCountsBatch(ctx context.Context, userIDs []int) (map[int]FollowCounts, error)
+}
+
 type UsersService struct {
-	repo      *UsersRepository
+	repo      UsersRepositoryInterface
 	postsRepo posts.PostsRepositoryInterface
+	publisher message.Publisher
+	logger    watermill.LoggerAdapter
 }
 
-func NewUsersService(repo *UsersRepository, postsRepo posts.PostsRepositoryInterface) *UsersService {
+func NewUsersService(repo UsersRepositoryInterface, postsRepo posts.PostsRepositoryInterface, publisher message.Publisher, logger watermill.LoggerAdapter) *UsersService {
 	return &UsersService{
 		repo:      repo,
 		postsRepo: postsRepo,
+		publisher: publisher,
+		logger:    logger,
 	}
 }
 
@@ -35,25 +65,138 @@ func (s *UsersService) GetUserProfile(ctx context.Context, userID int) (*UserPro
 		attachmentsCount = 0
 	}
 
+	followers, following, err := s.repo.GetFollowCounts(ctx, userID)
+	if err != nil {
+		followers, following = 0, 0
+	}
+
+	commentLikes, err := s.repo.GetCommentLikesCount(ctx, userID)
+	if err != nil {
+		commentLikes = 0
+	}
+
 	return &UserProfile{
-		User:             *u,
-		PostsCount:       postsCount,
-		AttachmentsCount: attachmentsCount,
+		User:              *u,
+		PostsCount:        postsCount,
+		AttachmentsCount:  attachmentsCount,
+		FollowersCount:    followers,
+		FollowingCount:    following,
+		CommentLikesCount: commentLikes,
 	}, nil
 }
 
-func (s *UsersService) GetUserPosts(ctx context.Context, userID int, filter posts.PostFilter) ([]posts.Post, error) {
-	_, err := s.repo.FindByID(ctx, userID)
+func (s *UsersService) EnsureUserExists(ctx context.Context, userID int) error {
+	if _, err := s.repo.FindByID(ctx, userID); err != nil {
+		return errors_constant.UserNotFound
+	}
+	return nil
+}
+
+func (s *UsersService) ListUsers(ctx context.Context, filter UserFilter) ([]user.User, error) {
+	return s.repo.List(ctx, filter)
+}
+
+// FollowCounts возвращает число подписчиков и подписок для списка пользователей
+func (s *UsersService) FollowCounts(ctx context.Context, userIDs []int) (map[int]FollowCounts, error) {
+	if len(userIDs) == 0 {
+		return map[int]FollowCounts{}, nil
+	}
+	return s.repo.GetFollowCountsBatch(ctx, userIDs)
+}
+
+// CommentLikesCounts возвращает число «нравится» на комментариях для списка пользователей
+func (s *UsersService) CommentLikesCounts(ctx context.Context, userIDs []int) (map[int]int, error) {
+	if len(userIDs) == 0 {
+		return map[int]int{}, nil
+	}
+	return s.repo.GetCommentLikesCountBatch(ctx, userIDs)
+}
+
+// FollowUser подписывает followerID на followeeID и публикует user.followed
+func (s *UsersService) FollowUser(ctx context.Context, followerID, followeeID int) error {
+	if followerID == followeeID {
+		return errors_constant.CannotFollowSelf
+	}
+	if err := s.EnsureUserExists(ctx, followeeID); err != nil {
+		return err
+	}
+
+	created, err := s.repo.Follow(ctx, followerID, followeeID)
 	if err != nil {
-		return nil, errors_constant.UserNotFound
+		return err
+	}
+	if !created {
+		return errors_constant.AlreadyFollowing
 	}
 
-	filter.UserID = &userID
-	filter.OnlyActive = true
+	s.publish("user.followed", UserFollowedEvent{FollowerID: followerID, FolloweeID: followeeID, CreatedAt: time.Now()})
+	return nil
+}
 
-	return s.postsRepo.List(ctx, filter)
+// UnfollowUser снимает подписку и публикует user.unfollowed
+func (s *UsersService) UnfollowUser(ctx context.Context, followerID, followeeID int) error {
+	removed, err := s.repo.Unfollow(ctx, followerID, followeeID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return errors_constant.NotFollowing
+	}
+
+	s.publish("user.unfollowed", UserUnfollowedEvent{FollowerID: followerID, FolloweeID: followeeID})
+	return nil
 }
 
-func (s *UsersService) ListUsers(ctx context.Context, filter UserFilter) ([]user.User, error) {
-	return s.repo.List(ctx, filter)
+// ListFollowers возвращает страницу подписчиков пользователя
+func (s *UsersService) ListFollowers(ctx context.Context, userID, limit int, cursor *FollowCursor) (*FollowsPage, error) {
+	return s.listFollows(ctx, userID, limit, cursor, s.repo.ListFollowers)
+}
+
+// ListFollowing возвращает страницу подписок пользователя
+func (s *UsersService) ListFollowing(ctx context.Context, userID, limit int, cursor *FollowCursor) (*FollowsPage, error) {
+	return s.listFollows(ctx, userID, limit, cursor, s.repo.ListFollowing)
+}
+
+func (s *UsersService) listFollows(
+	ctx context.Context,
+	userID, limit int,
+	cursor *FollowCursor,
+	list func(context.Context, int, *FollowCursor, int) ([]FollowUser, error),
+) (*FollowsPage, error) {
+	if err := s.EnsureUserExists(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = defaultFollowsLimit
+	}
+	if limit > maxFollowsLimit {
+		limit = maxFollowsLimit
+	}
+
+	users, err := list(ctx, userID, cursor, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &FollowsPage{Users: users}
+	if len(users) > limit {
+		page.Users = users[:limit]
+		last := page.Users[limit-1]
+		page.NextCursor = FollowCursor{Time: last.FollowedAt, ID: last.ID}.Encode()
+	}
+	return page, nil
+}
+
+func (s *UsersService) publish(topic string, event interface{}) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		s.logger.Error(fmt.Sprintf("failed to marshal %s event", topic), err, nil)
+		return
+	}
+
+	msg := message.NewMessage(watermill.NewUUID(), payload)
+	if err := s.publisher.Publish(topic, msg); err != nil {
+		s.logger.Error(fmt.Sprintf("failed to publish %s event", topic), err, nil)
+	}
 }
@@ -0,0 +1,256 @@
+package users
+
+import (
+	"context"
+	"encoding/json"
+	"mpb/internal/user"
+	"mpb/pkg/errors_constant"
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockUsersRepository struct {
+	mock.Mock
+}
+
+func (m *MockUsersRepository) FindByID(ctx context.Context, userID int) (*user.User, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*user.User), args.Error(1)
+}
+
+func (m *MockUsersRepository) List(ctx context.Context, f UserFilter) ([]user.User, error) {
+	args := m.Called(ctx, f)
+	return args.Get(0).([]user.User), args.Error(1)
+}
+
+func (m *MockUsersRepository) GetPostsCount(ctx context.Context, userID int) (int, error) {
+	args := m.Called(ctx, userID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockUsersRepository) GetAttachmentsCount(ctx context.Context, userID int) (int, error) {
+	args := m.Called(ctx, userID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockUsersRepository) GetCommentLikesCount(ctx context.Context, userID int) (int, error) {
+	args := m.Called(ctx, userID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockUsersRepository) Follow(ctx context.Context, followerID, followeeID int) (bool, error) {
+	args := m.Called(ctx, followerID, followeeID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUsersRepository) Unfollow(ctx context.Context, followerID, followeeID int) (bool, error) {
+	args := m.Called(ctx, followerID, followeeID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUsersRepository) ListFollowers(ctx context.Context, userID int, cursor *FollowCursor, limit int) ([]FollowUser, error) {
+	args := m.Called(ctx, userID, cursor, limit)
+	return args.Get(0).([]FollowUser), args.Error(1)
+}
+
+func (m *MockUsersRepository) ListFollowing(ctx context.Context, userID int, cursor *FollowCursor, limit int) ([]FollowUser, error) {
+	args := m.Called(ctx, userID, cursor, limit)
+	return args.Get(0).([]FollowUser), args.Error(1)
+}
+
+func (m *MockUsersRepository) GetFollowCounts(ctx context.Context, userID int) (int, int, error) {
+	args := m.Called(ctx, userID)
+	return args.Int(0), args.Int(1), args.Error(2)
+}
+
+func (m *MockUsersRepository) GetFollowCountsBatch(ctx context.Context, userIDs []int) (map[int]FollowCounts, error) {
+	args := m.Called(ctx, userIDs)
+	return args.Get(0).(map[int]FollowCounts), args.Error(1)
+}
+
+func (m *MockUsersRepository) GetCommentLikesCountBatch(ctx context.Context, userIDs []int) (map[int]int, error) {
+	args := m.Called(ctx, userIDs)
+	return args.Get(0).(map[int]int), args.Error(1)
+}
+
+type MockPublisher struct {
+	mock.Mock
+}
+
+func (m *MockPublisher) Publish(topic string, messages ...*message.Message) error {
+	return m.Called(topic, messages).Error(0)
+}
+
+func (m *MockPublisher) Close() error {
+	return m.Called().Error(0)
+}
+
+func TestUsersService_FollowUser(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("self follow", func(t *testing.T) {
+		repo := new(MockUsersRepository)
+		pub := new(MockPublisher)
+		service := NewUsersService(repo, nil, pub, watermill.NopLogger{})
+
+		err := service.FollowUser(ctx, 1, 1)
+		assert.ErrorIs(t, err, errors_constant.CannotFollowSelf)
+		repo.AssertNotCalled(t, "Follow", mock.Anything, mock.Anything, mock.Anything)
+		pub.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+	})
+
+	t.Run("unknown followee", func(t *testing.T) {
+		repo := new(MockUsersRepository)
+		repo.On("FindByID", ctx, 2).Return(nil, errors_constant.UserNotFound)
+		service := NewUsersService(repo, nil, new(MockPublisher), watermill.NopLogger{})
+
+		err := service.FollowUser(ctx, 1, 2)
+		assert.ErrorIs(t, err, errors_constant.UserNotFound)
+		repo.AssertNotCalled(t, "Follow", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("duplicate follow", func(t *testing.T) {
+		repo := new(MockUsersRepository)
+		pub := new(MockPublisher)
+		repo.On("FindByID", ctx, 2).Return(&user.User{ID: 2}, nil)
+		repo.On("Follow", ctx, 1, 2).Return(false, nil)
+		service := NewUsersService(repo, nil, pub, watermill.NopLogger{})
+
+		err := service.FollowUser(ctx, 1, 2)
+		assert.ErrorIs(t, err, errors_constant.AlreadyFollowing)
+		pub.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("publishes user.followed", func(t *testing.T) {
+		repo := new(MockUsersRepository)
+		pub := new(MockPublisher)
+		repo.On("FindByID", ctx, 2).Return(&user.User{ID: 2}, nil)
+		repo.On("Follow", ctx, 1, 2).Return(true, nil)
+
+		var event UserFollowedEvent
+		pub.On("Publish", "user.followed", mock.Anything).Run(func(args mock.Arguments) {
+			msgs := args.Get(1).([]*message.Message)
+			require.Len(t, msgs, 1)
+			require.NoError(t, json.Unmarshal(msgs[0].Payload, &event))
+		}).Return(nil)
+		service := NewUsersService(repo, nil, pub, watermill.NopLogger{})
+
+		require.NoError(t, service.FollowUser(ctx, 1, 2))
+		assert.Equal(t, 1, event.FollowerID)
+		assert.Equal(t, 2, event.FolloweeID)
+		assert.False(t, event.CreatedAt.IsZero())
+		pub.AssertExpectations(t)
+	})
+}
+
+func TestUsersService_UnfollowUser(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("not following", func(t *testing.T) {
+		repo := new(MockUsersRepository)
+		pub := new(MockPublisher)
+		repo.On("Unfollow", ctx, 1, 2).Return(false, nil)
+		service := NewUsersService(repo, nil, pub, watermill.NopLogger{})
+
+		err := service.UnfollowUser(ctx, 1, 2)
+		assert.ErrorIs(t, err, errors_constant.NotFollowing)
+		pub.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+	})
+
+	t.Run("publishes user.unfollowed", func(t *testing.T) {
+		repo := new(MockUsersRepository)
+		pub := new(MockPublisher)
+		repo.On("Unfollow", ctx, 1, 2).Return(true, nil)
+
+		var event UserUnfollowedEvent
+		pub.On("Publish", "user.unfollowed", mock.Anything).Run(func(args mock.Arguments) {
+			msgs := args.Get(1).([]*message.Message)
+			require.Len(t, msgs, 1)
+			require.NoError(t, json.Unmarshal(msgs[0].Payload, &event))
+		}).Return(nil)
+		service := NewUsersService(repo, nil, pub, watermill.NopLogger{})
+
+		require.NoError(t, service.UnfollowUser(ctx, 1, 2))
+		assert.Equal(t, UserUnfollowedEvent{FollowerID: 1, FolloweeID: 2}, event)
+		pub.AssertExpectations(t)
+	})
+}
+
+func TestUsersService_ListFollowersPaging(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	repo := new(MockUsersRepository)
+	repo.On("FindByID", ctx, 1).Return(&user.User{ID: 1}, nil)
+	// запрашивается на одну строку больше, чтобы понять, есть ли следующая страница
+	repo.On("ListFollowers", ctx, 1, (*FollowCursor)(nil), 3).Return([]FollowUser{
+		{ID: 9, FollowedAt: now},
+		{ID: 8, FollowedAt: now.Add(-time.Minute)},
+		{ID: 7, FollowedAt: now.Add(-2 * time.Minute)},
+	}, nil)
+	cursor := &FollowCursor{Time: now.Add(-time.Minute), ID: 8}
+	repo.On("ListFollowers", ctx, 1, cursor, 3).Return([]FollowUser{
+		{ID: 7, FollowedAt: now.Add(-2 * time.Minute)},
+	}, nil)
+	service := NewUsersService(repo, nil, new(MockPublisher), watermill.NopLogger{})
+
+	page, err := service.ListFollowers(ctx, 1, 2, nil)
+	require.NoError(t, err)
+	assert.Len(t, page.Users, 2)
+	require.NotEmpty(t, page.NextCursor)
+
+	next, err := DecodeFollowCursor(page.NextCursor)
+	require.NoError(t, err)
+	assert.Equal(t, 8, next.ID)
+	assert.True(t, next.Time.Equal(cursor.Time))
+
+	page, err = service.ListFollowers(ctx, 1, 2, cursor)
+	require.NoError(t, err)
+	assert.Len(t, page.Users, 1)
+	assert.Empty(t, page.NextCursor)
+	repo.AssertExpectations(t)
+}
+
+func TestUsersService_FollowCounts(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockUsersRepository)
+	repo.On("GetFollowCountsBatch", ctx, []int{1, 2}).Return(map[int]FollowCounts{1: {Followers: 3, Following: 1}}, nil)
+	service := NewUsersService(repo, nil, new(MockPublisher), watermill.NopLogger{})
+
+	counts, err := service.FollowCounts(ctx, []int{1, 2})
+	require.NoError(t, err)
+	assert.Equal(t, FollowCounts{Followers: 3, Following: 1}, counts[1])
+	assert.Equal(t, FollowCounts{}, counts[2])
+
+	counts, err = service.FollowCounts(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, counts)
+	repo.AssertNumberOfCalls(t, "GetFollowCountsBatch", 1)
+}
+
+func TestUsersService_CommentLikesCounts(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockUsersRepository)
+	repo.On("GetCommentLikesCountBatch", ctx, []int{1, 2}).Return(map[int]int{1: 7}, nil)
+	service := NewUsersService(repo, nil, new(MockPublisher), watermill.NopLogger{})
+
+	counts, err := service.CommentLikesCounts(ctx, []int{1, 2})
+	require.NoError(t, err)
+	assert.Equal(t, 7, counts[1])
+	assert.Equal(t, 0, counts[2])
+
+	counts, err = service.CommentLikesCounts(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, counts)
+	repo.AssertNumberOfCalls(t, "GetCommentLikesCountBatch", 1)
+}
@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE posts ADD COLUMN search_vector tsvector;
+
+CREATE OR REPLACE FUNCTION posts_search_vector_update()
+    RETURNS TRIGGER AS $BODY$
+BEGIN
+    NEW.search_vector =
+        setweight(to_tsvector('simple', coalesce(NEW.title, '')), 'A') ||
+        setweight(to_tsvector('simple', coalesce(NEW.description, '')), 'B') ||
+        setweight(to_tsvector('simple', coalesce(NEW.tag, '')), 'C');
+    RETURN NEW;
+END;
+$BODY$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trigger_posts_search_vector
+    BEFORE INSERT OR UPDATE OF title, description, tag ON posts
+    FOR EACH ROW
+EXECUTE FUNCTION posts_search_vector_update();
+
+-- заполняем существующие строки, не трогая updated_at
+ALTER TABLE posts DISABLE TRIGGER trigger_set_updated_at_posts;
+UPDATE posts SET search_vector =
+    setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(description, '')), 'B') ||
+    setweight(to_tsvector('simple', coalesce(tag, '')), 'C');
+ALTER TABLE posts ENABLE TRIGGER trigger_set_updated_at_posts;
+
+CREATE INDEX idx_posts_search_vector ON posts USING GIN (search_vector);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_posts_search_vector;
+DROP TRIGGER IF EXISTS trigger_posts_search_vector ON posts;
+DROP FUNCTION IF EXISTS posts_search_vector_update();
+ALTER TABLE posts DROP COLUMN IF EXISTS search_vector;
+-- +goose StatementEnd
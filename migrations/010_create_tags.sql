@@ -0,0 +1,45 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE tags (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    slug TEXT UNIQUE NOT NULL,
+    alias_of INT NULL REFERENCES tags(id) ON DELETE CASCADE,   -- канонический тег, если это синоним
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (alias_of IS NULL OR alias_of <> id)
+);
+
+CREATE TABLE post_tags (
+    post_id INT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    tag_id INT NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (post_id, tag_id)
+);
+
+CREATE INDEX idx_tags_alias_of ON tags (alias_of);
+CREATE INDEX idx_post_tags_tag_id ON post_tags (tag_id);
+
+-- переносим старые значения posts.tag
+INSERT INTO tags (name, slug)
+SELECT DISTINCT ON (slug) name, slug
+FROM (
+    SELECT lower(regexp_replace(trim(tag), '\s+', ' ', 'g')) AS name,
+           trim(BOTH '-' FROM regexp_replace(lower(trim(tag)), '[^[:alnum:]]+', '-', 'g')) AS slug
+    FROM posts
+    WHERE trim(tag) <> ''
+) t
+WHERE slug <> ''
+ORDER BY slug, name;
+
+INSERT INTO post_tags (post_id, tag_id)
+SELECT p.id, t.id
+FROM posts p
+JOIN tags t ON t.slug = trim(BOTH '-' FROM regexp_replace(lower(trim(p.tag)), '[^[:alnum:]]+', '-', 'g'))
+ON CONFLICT DO NOTHING;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS post_tags CASCADE;
+DROP TABLE IF EXISTS tags CASCADE;
+-- +goose StatementEnd
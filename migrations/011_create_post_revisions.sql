@@ -0,0 +1,46 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE post_revisions (
+    id SERIAL PRIMARY KEY,
+    post_id INT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    revision INT NOT NULL,
+    editor_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    title TEXT NOT NULL,
+    description TEXT NOT NULL,
+    tag TEXT NOT NULL,
+    tags TEXT[] NOT NULL DEFAULT '{}',       -- slug'и тегов на момент правки
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (post_id, revision)
+);
+
+CREATE INDEX idx_post_revisions_editor_id ON post_revisions (editor_id);
+
+-- ревизии неизменяемы
+CREATE OR REPLACE FUNCTION prevent_post_revisions_update()
+    RETURNS TRIGGER AS $BODY$
+BEGIN
+    RAISE EXCEPTION 'post_revisions rows are immutable';
+END;
+$BODY$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trigger_prevent_post_revisions_update
+    BEFORE UPDATE ON post_revisions
+    FOR EACH ROW
+EXECUTE FUNCTION prevent_post_revisions_update();
+
+-- текущее состояние существующих постов становится первой ревизией
+INSERT INTO post_revisions (post_id, revision, editor_id, title, description, tag, tags, created_at)
+SELECT p.id, 1, p.user_id, p.title, p.description, p.tag,
+       COALESCE((SELECT array_agg(t.slug ORDER BY t.slug)
+                 FROM post_tags pt JOIN tags t ON t.id = pt.tag_id
+                 WHERE pt.post_id = p.id), '{}'),
+       p.updated_at
+FROM posts p;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS trigger_prevent_post_revisions_update ON post_revisions;
+DROP FUNCTION IF EXISTS prevent_post_revisions_update();
+DROP TABLE IF EXISTS post_revisions CASCADE;
+-- +goose StatementEnd
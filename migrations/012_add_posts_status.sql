@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE posts
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'published'
+        CHECK (status IN ('draft', 'scheduled', 'published', 'archived')),
+    ADD COLUMN publish_at TIMESTAMP NULL;             -- время (планируемой) публикации
+
+ALTER TABLE posts DISABLE TRIGGER trigger_set_updated_at_posts;
+UPDATE posts SET publish_at = created_at;
+ALTER TABLE posts ENABLE TRIGGER trigger_set_updated_at_posts;
+
+ALTER TABLE posts ADD CONSTRAINT posts_scheduled_publish_at
+    CHECK (status <> 'scheduled' OR publish_at IS NOT NULL);
+
+CREATE INDEX idx_posts_status ON posts (status);
+CREATE INDEX idx_posts_scheduled_publish_at ON posts (publish_at) WHERE status = 'scheduled';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_posts_scheduled_publish_at;
+DROP INDEX IF EXISTS idx_posts_status;
+ALTER TABLE posts DROP CONSTRAINT IF EXISTS posts_scheduled_publish_at;
+ALTER TABLE posts DROP COLUMN IF EXISTS publish_at;
+ALTER TABLE posts DROP COLUMN IF EXISTS status;
+-- +goose StatementEnd
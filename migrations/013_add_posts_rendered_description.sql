@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE posts
+    ADD COLUMN description_html TEXT NOT NULL DEFAULT '',   -- очищенный HTML, рендерится из Markdown при записи
+    ADD COLUMN excerpt TEXT NOT NULL DEFAULT '',
+    ADD COLUMN word_count INT NOT NULL DEFAULT 0;
+
+-- существующие посты: экранированный исходный текст одним абзацем, до повторного сохранения через API
+ALTER TABLE posts DISABLE TRIGGER trigger_set_updated_at_posts;
+UPDATE posts
+SET description_html = '<p>' || replace(replace(replace(description, '&', '&amp;'), '<', '&lt;'), '>', '&gt;') || '</p>',
+    excerpt = left(regexp_replace(btrim(description), '\s+', ' ', 'g'), 200),
+    word_count = COALESCE(array_length(regexp_split_to_array(NULLIF(btrim(description), ''), '\s+'), 1), 0);
+ALTER TABLE posts ENABLE TRIGGER trigger_set_updated_at_posts;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE posts DROP COLUMN IF EXISTS word_count;
+ALTER TABLE posts DROP COLUMN IF EXISTS excerpt;
+ALTER TABLE posts DROP COLUMN IF EXISTS description_html;
+-- +goose StatementEnd
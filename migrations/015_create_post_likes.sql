@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE post_likes (
+    post_id INT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT post_likes_post_user_unique UNIQUE (post_id, user_id)   -- источник истины для лайков, Redis — кэш
+);
+
+CREATE INDEX idx_post_likes_post_created ON post_likes (post_id, created_at DESC, user_id DESC);
+CREATE INDEX idx_post_likes_user_created ON post_likes (user_id, created_at DESC, post_id DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS post_likes;
+-- +goose StatementEnd
@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+-- updated_at меняется только при изменении содержимого поста, обновление счётчиков его не трогает
+CREATE OR REPLACE FUNCTION set_posts_updated_at_timestamp()
+    RETURNS TRIGGER AS $BODY$
+BEGIN
+    IF to_jsonb(NEW) - ARRAY['like', 'count_viewers', 'updated_at']
+        IS DISTINCT FROM to_jsonb(OLD) - ARRAY['like', 'count_viewers', 'updated_at'] THEN
+        NEW.updated_at = NOW();
+    END IF;
+    RETURN NEW;
+END;
+$BODY$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_set_updated_at_posts ON posts;
+CREATE TRIGGER trigger_set_updated_at_posts
+    BEFORE UPDATE ON posts
+    FOR EACH ROW
+EXECUTE FUNCTION set_posts_updated_at_timestamp();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS trigger_set_updated_at_posts ON posts;
+CREATE TRIGGER trigger_set_updated_at_posts
+    BEFORE UPDATE ON posts
+    FOR EACH ROW
+EXECUTE FUNCTION set_updated_at_timestamp();
+
+DROP FUNCTION IF EXISTS set_posts_updated_at_timestamp();
+-- +goose StatementEnd
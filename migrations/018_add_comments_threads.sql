@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE comments
+    ADD COLUMN parent_comment_id INT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    ADD COLUMN depth INT NOT NULL DEFAULT 0,
+    ADD COLUMN path TEXT COLLATE "C";
+
+-- path — id предков и самого комментария, дополненные нулями до 10 знаков и разделённые точкой:
+-- сортировка по path даёт обход дерева, а поддерево выбирается по префиксу
+UPDATE comments SET path = lpad(id::text, 10, '0');
+
+ALTER TABLE comments ALTER COLUMN path SET NOT NULL;
+
+CREATE INDEX idx_comments_post_path ON comments (post_id, path);
+CREATE INDEX idx_comments_parent_comment_id ON comments (parent_comment_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_comments_parent_comment_id;
+DROP INDEX IF EXISTS idx_comments_post_path;
+
+ALTER TABLE comments
+    DROP COLUMN IF EXISTS path,
+    DROP COLUMN IF EXISTS depth,
+    DROP COLUMN IF EXISTS parent_comment_id;
+-- +goose StatementEnd
@@ -0,0 +1,156 @@
+-- +goose Up
+-- +goose StatementBegin
+-- оценки комментариев: не больше одной на пользователя, value = 1 (нравится) или -1 (не нравится)
+CREATE TABLE comment_likes (
+    comment_id INT NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    value SMALLINT NOT NULL DEFAULT 1 CHECK (value IN (-1, 1)),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (comment_id, user_id)
+);
+
+CREATE INDEX idx_comment_likes_user_id ON comment_likes (user_id);
+
+-- updated_at комментария меняется только при изменении содержимого, оценки его не трогают
+CREATE OR REPLACE FUNCTION set_comments_updated_at_timestamp()
+    RETURNS TRIGGER AS $BODY$
+BEGIN
+    IF to_jsonb(NEW) - ARRAY['like', 'dislike', 'score_top', 'score_controversial', 'updated_at']
+        IS DISTINCT FROM to_jsonb(OLD) - ARRAY['like', 'dislike', 'score_top', 'score_controversial', 'updated_at'] THEN
+        NEW.updated_at = NOW();
+    END IF;
+    RETURN NEW;
+END;
+$BODY$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_set_updated_at_comments ON comments;
+CREATE TRIGGER trigger_set_updated_at_comments
+    BEFORE UPDATE ON comments
+    FOR EACH ROW
+EXECUTE FUNCTION set_comments_updated_at_timestamp();
+
+-- счётчики прежнего LikeComment не привязаны к пользователям и пересчитываются из таблицы оценок
+UPDATE comments SET "like" = 0 WHERE "like" IS DISTINCT FROM 0;
+ALTER TABLE comments
+    ALTER COLUMN "like" SET NOT NULL,
+    ADD COLUMN dislike INT NOT NULL DEFAULT 0;
+
+-- нижняя граница доверительного интервала Уилсона (95%) для доли положительных оценок
+ALTER TABLE comments ADD COLUMN score_top DOUBLE PRECISION GENERATED ALWAYS AS (
+    CASE WHEN "like" + dislike = 0 THEN 0 ELSE
+        ("like"::float8 / ("like" + dislike) + 1.9208 / ("like" + dislike)
+            - 1.96 * sqrt(("like"::float8 * dislike) / ("like" + dislike) + 0.9604) / ("like" + dislike))
+        / (1 + 3.8416 / ("like" + dislike))
+    END
+) STORED;
+
+-- спорность: много оценок, поделённых примерно поровну
+ALTER TABLE comments ADD COLUMN score_controversial DOUBLE PRECISION GENERATED ALWAYS AS (
+    CASE WHEN "like" = 0 OR dislike = 0 THEN 0 ELSE
+        power(("like" + dislike)::float8, LEAST("like", dislike)::float8 / GREATEST("like", dislike))
+    END
+) STORED;
+
+CREATE INDEX idx_comments_post_top ON comments (post_id, score_top DESC, id DESC) WHERE parent_comment_id IS NULL;
+CREATE INDEX idx_comments_post_controversial ON comments (post_id, score_controversial DESC, id DESC) WHERE parent_comment_id IS NULL;
+
+CREATE OR REPLACE FUNCTION update_comment_like_counters()
+    RETURNS TRIGGER AS $BODY$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE comments
+        SET "like" = "like" - (OLD.value = 1)::int,
+            dislike = dislike - (OLD.value = -1)::int
+        WHERE id = OLD.comment_id;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        UPDATE comments
+        SET "like" = "like" + (NEW.value = 1)::int,
+            dislike = dislike + (NEW.value = -1)::int
+        WHERE id = NEW.comment_id;
+    END IF;
+    RETURN NULL;
+END;
+$BODY$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trigger_comment_likes_counters
+    AFTER INSERT OR UPDATE OF value OR DELETE ON comment_likes
+    FOR EACH ROW
+EXECUTE FUNCTION update_comment_like_counters();
+
+-- число неудалённых комментариев поста
+ALTER TABLE posts ADD COLUMN comments_count INT NOT NULL DEFAULT 0;
+
+ALTER TABLE posts DISABLE TRIGGER trigger_set_updated_at_posts;
+UPDATE posts p SET comments_count = c.count
+FROM (SELECT post_id, COUNT(*) AS count FROM comments WHERE deleted_at IS NULL GROUP BY post_id) c
+WHERE p.id = c.post_id;
+ALTER TABLE posts ENABLE TRIGGER trigger_set_updated_at_posts;
+
+CREATE OR REPLACE FUNCTION update_posts_comments_count()
+    RETURNS TRIGGER AS $BODY$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') AND OLD.deleted_at IS NULL THEN
+        UPDATE posts SET comments_count = comments_count - 1 WHERE id = OLD.post_id;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') AND NEW.deleted_at IS NULL THEN
+        UPDATE posts SET comments_count = comments_count + 1 WHERE id = NEW.post_id;
+    END IF;
+    RETURN NULL;
+END;
+$BODY$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trigger_posts_comments_count
+    AFTER INSERT OR UPDATE OF deleted_at OR DELETE ON comments
+    FOR EACH ROW
+EXECUTE FUNCTION update_posts_comments_count();
+
+CREATE OR REPLACE FUNCTION set_posts_updated_at_timestamp()
+    RETURNS TRIGGER AS $BODY$
+BEGIN
+    IF to_jsonb(NEW) - ARRAY['like', 'count_viewers', 'comments_count', 'updated_at']
+        IS DISTINCT FROM to_jsonb(OLD) - ARRAY['like', 'count_viewers', 'comments_count', 'updated_at'] THEN
+        NEW.updated_at = NOW();
+    END IF;
+    RETURN NEW;
+END;
+$BODY$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION set_posts_updated_at_timestamp()
+    RETURNS TRIGGER AS $BODY$
+BEGIN
+    IF to_jsonb(NEW) - ARRAY['like', 'count_viewers', 'updated_at']
+        IS DISTINCT FROM to_jsonb(OLD) - ARRAY['like', 'count_viewers', 'updated_at'] THEN
+        NEW.updated_at = NOW();
+    END IF;
+    RETURN NEW;
+END;
+$BODY$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_posts_comments_count ON comments;
+DROP FUNCTION IF EXISTS update_posts_comments_count();
+ALTER TABLE posts DROP COLUMN IF EXISTS comments_count;
+
+DROP TRIGGER IF EXISTS trigger_set_updated_at_comments ON comments;
+CREATE TRIGGER trigger_set_updated_at_comments
+    BEFORE UPDATE ON comments
+    FOR EACH ROW
+EXECUTE FUNCTION set_updated_at_timestamp();
+DROP FUNCTION IF EXISTS set_comments_updated_at_timestamp();
+
+DROP TRIGGER IF EXISTS trigger_comment_likes_counters ON comment_likes;
+DROP FUNCTION IF EXISTS update_comment_like_counters();
+
+DROP INDEX IF EXISTS idx_comments_post_controversial;
+DROP INDEX IF EXISTS idx_comments_post_top;
+ALTER TABLE comments
+    DROP COLUMN IF EXISTS score_controversial,
+    DROP COLUMN IF EXISTS score_top,
+    DROP COLUMN IF EXISTS dislike,
+    ALTER COLUMN "like" DROP NOT NULL;
+
+DROP TABLE IF EXISTS comment_likes;
+-- +goose StatementEnd
@@ -0,0 +1,41 @@
+-- +goose Up
+-- +goose StatementBegin
+-- жалобы на комментарии; reporter_id = NULL — комментарий помечен автоматически при создании
+CREATE TABLE comment_reports (
+    id SERIAL PRIMARY KEY,
+    comment_id INT NOT NULL REFERENCES comments(id) ON DELETE CASCADE,
+    reporter_id INT NULL REFERENCES users(id) ON DELETE SET NULL,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    resolved_at TIMESTAMP NULL
+);
+
+-- один пользователь — одна открытая жалоба на комментарий
+CREATE UNIQUE INDEX idx_comment_reports_open_reporter ON comment_reports (comment_id, reporter_id)
+    WHERE resolved_at IS NULL AND reporter_id IS NOT NULL;
+CREATE INDEX idx_comment_reports_open ON comment_reports (created_at, comment_id) WHERE resolved_at IS NULL;
+
+-- журнал действий модераторов; comment_id без внешнего ключа, чтобы запись пережила удаление комментария
+CREATE TABLE comment_moderation_actions (
+    id SERIAL PRIMARY KEY,
+    comment_id INT NOT NULL,
+    moderator_id INT NULL REFERENCES users(id) ON DELETE SET NULL,
+    action TEXT NOT NULL CHECK (action IN ('block', 'unblock', 'delete', 'dismiss')),
+    reason TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_comment_moderation_actions_comment ON comment_moderation_actions (comment_id, created_at DESC);
+
+ALTER TABLE comments DISABLE TRIGGER trigger_set_updated_at_comments;
+UPDATE comments SET blocked = FALSE WHERE blocked IS NULL;
+ALTER TABLE comments ENABLE TRIGGER trigger_set_updated_at_comments;
+ALTER TABLE comments ALTER COLUMN blocked SET NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE comments ALTER COLUMN blocked DROP NOT NULL;
+DROP TABLE IF EXISTS comment_moderation_actions;
+DROP TABLE IF EXISTS comment_reports;
+-- +goose StatementEnd
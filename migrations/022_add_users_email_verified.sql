@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- NULL — адрес не подтверждён; при смене адреса значение ставится заново, потому что новый адрес подтверждается ссылкой
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN IF EXISTS email_verified_at;
+-- +goose StatementEnd
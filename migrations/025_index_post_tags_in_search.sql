@@ -0,0 +1,101 @@
+-- +goose Up
+-- +goose StatementBegin
+-- в поиск попадают все теги поста из post_tags, а не только posts.tag
+CREATE OR REPLACE FUNCTION posts_tags_text(p_post_id INT)
+    RETURNS TEXT AS $BODY$
+    SELECT string_agg(t.name, ' ')
+    FROM post_tags pt
+    JOIN tags t ON t.id = pt.tag_id
+    WHERE pt.post_id = p_post_id
+$BODY$ LANGUAGE sql STABLE;
+
+CREATE OR REPLACE FUNCTION posts_build_search_vector(p_id INT, p_title TEXT, p_description TEXT, p_tag TEXT)
+    RETURNS tsvector AS $BODY$
+    SELECT setweight(to_tsvector('simple', coalesce(p_title, '')), 'A') ||
+           setweight(to_tsvector('simple', coalesce(p_description, '')), 'B') ||
+           setweight(to_tsvector('simple', coalesce(posts_tags_text(p_id), p_tag, '')), 'C')
+$BODY$ LANGUAGE sql STABLE;
+
+CREATE OR REPLACE FUNCTION posts_search_vector_update()
+    RETURNS TRIGGER AS $BODY$
+BEGIN
+    NEW.search_vector = posts_build_search_vector(NEW.id, NEW.title, NEW.description, NEW.tag);
+    RETURN NEW;
+END;
+$BODY$ LANGUAGE plpgsql;
+
+-- смена тегов (в том числе слияние и синонимы) пересчитывает search_vector затронутых постов
+CREATE OR REPLACE FUNCTION post_tags_search_vector_update()
+    RETURNS TRIGGER AS $BODY$
+BEGIN
+    -- changed_post_tags — вставленные или удалённые строки, см. триггеры ниже
+    UPDATE posts p
+    SET search_vector = posts_build_search_vector(p.id, p.title, p.description, p.tag)
+    WHERE p.id IN (SELECT DISTINCT post_id FROM changed_post_tags);
+    RETURN NULL;
+END;
+$BODY$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trigger_post_tags_insert_search_vector
+    AFTER INSERT ON post_tags
+    REFERENCING NEW TABLE AS changed_post_tags
+    FOR EACH STATEMENT
+EXECUTE FUNCTION post_tags_search_vector_update();
+
+CREATE TRIGGER trigger_post_tags_delete_search_vector
+    AFTER DELETE ON post_tags
+    REFERENCING OLD TABLE AS changed_post_tags
+    FOR EACH STATEMENT
+EXECUTE FUNCTION post_tags_search_vector_update();
+
+-- search_vector производный, его пересчёт не меняет updated_at
+CREATE OR REPLACE FUNCTION set_posts_updated_at_timestamp()
+    RETURNS TRIGGER AS $BODY$
+BEGIN
+    IF to_jsonb(NEW) - ARRAY['like', 'count_viewers', 'comments_count', 'search_vector', 'updated_at']
+        IS DISTINCT FROM to_jsonb(OLD) - ARRAY['like', 'count_viewers', 'comments_count', 'search_vector', 'updated_at'] THEN
+        NEW.updated_at = NOW();
+    END IF;
+    RETURN NEW;
+END;
+$BODY$ LANGUAGE plpgsql;
+
+UPDATE posts SET search_vector = posts_build_search_vector(id, title, description, tag);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS trigger_post_tags_insert_search_vector ON post_tags;
+DROP TRIGGER IF EXISTS trigger_post_tags_delete_search_vector ON post_tags;
+DROP FUNCTION IF EXISTS post_tags_search_vector_update();
+
+CREATE OR REPLACE FUNCTION set_posts_updated_at_timestamp()
+    RETURNS TRIGGER AS $BODY$
+BEGIN
+    IF to_jsonb(NEW) - ARRAY['like', 'count_viewers', 'comments_count', 'updated_at']
+        IS DISTINCT FROM to_jsonb(OLD) - ARRAY['like', 'count_viewers', 'comments_count', 'updated_at'] THEN
+        NEW.updated_at = NOW();
+    END IF;
+    RETURN NEW;
+END;
+$BODY$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION posts_search_vector_update()
+    RETURNS TRIGGER AS $BODY$
+BEGIN
+    NEW.search_vector =
+        setweight(to_tsvector('simple', coalesce(NEW.title, '')), 'A') ||
+        setweight(to_tsvector('simple', coalesce(NEW.description, '')), 'B') ||
+        setweight(to_tsvector('simple', coalesce(NEW.tag, '')), 'C');
+    RETURN NEW;
+END;
+$BODY$ LANGUAGE plpgsql;
+
+DROP FUNCTION IF EXISTS posts_build_search_vector(INT, TEXT, TEXT, TEXT);
+DROP FUNCTION IF EXISTS posts_tags_text(INT);
+
+UPDATE posts SET search_vector =
+    setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(description, '')), 'B') ||
+    setweight(to_tsvector('simple', coalesce(tag, '')), 'C');
+-- +goose StatementEnd
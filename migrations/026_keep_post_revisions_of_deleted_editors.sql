@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+-- удаление редактора не стирает историю правок: editor_id обнуляется
+ALTER TABLE post_revisions ALTER COLUMN editor_id DROP NOT NULL;
+ALTER TABLE post_revisions DROP CONSTRAINT post_revisions_editor_id_fkey;
+ALTER TABLE post_revisions
+    ADD CONSTRAINT post_revisions_editor_id_fkey
+    FOREIGN KEY (editor_id) REFERENCES users(id) ON DELETE SET NULL;
+
+-- ревизии неизменяемы; единственное допустимое изменение — обнуление editor_id по ON DELETE SET NULL
+CREATE OR REPLACE FUNCTION prevent_post_revisions_update()
+    RETURNS TRIGGER AS $BODY$
+BEGIN
+    IF NEW.editor_id IS NULL AND OLD.editor_id IS NOT NULL
+        AND to_jsonb(NEW) - 'editor_id' = to_jsonb(OLD) - 'editor_id' THEN
+        RETURN NEW;
+    END IF;
+    RAISE EXCEPTION 'post_revisions rows are immutable';
+END;
+$BODY$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION prevent_post_revisions_update()
+    RETURNS TRIGGER AS $BODY$
+BEGIN
+    RAISE EXCEPTION 'post_revisions rows are immutable';
+END;
+$BODY$ LANGUAGE plpgsql;
+
+DELETE FROM post_revisions WHERE editor_id IS NULL;
+ALTER TABLE post_revisions DROP CONSTRAINT post_revisions_editor_id_fkey;
+ALTER TABLE post_revisions
+    ADD CONSTRAINT post_revisions_editor_id_fkey
+    FOREIGN KEY (editor_id) REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE post_revisions ALTER COLUMN editor_id SET NOT NULL;
+-- +goose StatementEnd
@@ -0,0 +1,47 @@
+package diff
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLines(t *testing.T) {
+	got := Lines("first\nsecond\nthird", "first\nchanged\nthird\nfourth")
+
+	assert.Equal(t, []Line{
+		{Op: OpEqual, Text: "first"},
+		{Op: OpDelete, Text: "second"},
+		{Op: OpInsert, Text: "changed"},
+		{Op: OpEqual, Text: "third"},
+		{Op: OpInsert, Text: "fourth"},
+	}, got)
+}
+
+func TestLines_Empty(t *testing.T) {
+	assert.Empty(t, Lines("", ""))
+	assert.Equal(t, []Line{{Op: OpInsert, Text: "new"}}, Lines("", "new"))
+	assert.Equal(t, []Line{{Op: OpDelete, Text: "old"}}, Lines("old", ""))
+}
+
+func TestLines_LargeChangeFallsBack(t *testing.T) {
+	a := make([]string, 0, 2002)
+	b := make([]string, 0, 2002)
+	a = append(a, "head")
+	b = append(b, "head")
+	for i := 0; i < 2000; i++ {
+		a = append(a, fmt.Sprintf("old %d", i))
+		b = append(b, fmt.Sprintf("new %d", i))
+	}
+	a = append(a, "tail")
+	b = append(b, "tail")
+
+	got := Slices(a, b)
+
+	assert.Len(t, got, 4002)
+	assert.Equal(t, Line{Op: OpEqual, Text: "head"}, got[0])
+	assert.Equal(t, Line{Op: OpDelete, Text: "old 0"}, got[1])
+	assert.Equal(t, Line{Op: OpInsert, Text: "new 0"}, got[2001])
+	assert.Equal(t, Line{Op: OpEqual, Text: "tail"}, got[4001])
+}
@@ -3,11 +3,49 @@ package errors_constant
 import "errors"
 
 var (
-	UserAlreadyExists  = errors.New("user already exists")
-	UserNotFound       = errors.New("user not found")
-	PostNotFound       = errors.New("post not found")
-	InvalidTitle       = errors.New("title must be at least 3 characters long")
-	UserNotAuthorized  = errors.New("user not authorized to modify this post")
-	CommentDeleted     = errors.New("comment deleted")
-	InvalidCommentText = errors.New("invalid comment text")
+	UserAlreadyExists       = errors.New("user already exists")
+	UserNotFound            = errors.New("user not found")
+	PostNotFound            = errors.New("post not found")
+	InvalidTitle            = errors.New("title must be at least 3 characters long")
+	UserNotAuthorized       = errors.New("user not authorized to modify this post")
+	CommentDeleted          = errors.New("comment deleted")
+	InvalidCommentText      = errors.New("invalid comment text")
+	InvalidCursor           = errors.New("invalid pagination cursor")
+	InvalidSearchQuery      = errors.New("search query must not be empty")
+	InvalidTag              = errors.New("tag must be at most 50 characters long")
+	TooManyTags             = errors.New("too many tags")
+	TagNotFound             = errors.New("tag not found")
+	TagMergeConflict        = errors.New("cannot merge a tag into itself")
+	RevisionNotFound        = errors.New("revision not found")
+	InvalidPostStatus       = errors.New("invalid post status transition")
+	InvalidPublishAt        = errors.New("publish_at must be in the future for scheduled posts")
+	InvalidReaction         = errors.New("reaction is not allowed")
+	ReactionNotFound        = errors.New("user has no reaction on this post")
+	InvalidTrendingWindow   = errors.New("trending window must be one of 24h, 7d")
+	CannotFollowSelf        = errors.New("users cannot follow themselves")
+	AlreadyFollowing        = errors.New("user is already followed")
+	NotFollowing            = errors.New("user is not followed")
+	CommentNotFound         = errors.New("comment not found")
+	CommentTooDeep          = errors.New("reply exceeds the maximum comment depth")
+	CommentPostMismatch     = errors.New("reply must belong to the same post as its parent")
+	InvalidCommentSort      = errors.New("comment sort must be one of new, old, top, controversial")
+	InvalidCommentVote      = errors.New("comment vote must be 1 or -1")
+	InvalidModerationAction = errors.New("moderation action must be one of block, unblock, delete, dismiss")
+	InvalidModerationReason = errors.New("moderation reason must not be empty")
+	SessionNotFound         = errors.New("session not found")
+	RefreshTokenReused      = errors.New("refresh token reuse detected, session revoked")
+	UserBanned              = errors.New("user account is disabled")
+	InvalidPassword         = errors.New("invalid password")
+	InvalidEmailToken       = errors.New("invalid or expired email link")
+	EmailTaken              = errors.New("email is already in use")
+	EmailUnchanged          = errors.New("new email matches the current one")
+	EmailAlreadyVerified    = errors.New("email is already verified")
+	EmailResendTooSoon      = errors.New("email was sent recently, try again later")
+	InvalidResetToken       = errors.New("invalid or expired password reset token")
+	PasswordUnchanged       = errors.New("new password must differ from the current one")
+	TOTPNotEnabled          = errors.New("two-factor authentication is not enabled")
+	TOTPAlreadyEnabled      = errors.New("two-factor authentication is already enabled")
+	InvalidMFACode          = errors.New("invalid authentication code")
+	InvalidMFAToken         = errors.New("invalid or expired mfa token")
+	TooManyMFAAttempts      = errors.New("too many authentication attempts, try again later")
 )
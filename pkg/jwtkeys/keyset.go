@@ -0,0 +1,290 @@
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"mpb/configs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minRSABits — RSA-ключи короче не принимаются
+const minRSABits = 2048
+
+// Key — ключ проверки подписи; kid берётся из имени файла
+type Key struct {
+	ID     string
+	Method jwt.SigningMethod
+	Public crypto.PublicKey
+}
+
+// KeySet подписывает токены активным приватным ключом и проверяет их любым из загруженных публичных ключей по kid.
+// Ротация без простоя: новый публичный ключ сначала раскладывается на все сервисы, затем меняется JWT_SIGNING_KEY_ID,
+// а старый ключ убирается, когда истекут подписанные им токены.
+type KeySet struct {
+	signingKID string
+	signer     crypto.Signer
+	keys       map[string]Key
+	// legacySecret проверяет HS256-токены без kid, выданные до перехода на асимметричную подпись,
+	// и только до legacyUntil: к этому моменту все такие токены уже истекли
+	legacySecret []byte
+	legacyUntil  time.Time
+}
+
+// NewKeySet загружает ключи из conf.JWT.KeysDir. Без каталога временный Ed25519-ключ создаётся
+// только при явном conf.JWT.EphemeralKey: токены не переживут перезапуск и не проверятся другими сервисами.
+func NewKeySet(conf *configs.Config) (*KeySet, error) {
+	var ks *KeySet
+	var err error
+	switch {
+	case conf.JWT.KeysDir != "":
+		ks, err = Load(conf.JWT.KeysDir, conf.JWT.SigningKeyID)
+	case conf.JWT.EphemeralKey:
+		log.Printf("JWT_KEYS_DIR is not set, signing with an ephemeral key: tokens will not survive a restart")
+		ks, err = Generate()
+	default:
+		return nil, errors.New("JWT_KEYS_DIR is required; set JWT_EPHEMERAL_KEY=true to sign with a temporary key in local development")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if conf.JWT.SecretKey != "" {
+		switch {
+		case conf.JWT.LegacyUntil.IsZero():
+			return nil, errors.New("JWT_LEGACY_UNTIL is required while JWT_SECRET is set")
+		case time.Now().After(conf.JWT.LegacyUntil):
+			log.Printf("JWT_SECRET is ignored: legacy tokens expired at %s, remove it from the config", conf.JWT.LegacyUntil.Format(time.RFC3339))
+		default:
+			ks.legacySecret = []byte(conf.JWT.SecretKey)
+			ks.legacyUntil = conf.JWT.LegacyUntil
+		}
+	}
+	return ks, nil
+}
+
+// Load читает *.pem из dir: приватные ключи (PKCS#8, PKCS#1) и публичные (PKIX).
+// Пустой signingKID означает, что сервис только проверяет токены.
+func Load(dir, signingKID string) (*KeySet, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	ks := &KeySet{keys: make(map[string]Key)}
+	for _, path := range paths {
+		kid := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		if err := ks.loadFile(kid, path, kid == signingKID); err != nil {
+			return nil, fmt.Errorf("failed to load JWT key %s: %w", path, err)
+		}
+	}
+
+	if len(ks.keys) == 0 {
+		return nil, fmt.Errorf("no JWT keys found in %s", dir)
+	}
+	if signingKID != "" && ks.signer == nil {
+		return nil, fmt.Errorf("no private key for signing key id %q in %s", signingKID, dir)
+	}
+	return ks, nil
+}
+
+// Generate создаёт набор из одного временного Ed25519-ключа
+func Generate() (*KeySet, error) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	kidBytes := make([]byte, 8)
+	if _, err := rand.Read(kidBytes); err != nil {
+		return nil, err
+	}
+
+	ks := &KeySet{keys: make(map[string]Key)}
+	if err := ks.add("ephemeral-"+base64.RawURLEncoding.EncodeToString(kidBytes), private, true); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+func (ks *KeySet) loadFile(kid, path string, signing bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return errors.New("no PEM block")
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return err
+	}
+	return ks.add(kid, key, signing)
+}
+
+func (ks *KeySet) add(kid string, key interface{}, signing bool) error {
+	var public crypto.PublicKey
+	var method jwt.SigningMethod
+
+	switch k := key.(type) {
+	case ed25519.PrivateKey:
+		public, method = k.Public(), jwt.SigningMethodEdDSA
+	case ed25519.PublicKey:
+		public, method = k, jwt.SigningMethodEdDSA
+	case *rsa.PrivateKey:
+		public, method = &k.PublicKey, jwt.SigningMethodRS256
+	case *rsa.PublicKey:
+		public, method = k, jwt.SigningMethodRS256
+	default:
+		return fmt.Errorf("unsupported key type %T, use Ed25519 or RSA", key)
+	}
+
+	if rsaKey, ok := public.(*rsa.PublicKey); ok && rsaKey.N.BitLen() < minRSABits {
+		return fmt.Errorf("RSA key is %d bits, at least %d required", rsaKey.N.BitLen(), minRSABits)
+	}
+
+	ks.keys[kid] = Key{ID: kid, Method: method, Public: public}
+
+	if signing {
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return errors.New("signing key must be a private key")
+		}
+		ks.signingKID, ks.signer = kid, signer
+	}
+	return nil
+}
+
+// Sign подписывает claims активным ключом и ставит его kid в заголовок
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	if ks.signer == nil {
+		return "", errors.New("no JWT signing key configured")
+	}
+
+	token := jwt.NewWithClaims(ks.keys[ks.signingKID].Method, claims)
+	token.Header["kid"] = ks.signingKID
+	return token.SignedString(ks.signer)
+}
+
+// Parse проверяет подпись и срок действия токена
+func (ks *KeySet) Parse(tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, ks.keyfunc, jwt.WithValidMethods(ks.methods()))
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	return token.Claims.(jwt.MapClaims), nil
+}
+
+// keyfunc выбирает ключ проверки по kid и сверяет алгоритм, чтобы токен нельзя было проверить чужим методом
+func (ks *KeySet) keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		if ks.legacySecret != nil && token.Method == jwt.SigningMethodHS256 {
+			return ks.legacyKey(token)
+		}
+		return nil, errors.New("token has no kid")
+	}
+
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown kid %q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("kid %q expects %s, got %s", kid, key.Method.Alg(), token.Method.Alg())
+	}
+	return key.Public, nil
+}
+
+// legacyKey отдаёт секрет легаси-токена, только если и сейчас, и его срок действия не позже legacyUntil.
+// Каждое такое использование логируется, чтобы было видно, когда JWT_SECRET можно убрать.
+func (ks *KeySet) legacyKey(token *jwt.Token) (interface{}, error) {
+	if time.Now().After(ks.legacyUntil) {
+		return nil, errors.New("legacy tokens are no longer accepted")
+	}
+	exp, err := token.Claims.GetExpirationTime()
+	if err != nil || exp == nil || exp.After(ks.legacyUntil) {
+		return nil, errors.New("legacy token outlives JWT_LEGACY_UNTIL")
+	}
+
+	userID := "-"
+	if claims, ok := token.Claims.(jwt.MapClaims); ok {
+		userID = fmt.Sprint(claims["user_id"])
+	}
+	log.Printf("accepted legacy HS256 token without kid: user_id=%s exp=%s", userID, exp.Format(time.RFC3339))
+	return ks.legacySecret, nil
+}
+
+func (ks *KeySet) methods() []string {
+	methods := []string{jwt.SigningMethodEdDSA.Alg(), jwt.SigningMethodRS256.Alg()}
+	if ks.legacySecret != nil {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	return methods
+}
+
+// JWK — публичный ключ в формате RFC 7517
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS возвращает все ключи проверки, упорядоченные по kid
+func (ks *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: make([]JWK, 0, len(ks.keys))}
+	for _, key := range ks.keys {
+		jwk := JWK{Kid: key.ID, Use: "sig", Alg: key.Method.Alg()}
+		switch public := key.Public.(type) {
+		case ed25519.PublicKey:
+			jwk.Kty, jwk.Crv = "OKP", "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
@@ -0,0 +1,148 @@
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"mpb/configs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeKey(t *testing.T, dir, name, blockType string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), data, 0o600))
+}
+
+func newEd25519(t *testing.T) (ed25519.PublicKey, []byte) {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+	return public, der
+}
+
+func claims() jwt.MapClaims {
+	return jwt.MapClaims{"user_id": 1, "exp": time.Now().Add(time.Hour).Unix()}
+}
+
+func TestRotation(t *testing.T) {
+	oldDir, newDir := t.TempDir(), t.TempDir()
+
+	oldPublic, oldPrivate := newEd25519(t)
+	_, newPrivate := newEd25519(t)
+	oldPublicDER, err := x509.MarshalPKIXPublicKey(oldPublic)
+	require.NoError(t, err)
+
+	// до ротации подписывает 2024-01, после — 2024-06, а старый ключ остаётся только для проверки
+	writeKey(t, oldDir, "2024-01.pem", "PRIVATE KEY", oldPrivate)
+	writeKey(t, newDir, "2024-01.pem", "PUBLIC KEY", oldPublicDER)
+	writeKey(t, newDir, "2024-06.pem", "PRIVATE KEY", newPrivate)
+
+	before, err := Load(oldDir, "2024-01")
+	require.NoError(t, err)
+	after, err := Load(newDir, "2024-06")
+	require.NoError(t, err)
+
+	oldToken, err := before.Sign(claims())
+	require.NoError(t, err)
+	newToken, err := after.Sign(claims())
+	require.NoError(t, err)
+
+	_, err = after.Parse(oldToken)
+	assert.NoError(t, err, "tokens signed before rotation stay valid")
+	_, err = after.Parse(newToken)
+	assert.NoError(t, err)
+	_, err = before.Parse(newToken)
+	assert.Error(t, err, "unknown kid")
+
+	jwks := after.JWKS()
+	require.Len(t, jwks.Keys, 2)
+	assert.Equal(t, "2024-01", jwks.Keys[0].Kid)
+	assert.Equal(t, "OKP", jwks.Keys[0].Kty)
+	assert.Equal(t, "EdDSA", jwks.Keys[1].Alg)
+}
+
+func TestLoad_UnknownSigningKey(t *testing.T) {
+	dir := t.TempDir()
+	_, private := newEd25519(t)
+	writeKey(t, dir, "a.pem", "PRIVATE KEY", private)
+
+	_, err := Load(dir, "b")
+	assert.Error(t, err)
+
+	verifyOnly, err := Load(dir, "")
+	require.NoError(t, err)
+	_, err = verifyOnly.Sign(claims())
+	assert.Error(t, err)
+}
+
+func TestParse_RejectsForeignAlgorithms(t *testing.T) {
+	ks, err := Generate()
+	require.NoError(t, err)
+
+	// HS256 с kid асимметричного ключа и без kid при выключенном легаси-режиме
+	withKid := jwt.NewWithClaims(jwt.SigningMethodHS256, claims())
+	withKid.Header["kid"] = ks.signingKID
+	signed, err := withKid.SignedString([]byte("secret"))
+	require.NoError(t, err)
+	_, err = ks.Parse(signed)
+	assert.Error(t, err)
+
+	legacy, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims()).SignedString([]byte("secret"))
+	require.NoError(t, err)
+	_, err = ks.Parse(legacy)
+	assert.Error(t, err)
+
+	ks.legacySecret = []byte("secret")
+	ks.legacyUntil = time.Now().Add(2 * time.Hour)
+	_, err = ks.Parse(legacy)
+	assert.NoError(t, err)
+}
+
+func TestParse_LegacyCutoff(t *testing.T) {
+	ks, err := Generate()
+	require.NoError(t, err)
+	ks.legacySecret = []byte("secret")
+	ks.legacyUntil = time.Now().Add(30 * time.Minute)
+
+	// токен живёт дольше отсечки — значит, выдан не до перехода
+	late, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims()).SignedString([]byte("secret"))
+	require.NoError(t, err)
+	_, err = ks.Parse(late)
+	assert.Error(t, err)
+
+	noExp, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": 1}).SignedString([]byte("secret"))
+	require.NoError(t, err)
+	_, err = ks.Parse(noExp)
+	assert.Error(t, err)
+
+	ks.legacyUntil = time.Now().Add(-time.Minute)
+	expired, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": 1, "exp": time.Now().Add(-2 * time.Minute).Unix(),
+	}).SignedString([]byte("secret"))
+	require.NoError(t, err)
+	_, err = ks.Parse(expired)
+	assert.Error(t, err)
+}
+
+func TestNewKeySet_EphemeralKeyIsOptIn(t *testing.T) {
+	_, err := NewKeySet(&configs.Config{})
+	assert.Error(t, err)
+
+	ks, err := NewKeySet(&configs.Config{JWT: configs.JWTConfig{EphemeralKey: true}})
+	require.NoError(t, err)
+	token, err := ks.Sign(claims())
+	require.NoError(t, err)
+	_, err = ks.Parse(token)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,135 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"mime"
+	"mpb/configs"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Message — текстовое письмо одному получателю
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer отправляет письма; реализация выбирается конфигурацией MAIL_BACKEND
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New создаёт отправителя по conf.Mail.Backend: smtp, file или log
+func New(conf *configs.Config) (Mailer, error) {
+	switch conf.Mail.Backend {
+	case "smtp":
+		if conf.Mail.SMTPAddr == "" {
+			return nil, fmt.Errorf("SMTP_ADDR is required for the smtp mail backend")
+		}
+		return NewSMTPMailer(conf.Mail), nil
+	case "file":
+		return NewFileMailer(conf.Mail.Dir, conf.Mail.From)
+	case "log":
+		return NewLogMailer(conf.Mail.From), nil
+	default:
+		return nil, fmt.Errorf("unknown mail backend %q, use smtp, file or log", conf.Mail.Backend)
+	}
+}
+
+// compose собирает письмо в формате RFC 5322
+func compose(from string, msg Message, now time.Time) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return buf.Bytes()
+}
+
+// SMTPMailer отправляет письма через SMTP-сервер; при заданном логине использует PLAIN-аутентификацию
+type SMTPMailer struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+func NewSMTPMailer(conf configs.MailConfig) *SMTPMailer {
+	m := &SMTPMailer{addr: conf.SMTPAddr, from: conf.From}
+	if conf.SMTPUsername != "" {
+		host, _, err := net.SplitHostPort(conf.SMTPAddr)
+		if err != nil {
+			host = conf.SMTPAddr
+		}
+		m.auth = smtp.PlainAuth("", conf.SMTPUsername, conf.SMTPPassword, host)
+	}
+	return m
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(m.addr, m.auth, m.from, []string{msg.To}, compose(m.from, msg, time.Now()))
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("failed to send mail: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9@._-]`)
+
+// FileMailer сохраняет каждое письмо в отдельный .eml-файл — для локальной разработки и тестов
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create mail dir: %w", err)
+	}
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+func (m *FileMailer) Send(_ context.Context, msg Message) error {
+	now := time.Now()
+	name := fmt.Sprintf("%s-%s.eml", now.Format("20060102T150405.000000000"), unsafeFileChars.ReplaceAllString(msg.To, "_"))
+	if err := os.WriteFile(filepath.Join(m.dir, name), compose(m.from, msg, now), 0o644); err != nil {
+		return fmt.Errorf("failed to write mail: %w", err)
+	}
+	return nil
+}
+
+// LogMailer только пишет в лог заголовки письма. Тело не логируется:
+// в нём бывают одноразовые ссылки и коды (подтверждение почты, сброс пароля).
+type LogMailer struct {
+	from string
+}
+
+func NewLogMailer(from string) *LogMailer {
+	return &LogMailer{from: from}
+}
+
+func (m *LogMailer) Send(_ context.Context, msg Message) error {
+	log.Printf("mail from %s to %s: %s (body redacted, %d bytes)", m.from, msg.To, msg.Subject, len(msg.Body))
+	return nil
+}
@@ -0,0 +1,57 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	m, err := NewFileMailer(dir, "no-reply@mpb.local")
+	require.NoError(t, err)
+
+	err = m.Send(context.Background(), Message{
+		To:      "user@example.com",
+		Subject: "Подтвердите адрес",
+		Body:    "line 1\nline 2",
+	})
+	require.NoError(t, err)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.True(t, strings.HasSuffix(files[0], "-user@example.com.eml"))
+
+	data, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	content := string(data)
+	assert.Contains(t, content, "From: no-reply@mpb.local\r\n")
+	assert.Contains(t, content, "To: user@example.com\r\n")
+	assert.Contains(t, content, "Subject: =?utf-8?q?")
+	assert.True(t, strings.HasSuffix(content, "\r\n\r\nline 1\r\nline 2"))
+}
+
+func TestLogMailerRedactsBody(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	err := NewLogMailer("no-reply@mpb.local").Send(context.Background(), Message{
+		To:      "user@example.com",
+		Subject: "Reset password",
+		Body:    "https://mpb.local/reset?token=secret",
+	})
+	require.NoError(t, err)
+
+	assert.Contains(t, buf.String(), "user@example.com")
+	assert.Contains(t, buf.String(), "Reset password")
+	assert.NotContains(t, buf.String(), "secret")
+}
@@ -0,0 +1,78 @@
+package markdown
+
+import (
+	"bytes"
+	"html"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// WordsPerMinute — средняя скорость чтения для расчёта reading time
+const WordsPerMinute = 200
+
+var (
+	renderer = goldmark.New(goldmark.WithExtensions(extension.Table))
+
+	// policy пропускает только безопасное подмножество HTML, которое выдаёт Markdown
+	policy = newPolicy()
+
+	textPolicy = bluemonday.StrictPolicy()
+)
+
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#.-]+$`)).OnElements("code")
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	return p
+}
+
+// Document — результат рендеринга Markdown
+type Document struct {
+	HTML      string
+	Text      string
+	WordCount int
+}
+
+// Render переводит CommonMark (с таблицами) в очищенный HTML и извлекает из него простой текст
+func Render(source string) (*Document, error) {
+	var buf bytes.Buffer
+	if err := renderer.Convert([]byte(source), &buf); err != nil {
+		return nil, err
+	}
+
+	safe := policy.SanitizeBytes(buf.Bytes())
+	text := strings.Join(strings.Fields(html.UnescapeString(string(textPolicy.SanitizeBytes(safe)))), " ")
+
+	return &Document{
+		HTML:      string(safe),
+		Text:      text,
+		WordCount: len(strings.Fields(text)),
+	}, nil
+}
+
+// Excerpt обрезает текст до maxRunes символов по границе слова
+func Excerpt(text string, maxRunes int) string {
+	if utf8.RuneCountInString(text) <= maxRunes {
+		return text
+	}
+
+	runes := []rune(text)
+	cut := string(runes[:maxRunes])
+	if i := strings.LastIndexByte(cut, ' '); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,.;:-") + "…"
+}
+
+// ReadingTime возвращает время чтения в минутах, округлённое вверх; для непустого текста не меньше минуты
+func ReadingTime(words int) int {
+	if words <= 0 {
+		return 0
+	}
+	return (words + WordsPerMinute - 1) / WordsPerMinute
+}
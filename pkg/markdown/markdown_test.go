@@ -0,0 +1,80 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name        string
+		source      string
+		contains    []string
+		notContains []string
+		text        string
+	}{
+		{
+			name:     "emphasis and links",
+			source:   "Hello **world**, see [docs](https://example.com).",
+			contains: []string{"<strong>world</strong>", `href="https://example.com"`, `rel="nofollow noopener"`},
+			text:     "Hello world, see docs.",
+		},
+		{
+			name:     "table",
+			source:   "| a | b |\n|---|---|\n| 1 | 2 |",
+			contains: []string{"<table>", "<th>a</th>", "<td>2</td>"},
+			text:     "a b 1 2",
+		},
+		{
+			name:     "fenced code keeps language class",
+			source:   "```go\nfmt.Println(\"<hi>\")\n```",
+			contains: []string{`<code class="language-go">`, "&lt;hi&gt;"},
+			text:     `fmt.Println("<hi>")`,
+		},
+		{
+			name:        "raw html is dropped",
+			source:      "<script>alert(1)</script>\n\n<img src=x onerror=alert(1)>",
+			notContains: []string{"<script", "onerror"},
+		},
+		{
+			name:        "javascript links are dropped",
+			source:      "[click](javascript:alert(1))",
+			notContains: []string{"javascript:"},
+			text:        "click",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Render(tt.source)
+			require.NoError(t, err)
+
+			for _, s := range tt.contains {
+				assert.Contains(t, doc.HTML, s)
+			}
+			for _, s := range tt.notContains {
+				assert.NotContains(t, doc.HTML, s)
+			}
+			if tt.text != "" {
+				assert.Equal(t, tt.text, doc.Text)
+				assert.Equal(t, len(strings.Fields(tt.text)), doc.WordCount)
+			}
+		})
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	assert.Equal(t, "short text", Excerpt("short text", 20))
+	assert.Equal(t, "one two…", Excerpt("one two three", 10))
+	assert.Equal(t, "привет…", Excerpt("привет, мир", 8))
+}
+
+func TestReadingTime(t *testing.T) {
+	assert.Equal(t, 0, ReadingTime(0))
+	assert.Equal(t, 1, ReadingTime(1))
+	assert.Equal(t, 1, ReadingTime(200))
+	assert.Equal(t, 2, ReadingTime(201))
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import "github.com/gofiber/fiber/v2"
+
+var verifiedEmailRequired bool
+
+// SetVerifiedEmailRequired включает RequireVerifiedEmail; вызывается один раз при старте из REQUIRE_VERIFIED_EMAIL
+func SetVerifiedEmailRequired(required bool) {
+	verifiedEmailRequired = required
+}
+
+// RequireVerifiedEmail пропускает запрос только с подтверждённым адресом, если это включено конфигурацией.
+// Признак берётся из токена, поэтому после подтверждения нужно обновить токены. Должен стоять после JWTAuth.
+func RequireVerifiedEmail() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !verifiedEmailRequired {
+			return c.Next()
+		}
+		if verified, _ := c.Locals("email_verified").(bool); !verified {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "email is not verified"})
+		}
+		return c.Next()
+	}
+}
@@ -1,13 +1,15 @@
 package middleware
 
 import (
+	"mpb/pkg/jwtkeys"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func JWTAuth(secretKey []byte) fiber.Handler {
+// JWTAuth проверяет подпись access-токена ключом из набора по kid и его отзыв
+func JWTAuth(keys *jwtkeys.KeySet) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		authHeader := c.Get("Authorization")
 		if authHeader == "" {
@@ -19,19 +21,71 @@ func JWTAuth(secretKey []byte) fiber.Handler {
 		}
 		tokenString := parts[1]
 
-		token, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
-			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fiber.ErrUnauthorized
-			}
-			return secretKey, nil
-		})
-		if err != nil || !token.Valid {
+		claims, err := parseToken(tokenString, keys)
+		if err != nil {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "invalid token"})
 		}
-		claims := token.Claims.(jwt.MapClaims)
-		if uid, ok := claims["user_id"].(float64); ok {
-			c.Locals("user_id", int(uid))
+		if revocation != nil {
+			revoked, err := revocation.IsRevoked(c.Context(), tokenClaims(claims))
+			if err != nil {
+				return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "token check unavailable"})
+			}
+			if revoked {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "token revoked"})
+			}
 		}
+		setClaims(c, claims)
 		return c.Next()
 	}
 }
+
+// OptionalJWTAuth выставляет user_id, если передан валидный токен, и пропускает анонимные запросы;
+// с отозванным токеном запрос считается анонимным
+func OptionalJWTAuth(keys *jwtkeys.KeySet) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		parts := strings.SplitN(c.Get("Authorization"), " ", 2)
+		if len(parts) == 2 && strings.ToLower(parts[0]) == "bearer" {
+			if claims, err := parseToken(parts[1], keys); err == nil {
+				if revocation == nil {
+					setClaims(c, claims)
+				} else if revoked, err := revocation.IsRevoked(c.Context(), tokenClaims(claims)); err == nil && !revoked {
+					setClaims(c, claims)
+				}
+			}
+		}
+		return c.Next()
+	}
+}
+
+func parseToken(tokenString string, keys *jwtkeys.KeySet) (jwt.MapClaims, error) {
+	claims, err := keys.Parse(tokenString)
+	if err != nil {
+		return nil, fiber.ErrUnauthorized
+	}
+	// у access-токена нет typ: refresh-токены и ссылки из писем подписаны тем же ключом, но для входа не годятся
+	if _, ok := claims["typ"]; ok {
+		return nil, fiber.ErrUnauthorized
+	}
+	return claims, nil
+}
+
+func setClaims(c *fiber.Ctx, claims jwt.MapClaims) {
+	if uid, ok := claims["user_id"].(float64); ok {
+		c.Locals("user_id", int(uid))
+	}
+	if role, ok := claims["role"].(string); ok {
+		c.Locals("role", role)
+	}
+	if sid, ok := claims["sid"].(float64); ok {
+		c.Locals("session_id", int(sid))
+	}
+	if verified, ok := claims["email_verified"].(bool); ok {
+		c.Locals("email_verified", verified)
+	}
+	if jti, ok := claims["jti"].(string); ok {
+		c.Locals("token_id", jti)
+	}
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		c.Locals("token_exp", exp.Time)
+	}
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"context"
+	"time"
+)
+
+// TokenClaims — поля access-токена, по которым проверяется его отзыв
+type TokenClaims struct {
+	ID        string
+	UserID    int
+	SessionID int
+	IssuedAt  time.Time
+}
+
+// RevocationChecker сообщает, отозван ли токен до истечения срока
+type RevocationChecker interface {
+	IsRevoked(ctx context.Context, claims TokenClaims) (bool, error)
+}
+
+var revocation RevocationChecker
+
+// SetRevocationChecker подключает проверку отзыва к JWTAuth и OptionalJWTAuth; вызывается один раз при старте.
+// Без неё токены действуют до exp.
+func SetRevocationChecker(checker RevocationChecker) {
+	revocation = checker
+}
+
+func tokenClaims(claims map[string]interface{}) TokenClaims {
+	var tc TokenClaims
+	tc.ID, _ = claims["jti"].(string)
+	if uid, ok := claims["user_id"].(float64); ok {
+		tc.UserID = int(uid)
+	}
+	if sid, ok := claims["sid"].(float64); ok {
+		tc.SessionID = int(sid)
+	}
+	if iat, ok := claims["iat"].(float64); ok {
+		tc.IssuedAt = time.Unix(int64(iat), 0)
+	}
+	return tc
+}
@@ -28,23 +28,23 @@ func ValidateBody[T any]() fiber.Handler {
 	}
 }
 
-//func ValidateQuery[T any]() fiber.Handler {
-//	return func(c *fiber.Ctx) error {
-//		var req T
-//
-//		if err := c.QueryParser(&req); err != nil {
-//			return badRequest(c, "invalid query parameters")
-//		}
-//
-//		if err := validate.Struct(req); err != nil {
-//			return validationError(c, err)
-//		}
-//
-//		c.Locals("query", req)
-//		return c.Next()
-//	}
-//}
-//
+func ValidateQuery[T any]() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req T
+
+		if err := c.QueryParser(&req); err != nil {
+			return badRequest(c, "invalid query parameters")
+		}
+
+		if err := validate.Struct(req); err != nil {
+			return validationError(c, err)
+		}
+
+		c.Locals("query", req)
+		return c.Next()
+	}
+}
+
 //func ValidateHeader[T any]() fiber.Handler {
 //	return func(c *fiber.Ctx) error {
 //		var req T
@@ -71,15 +71,15 @@ func Body[T any](c *fiber.Ctx) *T {
 	return nil
 }
 
-//func Query[T any](c *fiber.Ctx) *T {
-//	if v := c.Locals("query"); v != nil {
-//		if typed, ok := v.(T); ok {
-//			return &typed
-//		}
-//	}
-//	return nil
-//}
-//
+func Query[T any](c *fiber.Ctx) *T {
+	if v := c.Locals("query"); v != nil {
+		if typed, ok := v.(T); ok {
+			return &typed
+		}
+	}
+	return nil
+}
+
 //func Header[T any](c *fiber.Ctx) *T {
 //	if v := c.Locals("header"); v != nil {
 //		if typed, ok := v.(T); ok {
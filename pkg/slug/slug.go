@@ -0,0 +1,26 @@
+package slug
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Make приводит строку к нижнему регистру и заменяет всё, кроме букв и цифр, на дефисы
+func Make(s string) string {
+	var b strings.Builder
+	pendingDash := false
+
+	for _, r := range strings.ToLower(strings.TrimSpace(s)) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if pendingDash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			pendingDash = false
+			continue
+		}
+		pendingDash = true
+	}
+
+	return b.String()
+}
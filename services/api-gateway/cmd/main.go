@@ -3,6 +3,7 @@ package main
 import (
 	"log"
 	"mpb/configs"
+	"mpb/pkg/jwtkeys"
 	"mpb/services/api-gateway/internal/client"
 	"mpb/services/api-gateway/internal/router"
 	"os"
@@ -18,6 +19,12 @@ func main() {
 	// Load configuration
 	conf := configs.LoadConfig()
 
+	// Gateway only verifies tokens: JWT_KEYS_DIR holds public keys and JWT_SIGNING_KEY_ID stays empty
+	jwtKeys, err := jwtkeys.NewKeySet(conf)
+	if err != nil {
+		log.Fatalf("Failed to load JWT keys: %v", err)
+	}
+
 	// Initialize gRPC clients
 	postsServiceAddr := os.Getenv("POSTS_SERVICE_ADDR")
 	if postsServiceAddr == "" {
@@ -76,7 +83,7 @@ func main() {
 	}))
 
 	api := app.Group("/api")
-	router.SetupRoutes(api, postsClient, commentsClient, storiesClient, usersClient, []byte(conf.JWT.SecretKey))
+	router.SetupRoutes(api, postsClient, commentsClient, storiesClient, usersClient, jwtKeys)
 
 	app.Get("/health", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{"status": "ok"})
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"mpb/pkg/jwtkeys"
 	posts_proto "mpb/proto/posts"
 	"mpb/services/api-gateway/internal/client"
 	"strconv"
@@ -9,14 +10,14 @@ import (
 )
 
 type CommentsHandler struct {
-	client    *client.CommentsClient
-	jwtSecret []byte
+	client  *client.CommentsClient
+	jwtKeys *jwtkeys.KeySet
 }
 
-func NewCommentsHandler(client *client.CommentsClient, jwtSecret []byte) *CommentsHandler {
+func NewCommentsHandler(client *client.CommentsClient, jwtKeys *jwtkeys.KeySet) *CommentsHandler {
 	return &CommentsHandler{
-		client:    client,
-		jwtSecret: jwtSecret,
+		client:  client,
+		jwtKeys: jwtKeys,
 	}
 }
 
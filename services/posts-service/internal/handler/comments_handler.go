@@ -22,7 +22,7 @@ func NewCommentsHandler(service *comments.CommentsService) *CommentsHandler {
 }
 
 func (h *CommentsHandler) CreateComment(ctx context.Context, req *posts_proto.CreateCommentRequest) (*posts_proto.CommentResponse, error) {
-	comment, err := h.service.CreateComment(ctx, int(req.PostId), int(req.UserId), req.Content)
+	comment, err := h.service.CreateComment(ctx, int(req.PostId), nil, int(req.UserId), req.Content)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to create comment: %v", err)
 	}
@@ -44,15 +44,12 @@ func (h *CommentsHandler) GetComment(ctx context.Context, req *posts_proto.GetCo
 }
 
 func (h *CommentsHandler) ListComments(ctx context.Context, req *posts_proto.ListCommentsRequest) (*posts_proto.ListCommentsResponse, error) {
-	commentList, err := h.service.ListComments(ctx, int(req.PostId))
+	page, err := h.service.ListComments(ctx, int(req.PostId), comments.ListOptions{Limit: int(req.GetPagination().GetPageSize())})
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to list comments: %v", err)
 	}
 
-	protoComments := make([]*posts_proto.Comment, len(commentList))
-	for i, c := range commentList {
-		protoComments[i] = toProtoComment(&c)
-	}
+	protoComments := appendProtoComments(nil, page.Comments)
 
 	return &posts_proto.ListCommentsResponse{
 		Comments: protoComments,
@@ -80,6 +77,15 @@ func (h *CommentsHandler) DeleteComment(ctx context.Context, req *posts_proto.De
 	return &common_proto.Empty{}, nil
 }
 
+// appendProtoComments раскладывает дерево в плоский список в порядке обхода: ответы идут сразу за родителем
+func appendProtoComments(dst []*posts_proto.Comment, nodes []*comments.CommentNode) []*posts_proto.Comment {
+	for _, n := range nodes {
+		dst = append(dst, toProtoComment(&n.Comment))
+		dst = appendProtoComments(dst, n.Replies)
+	}
+	return dst
+}
+
 func toProtoComment(c *comments.Comment) *posts_proto.Comment {
 	return &posts_proto.Comment{
 		Id:        int32(c.ID),
@@ -44,13 +44,13 @@ func TestPostsIntegration(t *testing.T) {
 		ctx := context.Background()
 		userID := 1
 
-		post, err := postService.CreatePost(ctx, userID, "Integration Test Post", "This is a test description", "test")
+		post, err := postService.CreatePost(ctx, userID, "Integration Test Post", "This is a test description", []string{"test"}, "", nil)
 		require.NoError(t, err)
 		assert.NotZero(t, post.ID)
 		assert.Equal(t, userID, post.UserID)
 		assert.Equal(t, "Integration Test Post", post.Title)
 
-		retrievedPost, err := postService.GetPostByID(ctx, post.ID)
+		retrievedPost, err := postService.GetPostByID(ctx, post.ID, userID)
 		require.NoError(t, err)
 		assert.Equal(t, post.ID, retrievedPost.ID)
 		assert.Equal(t, post.Title, retrievedPost.Title)